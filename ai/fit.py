@@ -15,6 +15,7 @@
   limitations under the License.
 """
 
+import json
 from pathlib import Path
 import pandas as pd
 from sklearn.linear_model import LinearRegression
@@ -52,6 +53,85 @@ def create_dataframe(swpc_sample: Path) -> pd.DataFrame:
     return df
 
 
+def json_file(model_file: Path) -> Path:
+    """Json file of the model that the server evaluates without python
+
+    Args:
+        model_file (Path): Model file path
+
+    Returns:
+        Path: The model file path with the json suffix
+    """
+    return model_file.with_name(model_file.name + '.json')
+
+
+def export_tree(
+        model: DecisionTreeClassifier,
+        feature_names: list,
+        json_path: Path):
+    """Export the decision tree as json
+
+    The nodes are in the order of the tree, the root first. The leaves
+    have no children (-1) and the predicted class is the one with the
+    greatest value. The server goes to the left node if the feature is
+    less than or equal to the threshold.
+
+    Args:
+        model (DecisionTreeClassifier): Fitted model
+        feature_names (list): Names of the features in the model order
+        json_path (Path): Json file path
+    """
+    t = model.tree_
+    nodes = []
+
+    for i in range(t.node_count):
+        nodes.append({
+            'left': int(t.children_left[i]),
+            'right': int(t.children_right[i]),
+            'feature': int(t.feature[i]),
+            'threshold': float(t.threshold[i]),
+            'value': [float(v) for v in t.value[i][0]],
+        })
+
+    json_path.write_text(json.dumps({
+        'type': 'decision_tree',
+        'features': [str(f) for f in feature_names],
+        'classes': [str(c) for c in model.classes_],
+        'nodes': nodes,
+    }, indent=2))
+
+    print(f'Exported model in the file: {str(json_path)}\n')
+
+
+def export_linear(
+        scaler: StandardScaler,
+        model: LinearRegression,
+        feature_names: list,
+        json_path: Path):
+    """Export the scaler and the linear regression as json
+
+    The server scales the features with the mean and the scale and
+    adds the intercept to the products of the coefficients. The scaler
+    is only exported here, the joblib model keeps its format.
+
+    Args:
+        scaler (StandardScaler): Fitted scaler of the features
+        model (LinearRegression): Fitted model
+        feature_names (list): Names of the features in the model order
+        json_path (Path): Json file path
+    """
+    json_path.write_text(json.dumps({
+        'type': 'linear_regression',
+        'features': [str(f) for f in feature_names],
+        'mean': [float(v) for v in scaler.mean_],
+        'scale': [float(v) for v in scaler.scale_],
+        'coefficients': [float(v) for v in model.coef_],
+        'intercept': float(model.intercept_),
+    }, indent=2))
+
+    print(f'Exported model in the file: {str(json_path)}\n')
+
+
 def fit_water_quality(
         swpc_sample: pd.DataFrame,
         decision_tree: Path,
@@ -103,6 +183,8 @@ def fit_water_quality(
     joblib.dump(model, model_file)
     print(f'Saved model in the file: {str(model_file)}\n')
 
+    export_tree(model, feature_names, json_file(model_file))
+
 
 def fit_chlorine(swpc_sample: pd.DataFrame, model_file: Path):
     """Create chlorine model
@@ -157,3 +239,5 @@ def fit_chlorine(swpc_sample: pd.DataFrame, model_file: Path):
     # Save the trained model to a file
     joblib.dump(model, model_file)
     print(f'Saved model in the file: {str(model_file)}\n')
+
+    export_linear(scaler, model, feature_names, json_file(model_file))
@@ -35,12 +35,13 @@ def random_sample(swpc_sample: Path, size_dataset: int = 1000):
         swpc_sample (Path): swpc sample path
         size_dataset (int, optional): sample size. Defaults to 1000.
     """
-    # Numerical values are assigned randomly
+    # Numerical values are assigned randomly, in the ranges of the
+    # samples that the server accepts (SampleRules of internal/ai)
     data = {
         field_temp: np.random.uniform(20, 40, size_dataset),
         field_ph: np.random.uniform(6, 8.5, size_dataset),
         field_orp: np.random.uniform(400, 900, size_dataset),
-        field_cl: np.random.uniform(5.5, 8.5, size_dataset)
+        field_cl: np.random.uniform(0.5, 3, size_dataset)
     }
 
     # Water quality labels are assigned in a fictitious manner (balanced)
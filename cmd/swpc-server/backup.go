@@ -0,0 +1,156 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal"
+)
+
+var errBackupRead = errors.New("The backup cannot be read")
+
+const backupUsage = `Usage: swpc-server backup [-output <file>] [flags]
+       swpc-server restore [-input <file>] [flags]
+
+backup writes the micro controller configuration, the samples, the users
+and the api keys of the stores as json. By default, to the standard output.
+restore saves them in the stores of the configuration. The samples are
+added, so restore into empty stores and restart the server afterwards.
+
+The configuration is loaded with the -config and -set flags.
+`
+
+// backupCommand writes the data of the stores. It returns the exit code
+func backupCommand(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(stdout, backupUsage) }
+	out := fs.String("output", "", "Json file")
+	src := addSourceFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	t, ok := newTools(src)
+	if !ok {
+		return 1
+	}
+
+	defer t.Close() //nolint:errcheck
+
+	if err := writeBackup(t.Stores, *out, stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+// restoreCommand saves a backup in the stores. It returns the exit code
+func restoreCommand(args []string, stdin io.Reader, stdout io.Writer) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(stdout, backupUsage) }
+	in := fs.String("input", "", "Json file")
+	src := addSourceFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	t, ok := newTools(src)
+	if !ok {
+		return 1
+	}
+
+	defer t.Close() //nolint:errcheck
+
+	// The saved configuration is notified to the hub,
+	// which traces it without a device
+	t.Hubt.Register()
+	t.Hub.Run()
+
+	defer t.Hub.Stop()
+
+	if err := readBackup(t.Stores, *in, stdin); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+func writeBackup(stores internal.Stores, name string, stdout io.Writer) error {
+	b, err := stores.Backup()
+	if err != nil {
+		return err
+	}
+
+	w, closeOut, err := output(name, stdout)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(b); err != nil {
+		_ = closeOut()
+
+		return err
+	}
+
+	if err := closeOut(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Backup: %d samples, %d users, %d api keys\n",
+		len(b.Samples), len(b.Users), len(b.APIKeys))
+
+	return nil
+}
+
+func readBackup(stores internal.Stores, name string, stdin io.Reader) error {
+	r, closeIn, err := input(name, stdin)
+	if err != nil {
+		return err
+	}
+
+	defer closeIn() //nolint:errcheck
+
+	var b internal.Backup
+
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return errors.Wrap(err, errBackupRead.Error())
+	}
+
+	if err := stores.Restore(b); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Restored: %d samples, %d users, %d api keys\n",
+		len(b.Samples), len(b.Users), len(b.APIKeys))
+
+	return nil
+}
@@ -0,0 +1,165 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/config"
+)
+
+var errSetFlag = errors.New("The flag must be key=value")
+
+const configUsage = `Usage: swpc-server config <command> [flags]
+
+Commands:
+  validate            Checks the configuration and lists every invalid field.
+                      It exits with 1 if it is not valid
+  print [-effective]  Prints the configuration as json with the secrets
+                      redacted. Without -effective, it prints the defaults
+
+Flags:
+  -effective         Prints the configuration loaded from the defaults,
+                     the config file, the env vars and the -set flags
+  -config <file>     Json or yaml config file
+  -set <key=value>   Sets a config key, for example api.heartbeatInterval=10
+`
+
+// setFlag collects the repeated -set key=value flags
+type setFlag map[string]string
+
+func (s setFlag) String() string {
+	return fmt.Sprint(map[string]string(s))
+}
+
+func (s setFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return errSetFlag
+	}
+
+	s[k] = v
+
+	return nil
+}
+
+// sourceFlags are the flags of the config sources.
+// They are shared by the commands that load the configuration
+type sourceFlags struct {
+	file string
+	sets setFlag
+}
+
+func addSourceFlags(fs *flag.FlagSet) *sourceFlags {
+	f := &sourceFlags{sets: make(setFlag)}
+
+	fs.StringVar(&f.file, "config", "", "Json or yaml config file")
+	fs.Var(f.sets, "set", "Sets a config key (key=value). It can be repeated")
+
+	return f
+}
+
+// sources layers the flags over the environment variables
+func (f *sourceFlags) sources() config.Sources {
+	return config.Sources{
+		File:  f.file,
+		Env:   os.Environ(),
+		Flags: f.sets,
+	}
+}
+
+// loadConfig loads and validates the configuration of the sources.
+// The errors are printed at once
+func loadConfig(src config.Sources) (config.Config, bool) {
+	cnf, err := config.LoadConfig(src)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		return cnf, false
+	}
+
+	return cnf, true
+}
+
+// configCommand runs the commands of the configuration.
+// It returns the exit code
+func configCommand(args []string, stdout io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stdout, configUsage)
+
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	effective := fs.Bool("effective", false, "Prints the loaded configuration")
+	src := addSourceFlags(fs)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	switch args[0] {
+	case "validate":
+		if _, ok := loadConfig(src.sources()); !ok {
+			return 1
+		}
+
+		fmt.Fprintln(stdout, "The configuration is valid")
+
+		return 0
+	case "print":
+		return printConfig(stdout, src, *effective)
+	default:
+		fmt.Fprint(stdout, configUsage)
+
+		return 2
+	}
+}
+
+// printConfig prints the defaults or the loaded configuration.
+// The loaded configuration is printed even if it is not valid
+func printConfig(stdout io.Writer, src *sourceFlags, effective bool) int {
+	cnf := config.Default()
+
+	if effective {
+		var err error
+
+		if cnf, err = config.Load(src.sources()); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+
+			return 1
+		}
+	}
+
+	out := json.NewEncoder(stdout)
+	out.SetIndent("", "  ")
+
+	if err := out.Encode(cnf.Redacted()); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,74 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	iotc "github.com/swpoolcontroller/internal/iot"
+)
+
+const deviceUsage = `Usage: swpc-server device <command> [flags]
+
+Commands:
+  token [-expiration <duration>]  Prints a device token signed with
+                                  api.tokenSecretKey, as the device gets
+                                  it from /auth/token/<client_id>.
+                                  By default, it expires in 5m
+
+The configuration is loaded with the -config and -set flags.
+`
+
+// deviceCommand runs the debugging commands of the device.
+// It returns the exit code
+func deviceCommand(args []string, stdout io.Writer) int {
+	if len(args) == 0 || args[0] != "token" {
+		fmt.Fprint(stdout, deviceUsage)
+
+		return 2
+	}
+
+	fs := flag.NewFlagSet("device "+args[0], flag.ContinueOnError)
+	expiration := fs.Duration(
+		"expiration", iotc.TokenExpiration, "Expiration of the token")
+	src := addSourceFlags(fs)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	t, ok := newTools(src)
+	if !ok {
+		return 1
+	}
+
+	defer t.Close() //nolint:errcheck
+
+	token, err := t.DeviceAuth.Sign(*expiration)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		return 1
+	}
+
+	fmt.Fprintln(stdout, token)
+
+	return 0
+}
@@ -1,28 +1,160 @@
 /*
- *   Copyright (c) 2022 ELIPCERO
- *   All rights reserved.
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
 
- *   Licensed under the Apache License, Version 2.0 (the "License");
- *   you may not use this file except in compliance with the License.
- *   You may obtain a copy of the License at
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
 
- *   http://www.apache.org/licenses/LICENSE-2.0
+*   http://www.apache.org/licenses/LICENSE-2.0
 
- *   Unless required by applicable law or agreed to in writing, software
- *   distributed under the License is distributed on an "AS IS" BASIS,
- *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
- *   See the License for the specific language governing permissions and
- *   limitations under the License.
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
  */
-
 package main
 
-import "github.com/swpoolcontroller/internal"
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/swpoolcontroller/internal"
+	"github.com/swpoolcontroller/internal/config"
+)
+
+// version is set at build time with -ldflags "-X main.version=<version>"
+var version = "dev"
+
+const usage = `Usage: swpc-server [command] [flags]
+
+Commands:
+  serve     Starts the server. It is the default command
+  config    Validates and prints the configuration
+  user      Manages the local accounts
+  secret    Encrypts and decrypts the config values
+  device    Creates a device token for debugging
+  samples   Exports and imports the samples as csv
+  backup    Writes the data of the stores as json
+  restore   Restores a backup in the stores
+  version   Prints the version
+
+Run swpc-server <command> without arguments to see its usage.
+The commands that use the stores load the configuration with the -config
+and -set flags, as serve does, and do not start the server.
+`
 
 func main() {
-	f := internal.NewFactory()
+	args := os.Args[1:]
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "serve":
+			os.Exit(serveCommand(args[1:]))
+		case "user":
+			os.Exit(userCommand(args[1:], os.Stdin, os.Stdout))
+		case "config":
+			os.Exit(configCommand(args[1:], os.Stdout))
+		case "secret":
+			os.Exit(secretCommand(args[1:], os.Stdin, os.Stdout))
+		case "device":
+			os.Exit(deviceCommand(args[1:], os.Stdout))
+		case "samples":
+			os.Exit(samplesCommand(args[1:], os.Stdin, os.Stdout))
+		case "backup":
+			os.Exit(backupCommand(args[1:], os.Stdout))
+		case "restore":
+			os.Exit(restoreCommand(args[1:], os.Stdin, os.Stdout))
+		case "version":
+			fmt.Fprintf(os.Stdout,
+				"swpc-server %s %s\n", version, runtime.Version())
+			os.Exit(0)
+		case "help", "-h", "-help", "--help":
+			fmt.Fprint(os.Stdout, usage)
+			os.Exit(0)
+		default:
+			if !strings.HasPrefix(args[0], "-") {
+				fmt.Fprint(os.Stdout, usage)
+				os.Exit(2)
+			}
+		}
+	}
+
+	// Without command, the flags are the flags of serve
+	os.Exit(serveCommand(args))
+}
+
+// serveCommand starts the server until it is stopped.
+// It returns the exit code
+func serveCommand(args []string) int {
+	fs := flag.NewFlagSet("swpc-server", flag.ExitOnError)
+	src := addSourceFlags(fs)
+	_ = fs.Parse(args)
+
+	// The environment is read once to reload the configuration,
+	// because the json variable is removed once it is loaded
+	sources := src.sources()
+
+	cnf, ok := loadConfig(sources)
+	if !ok {
+		return 1
+	}
+
+	f := internal.NewFactory(cnf)
 	s := internal.NewServer(f)
+	s.Load = func() (config.Config, error) {
+		return config.LoadConfig(sources)
+	}
 	s.Middleware()
 	s.Route()
-	_ = s.Start()
+
+	if err := s.Start(); err != nil {
+		return 1
+	}
+
+	return 0
+}
+
+// newTools loads the configuration of the flags and creates the services
+// of the administration commands, which are closed by them
+func newTools(src *sourceFlags) (*internal.Tools, bool) {
+	cnf, ok := loadConfig(src.sources())
+	if !ok {
+		return nil, false
+	}
+
+	return internal.NewTools(cnf), true
+}
+
+// output is the file or, if the name is empty, the standard output
+func output(name string, stdout io.Writer) (io.Writer, func() error, error) {
+	if name == "" {
+		return stdout, func() error { return nil }, nil
+	}
+
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, file.Close, nil
+}
+
+// input is the file or, if the name is empty, the standard input
+func input(name string, stdin io.Reader) (io.Reader, func() error, error) {
+	if name == "" {
+		return stdin, func() error { return nil }, nil
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, file.Close, nil
 }
@@ -0,0 +1,157 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/ai"
+	"github.com/swpoolcontroller/pkg/strings"
+)
+
+const errSampleInvalid = "The sample is not valid. Sample "
+
+const samplesUsage = `Usage: swpc-server samples <command> [flags]
+
+The csv has the header and the columns temp, ph, orp, chlorine, quality,
+the order that ai/fit.py expects. The quality is 0 (bad), 1 (regular)
+or 2 (good). The import checks the ranges of the values before adding
+any sample.
+
+Commands:
+  export [-output <file>]  Writes the samples of the store as csv.
+                           By default, to the standard output
+  import [-input <file>]   Adds the samples of a csv to the store.
+                           By default, from the standard input
+
+The configuration is loaded with the -config and -set flags.
+`
+
+// samplesCommand exports and imports the samples of the data provider.
+// It returns the exit code
+func samplesCommand(args []string, stdin io.Reader, stdout io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stdout, samplesUsage)
+
+		return 2
+	}
+
+	fs := flag.NewFlagSet("samples "+args[0], flag.ContinueOnError)
+	out := fs.String("output", "", "Csv file")
+	in := fs.String("input", "", "Csv file")
+	src := addSourceFlags(fs)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	var run func(repo ai.SampleRepo) error
+
+	switch args[0] {
+	case "export":
+		run = func(repo ai.SampleRepo) error {
+			return exportSamples(repo, *out, stdout)
+		}
+	case "import":
+		run = func(repo ai.SampleRepo) error {
+			return importSamples(repo, *in, stdin)
+		}
+	default:
+		fmt.Fprint(stdout, samplesUsage)
+
+		return 2
+	}
+
+	t, ok := newTools(src)
+	if !ok {
+		return 1
+	}
+
+	defer t.Close() //nolint:errcheck
+
+	if err := run(t.Stores.Samples); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+func exportSamples(repo ai.SampleRepo, name string, stdout io.Writer) error {
+	samples, err := repo.All()
+	if err != nil {
+		return err
+	}
+
+	w, closeOut, err := output(name, stdout)
+	if err != nil {
+		return err
+	}
+
+	if err := ai.WriteSamplesCSV(w, samples); err != nil {
+		_ = closeOut()
+
+		return err
+	}
+
+	if err := closeOut(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%d samples exported\n", len(samples))
+
+	return nil
+}
+
+func importSamples(repo ai.SampleRepo, name string, stdin io.Reader) error {
+	r, closeIn, err := input(name, stdin)
+	if err != nil {
+		return err
+	}
+
+	defer closeIn() //nolint:errcheck
+
+	samples, err := ai.ReadSamplesCSV(r)
+	if err != nil {
+		return err
+	}
+
+	// Nothing is imported if any sample is out of range
+	for i := range samples {
+		if err := samples[i].Validate(); err != nil {
+			return errors.Wrap(
+				err,
+				strings.Concat(errSampleInvalid, strconv.Itoa(i+1)))
+		}
+	}
+
+	for _, s := range samples {
+		if err := repo.Save(s); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%d samples imported\n", len(samples))
+
+	return nil
+}
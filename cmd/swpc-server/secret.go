@@ -0,0 +1,117 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/config"
+)
+
+var (
+	errValueRead = errors.New("The value cannot be read")
+	errNoKey     = errors.New("The master key is not configured. Use " +
+		"-key-file, " + config.ENVMasterKey + " or " + config.ENVMasterKeyFile)
+)
+
+const secretUsage = `Usage: swpc-server secret <command> [flags]
+
+Encrypts the config values with the master key, so the config file can be
+committed without plain secrets. The value is read from the standard input.
+
+Commands:
+  encrypt  Prints the value as enc:<base64>, ready to be used in the config
+  decrypt  Prints the plain value of an enc:<base64> value
+
+Flags:
+  -key-file <file>  File of the base64 master key. By default, the key is
+                    read from SW_POOL_CONTROLLER_MASTER_KEY or from the file
+                    of SW_POOL_CONTROLLER_MASTER_KEY_FILE
+`
+
+// secretCommand encrypts and decrypts the config values.
+// It returns the exit code
+func secretCommand(args []string, stdin io.Reader, stdout io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stdout, secretUsage)
+
+		return 2
+	}
+
+	fs := flag.NewFlagSet("secret "+args[0], flag.ContinueOnError)
+	keyFile := fs.String("key-file", "", "File of the base64 master key")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	var convert func(key []byte, value string) (string, error)
+
+	switch args[0] {
+	case "encrypt":
+		convert = config.EncryptValue
+	case "decrypt":
+		convert = config.DecryptValue
+	default:
+		fmt.Fprint(stdout, secretUsage)
+
+		return 2
+	}
+
+	value, err := convertSecret(*keyFile, stdin, convert)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		return 1
+	}
+
+	fmt.Fprintln(stdout, value)
+
+	return 0
+}
+
+func convertSecret(
+	keyFile string,
+	stdin io.Reader,
+	convert func(key []byte, value string) (string, error)) (string, error) {
+	//
+	env := os.Environ()
+	if keyFile != "" {
+		env = []string{config.ENVMasterKeyFile + "=" + keyFile}
+	}
+
+	key, err := config.MasterKey(env)
+	if err != nil {
+		return "", err
+	}
+
+	if key == nil {
+		return "", errNoKey
+	}
+
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", errors.Wrap(err, errValueRead.Error())
+	}
+
+	return convert(key, strings.TrimRight(string(data), "\r\n"))
+}
@@ -0,0 +1,207 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal"
+	"github.com/swpoolcontroller/internal/account"
+)
+
+var errPasswordRead = errors.New("The password cannot be read")
+
+const userUsage = `Usage: swpc-server user <command> [flags]
+
+Manages the local accounts of the "local" auth provider.
+The password is read from the standard input.
+
+Commands:
+  add     -username <name> -role <viewer|operator|admin> [-totp]
+  passwd  -username <name>
+  totp    -username <name> [-disable]
+  list
+
+The configuration is loaded with the -config and -set flags.
+`
+
+// userCommand runs the administration of the local accounts.
+// It returns the exit code
+func userCommand(args []string, stdin io.Reader, stdout io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stdout, userUsage)
+
+		return 2
+	}
+
+	fs := flag.NewFlagSet("user "+args[0], flag.ContinueOnError)
+	username := fs.String("username", "", "User name")
+	role := fs.String("role", "viewer", "Role (viewer, operator, admin)")
+	totp := fs.Bool("totp", false, "Enables TOTP as second factor")
+	disable := fs.Bool("disable", false, "Disables TOTP")
+	src := addSourceFlags(fs)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cnf, ok := loadConfig(src.sources())
+	if !ok {
+		return 1
+	}
+
+	accounts, closeDB := internal.NewAccounts(cnf)
+	defer closeDB() //nolint:errcheck
+	in := bufio.NewReader(stdin)
+
+	var err error
+
+	switch args[0] {
+	case "add":
+		err = addUser(accounts, in, stdout, *username, *role, *totp)
+	case "passwd":
+		err = changePassword(accounts, in, stdout, *username)
+	case "totp":
+		err = changeTOTP(accounts, stdout, *username, *disable)
+	case "list":
+		err = listUsers(accounts, stdout)
+	default:
+		fmt.Fprint(stdout, userUsage)
+
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+func addUser(
+	accounts *account.Accounts,
+	in *bufio.Reader,
+	out io.Writer,
+	username string,
+	role string,
+	totp bool) error {
+	//
+	password, err := readPassword(in, out)
+	if err != nil {
+		return err
+	}
+
+	if _, err := accounts.Create(username, password, role); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "User %s created with role %s\n", username, role)
+
+	if totp {
+		return changeTOTP(accounts, out, username, false)
+	}
+
+	return nil
+}
+
+func changePassword(
+	accounts *account.Accounts,
+	in *bufio.Reader,
+	out io.Writer,
+	username string) error {
+	//
+	password, err := readPassword(in, out)
+	if err != nil {
+		return err
+	}
+
+	if err := accounts.SetPassword(username, password); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Password of %s changed\n", username)
+
+	return nil
+}
+
+func changeTOTP(
+	accounts *account.Accounts,
+	out io.Writer,
+	username string,
+	disable bool) error {
+	//
+	if disable {
+		if err := accounts.DisableTOTP(username); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "TOTP of %s disabled\n", username)
+
+		return nil
+	}
+
+	uri, err := accounts.EnableTOTP(username)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(
+		out,
+		"Register this URI in the authenticator app of %s:\n%s\n",
+		username,
+		uri)
+
+	return nil
+}
+
+func listUsers(accounts *account.Accounts, out io.Writer) error {
+	users, err := accounts.List()
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		fmt.Fprintf(out, "%s\t%s\ttotp=%t\n", u.Username, u.Role, u.TOTPSecret != "")
+	}
+
+	return nil
+}
+
+func readPassword(in *bufio.Reader, out io.Writer) (string, error) {
+	fmt.Fprint(out, "Password: ")
+
+	line, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", errors.Wrap(err, errPasswordRead.Error())
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errPasswordRead
+	}
+
+	return password, nil
+}
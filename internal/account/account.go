@@ -0,0 +1,274 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package account
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/pkg/auth"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the user, the password
+	// or the TOTP code are not valid. The cause is not detailed
+	ErrInvalidCredentials = errors.New("The credentials are not valid")
+	// ErrLocked is returned when the user has exceeded the failed logins
+	ErrLocked = errors.New("Too many failed logins. Try again later")
+	// ErrWeakPassword is returned when the password is too short
+	ErrWeakPassword = errors.New("The password must have at least 8 characters")
+	// ErrUserExists is returned when creating an existing user
+	ErrUserExists = errors.New("The user already exists")
+	// ErrUsername is returned when the username is empty
+	ErrUsername = errors.New("The username cannot be empty")
+	// ErrRole is returned when the role is unknown
+	ErrRole = errors.New("The role must be viewer, operator or admin")
+)
+
+const minPasswordLen = 8
+
+// maxPasswordChecks is the number of passwords checked at the same time.
+// An argon2id check takes 64 MiB, so the logins cannot exhaust the memory
+const maxPasswordChecks = 2
+
+// roles are the app roles that can be assigned to the users
+//
+//nolint:gochecknoglobals
+var roles = []string{"viewer", "operator", "admin"}
+
+// Accounts manages the local user accounts
+type Accounts struct {
+	Repo    UserRepo
+	Config  config.LocalAuth
+	Limiter *auth.LoginLimiter
+	// OriginLimiter limits the failed logins of each origin with any user,
+	// so changing the username on every attempt is also locked
+	OriginLimiter *auth.LoginLimiter
+
+	// dummyHash is checked when the user does not exist,
+	// so the response time does not reveal the users
+	dummyHash string
+	// checks limits the passwords checked at the same time
+	checks chan struct{}
+}
+
+// NewAccounts creates the accounts service
+func NewAccounts(repo UserRepo, cnf config.LocalAuth) *Accounts {
+	dummyHash, _ := auth.HashPassword(cnf.Hash, "dummy-password")
+
+	return &Accounts{
+		Repo:   repo,
+		Config: cnf,
+		Limiter: auth.NewLoginLimiter(
+			cnf.MaxAttempts,
+			time.Duration(cnf.LockTime)*time.Minute),
+		OriginLimiter: auth.NewLoginLimiter(
+			cnf.MaxOriginAttempts,
+			time.Duration(cnf.LockTime)*time.Minute),
+		dummyHash: dummyHash,
+		checks:    make(chan struct{}, maxPasswordChecks),
+	}
+}
+
+// Create creates a new user
+func (a *Accounts) Create(
+	username string,
+	password string,
+	role string) (User, error) {
+	//
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return User{}, ErrUsername
+	}
+
+	if !validRole(role) {
+		return User{}, ErrRole
+	}
+
+	if _, err := a.Repo.Get(username); err == nil {
+		return User{}, ErrUserExists
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return User{}, err
+	}
+
+	hash, err := a.hash(password)
+	if err != nil {
+		return User{}, err
+	}
+
+	u := User{Username: username, Hash: hash, Role: role}
+
+	return u, a.Repo.Save(u)
+}
+
+// User gets the user
+func (a *Accounts) User(username string) (User, error) {
+	return a.Repo.Get(username)
+}
+
+// List lists the users
+func (a *Accounts) List() ([]User, error) {
+	return a.Repo.List()
+}
+
+// Authenticate checks the password and, if it is enabled, the TOTP code.
+// The failed logins are limited by user and origin (for example the IP)
+// and by origin with any user
+func (a *Accounts) Authenticate(
+	origin string,
+	username string,
+	password string,
+	code string) (User, error) {
+	//
+	key := username + "|" + origin
+
+	if a.Limiter.Locked(key) || a.OriginLimiter.Locked(origin) {
+		return User{}, ErrLocked
+	}
+
+	u, err := a.Repo.Get(username)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return User{}, err
+	}
+
+	if err != nil {
+		a.checkPassword(a.dummyHash, password)
+		a.Limiter.Fail(key)
+		a.OriginLimiter.Fail(origin)
+
+		return User{}, ErrInvalidCredentials
+	}
+
+	if !a.checkPassword(u.Hash, password) ||
+		(u.TOTPSecret != "" &&
+			!auth.ValidateTOTP(u.TOTPSecret, code, time.Now())) {
+		a.Limiter.Fail(key)
+		a.OriginLimiter.Fail(origin)
+
+		return User{}, ErrInvalidCredentials
+	}
+
+	// The failures of the origin are kept, so a valid login
+	// does not allow guessing the passwords of other users
+	a.Limiter.Reset(key)
+
+	return u, nil
+}
+
+// ChangePassword changes the password of the user
+// checking the current password
+func (a *Accounts) ChangePassword(
+	username string,
+	current string,
+	password string) error {
+	//
+	u, err := a.Repo.Get(username)
+	if err != nil {
+		return err
+	}
+
+	if !a.checkPassword(u.Hash, current) {
+		return ErrInvalidCredentials
+	}
+
+	return a.setPassword(u, password)
+}
+
+// SetPassword sets the password of the user without checking
+// the current one. Only for administration
+func (a *Accounts) SetPassword(username string, password string) error {
+	u, err := a.Repo.Get(username)
+	if err != nil {
+		return err
+	}
+
+	return a.setPassword(u, password)
+}
+
+// EnableTOTP generates a new TOTP secret for the user and returns
+// the otpauth URI to register it in an authenticator app
+func (a *Accounts) EnableTOTP(username string) (string, error) {
+	u, err := a.Repo.Get(username)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	u.TOTPSecret = secret
+
+	if err := a.Repo.Save(u); err != nil {
+		return "", err
+	}
+
+	return auth.TOTPURI(a.Config.Issuer, u.Username, secret), nil
+}
+
+// DisableTOTP removes the second factor of the user
+func (a *Accounts) DisableTOTP(username string) error {
+	u, err := a.Repo.Get(username)
+	if err != nil {
+		return err
+	}
+
+	u.TOTPSecret = ""
+
+	return a.Repo.Save(u)
+}
+
+func (a *Accounts) setPassword(u User, password string) error {
+	hash, err := a.hash(password)
+	if err != nil {
+		return err
+	}
+
+	u.Hash = hash
+
+	return a.Repo.Save(u)
+}
+
+// checkPassword checks the password when there is a free check
+func (a *Accounts) checkPassword(hash string, password string) bool {
+	a.checks <- struct{}{}
+	defer func() { <-a.checks }()
+
+	return auth.CheckPassword(hash, password)
+}
+
+func (a *Accounts) hash(password string) (string, error) {
+	if len(password) < minPasswordLen {
+		return "", ErrWeakPassword
+	}
+
+	return auth.HashPassword(a.Config.Hash, password)
+}
+
+func validRole(role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
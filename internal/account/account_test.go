@@ -0,0 +1,213 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package account_test
+
+import (
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/account"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/pkg/auth"
+	"go.uber.org/zap"
+)
+
+func newAccounts(t *testing.T) *account.Accounts {
+	t.Helper()
+
+	cnf := config.Default().Auth.Local
+	cnf.MaxAttempts = 2
+	cnf.MaxOriginAttempts = 4
+
+	return account.NewAccounts(
+		&account.UserFileRepo{
+			Log:      zap.NewExample(),
+			FileName: filepath.Join(t.TempDir(), "users.json"),
+		},
+		cnf)
+}
+
+func TestAccounts_Create(t *testing.T) {
+	t.Parallel()
+
+	a := newAccounts(t)
+
+	u, err := a.Create("admin", "password1", "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", u.Role)
+	assert.NotEqual(t, "password1", u.Hash)
+
+	_, err = a.Create("admin", "password1", "admin")
+	require.ErrorIs(t, err, account.ErrUserExists)
+
+	_, err = a.Create("other", "short", "admin")
+	require.ErrorIs(t, err, account.ErrWeakPassword)
+
+	_, err = a.Create("other", "password1", "root")
+	require.ErrorIs(t, err, account.ErrRole)
+
+	_, err = a.Create(" ", "password1", "admin")
+	require.ErrorIs(t, err, account.ErrUsername)
+
+	users, err := a.List()
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+}
+
+func TestAccounts_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	a := newAccounts(t)
+
+	_, err := a.Create("user", "password1", "viewer")
+	require.NoError(t, err)
+
+	u, err := a.Authenticate("ip", "user", "password1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "user", u.Username)
+
+	_, err = a.Authenticate("ip", "nobody", "password1", "")
+	require.ErrorIs(t, err, account.ErrInvalidCredentials, "Unknown user")
+
+	_, err = a.Authenticate("ip", "user", "wrong", "")
+	require.ErrorIs(t, err, account.ErrInvalidCredentials, "Wrong password")
+
+	_, err = a.Authenticate("ip", "user", "wrong", "")
+	require.ErrorIs(t, err, account.ErrInvalidCredentials, "Wrong password")
+
+	_, err = a.Authenticate("ip", "user", "password1", "")
+	require.ErrorIs(t, err, account.ErrLocked, "Locked")
+
+	_, err = a.Authenticate("other-ip", "user", "password1", "")
+	require.NoError(t, err, "Other origin")
+}
+
+func TestAccounts_AuthenticateOrigin(t *testing.T) {
+	t.Parallel()
+
+	a := newAccounts(t)
+
+	_, err := a.Create("user", "password1", "viewer")
+	require.NoError(t, err)
+
+	// A different user on every attempt is not locked by the user limiter
+	for i := 0; i < a.Config.MaxOriginAttempts; i++ {
+		_, err = a.Authenticate("ip", "user"+strconv.Itoa(i), "wrong", "")
+		require.ErrorIs(t, err, account.ErrInvalidCredentials)
+	}
+
+	_, err = a.Authenticate("ip", "user", "password1", "")
+	require.ErrorIs(t, err, account.ErrLocked, "Origin locked")
+
+	_, err = a.Authenticate("other-ip", "user", "password1", "")
+	require.NoError(t, err, "Other origin")
+}
+
+func TestAccounts_AuthenticateConcurrent(t *testing.T) {
+	t.Parallel()
+
+	a := newAccounts(t)
+
+	_, err := a.Create("user", "password1", "viewer")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 6)
+
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+
+		go func(origin string) {
+			defer wg.Done()
+
+			_, err := a.Authenticate(origin, "user", "password1", "")
+			errs <- err
+		}("ip" + strconv.Itoa(i))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err, "The checks wait for a free one")
+	}
+}
+
+func TestAccounts_TOTP(t *testing.T) {
+	t.Parallel()
+
+	a := newAccounts(t)
+
+	_, err := a.Create("user", "password1", "viewer")
+	require.NoError(t, err)
+
+	uri, err := a.EnableTOTP("user")
+	require.NoError(t, err)
+	assert.Contains(t, uri, "otpauth://totp/swpc:user")
+
+	u, err := a.User("user")
+	require.NoError(t, err)
+
+	_, err = a.Authenticate("ip", "user", "password1", "")
+	require.ErrorIs(t, err, account.ErrInvalidCredentials, "Without code")
+
+	code, err := auth.TOTPCode(u.TOTPSecret, time.Now())
+	require.NoError(t, err)
+
+	_, err = a.Authenticate("ip", "user", "password1", code)
+	require.NoError(t, err, "With code")
+
+	require.NoError(t, a.DisableTOTP("user"))
+
+	_, err = a.Authenticate("ip", "user", "password1", "")
+	require.NoError(t, err, "Disabled")
+}
+
+func TestAccounts_ChangePassword(t *testing.T) {
+	t.Parallel()
+
+	a := newAccounts(t)
+
+	_, err := a.Create("user", "password1", "viewer")
+	require.NoError(t, err)
+
+	err = a.ChangePassword("user", "wrong", "password2")
+	require.ErrorIs(t, err, account.ErrInvalidCredentials)
+
+	err = a.ChangePassword("user", "password1", "short")
+	require.ErrorIs(t, err, account.ErrWeakPassword)
+
+	require.NoError(t, a.ChangePassword("user", "password1", "password2"))
+
+	_, err = a.Authenticate("ip", "user", "password2", "")
+	require.NoError(t, err)
+
+	require.NoError(t, a.SetPassword("user", "password3"))
+
+	_, err = a.Authenticate("ip", "user", "password3", "")
+	require.NoError(t, err)
+
+	err = a.SetPassword("nobody", "password3")
+	require.ErrorIs(t, err, account.ErrUserNotFound)
+}
@@ -0,0 +1,344 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/pkg/strings"
+	"go.uber.org/zap"
+)
+
+// ErrAPIKeyNotFound is returned when the api key does not exist
+var ErrAPIKeyNotFound = errors.New("The api key does not exist")
+
+const (
+	errReadAPIKeys    = "Reading the api keys: "
+	errUnmarsAPIKeys  = "Unmarshalling the api keys: "
+	errMarshalAPIKeys = "Marshalling the api keys: "
+	errSaveAPIKeys    = "Saving the api keys: "
+)
+
+const (
+	infSavingAPIKey   = "Saving api key"
+	infDeletingAPIKey = "Deleting api key"
+	infAPIKey         = "APIKey"
+)
+
+// APIKey is a long-lived key of a third-party integration.
+// The key itself is never stored, only its hash
+type APIKey struct {
+	ID string `json:"id" dynamodbav:"id"`
+	// Name describes the integration that uses the key
+	Name string `json:"name" dynamodbav:"name"`
+	// Hash is the SHA-256 of the key
+	Hash string `json:"hash" dynamodbav:"hash"`
+	// Scopes are the permissions granted to the key
+	Scopes []string `json:"scopes" dynamodbav:"scopes"`
+	// Created is the creation time
+	Created time.Time `json:"created" dynamodbav:"created"`
+}
+
+// APIKeyRepo defines the api keys repository
+type APIKeyRepo interface {
+	// Get gets the api key. ErrAPIKeyNotFound if it does not exist
+	Get(id string) (APIKey, error)
+	// List lists the api keys sorted by creation time
+	List() ([]APIKey, error)
+	// Save creates or updates the api key
+	Save(key APIKey) error
+	// Delete deletes the api key. ErrAPIKeyNotFound if it does not exist
+	Delete(id string) error
+}
+
+// APIKeyFileRepo stores the api keys in a json file.
+// Operations are protected against concurrency
+type APIKeyFileRepo struct {
+	Log      *zap.Logger
+	FileName string
+
+	lock sync.Mutex
+}
+
+// Get gets the api key from the file
+func (r *APIKeyFileRepo) Get(id string) (APIKey, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	keys, err := r.read()
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	k, ok := keys[id]
+	if !ok {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+
+	return k, nil
+}
+
+// List lists the api keys of the file
+func (r *APIKeyFileRepo) List() ([]APIKey, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	keys, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]APIKey, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, k)
+	}
+
+	sortAPIKeys(res)
+
+	return res, nil
+}
+
+// Save saves the api key into the file
+func (r *APIKeyFileRepo) Save(key APIKey) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.Log.Info(
+		infSavingAPIKey,
+		zap.String(infAPIKey, key.ID),
+		zap.String(infFile, r.FileName))
+
+	keys, err := r.read()
+	if err != nil {
+		return err
+	}
+
+	keys[key.ID] = key
+
+	return r.write(keys)
+}
+
+// Delete deletes the api key from the file
+func (r *APIKeyFileRepo) Delete(id string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.Log.Info(
+		infDeletingAPIKey,
+		zap.String(infAPIKey, id),
+		zap.String(infFile, r.FileName))
+
+	keys, err := r.read()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := keys[id]; !ok {
+		return ErrAPIKeyNotFound
+	}
+
+	delete(keys, id)
+
+	return r.write(keys)
+}
+
+// read reads all the api keys. If the file not exists there are no keys
+func (r *APIKeyFileRepo) read() (map[string]APIKey, error) {
+	keys := make(map[string]APIKey)
+
+	data, err := os.ReadFile(r.FileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return keys, nil
+		}
+
+		return nil, errors.Wrap(
+			err,
+			strings.Concat(errReadAPIKeys, r.FileName))
+	}
+
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, errors.Wrap(
+			err,
+			strings.Concat(errUnmarsAPIKeys, r.FileName))
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyFileRepo) write(keys map[string]APIKey) error {
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errMarshalAPIKeys, r.FileName))
+	}
+
+	// Only the owner can read the hashes
+	if err := os.WriteFile(r.FileName, data, os.FileMode(0600)); err != nil {
+		return errors.Wrap(err, strings.Concat(errSaveAPIKeys, r.FileName))
+	}
+
+	return nil
+}
+
+// APIKeyAWSDynamoRepo stores the api keys in AWS dynamodb
+type APIKeyAWSDynamoRepo struct {
+	log       *zap.Logger
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewAPIKeyAWSDynamoRepo creates the AWS dynamo repository
+func NewAPIKeyAWSDynamoRepo(
+	cfg aws.Config,
+	log *zap.Logger,
+	tableName string) *APIKeyAWSDynamoRepo {
+	//
+	return &APIKeyAWSDynamoRepo{
+		log:       log,
+		client:    dynamodb.NewFromConfig(cfg),
+		tableName: tableName,
+	}
+}
+
+// Get gets the api key from dynamodb
+func (r *APIKeyAWSDynamoRepo) Get(id string) (APIKey, error) {
+	res, err := r.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       r.key(id),
+	})
+	if err != nil {
+		return APIKey{}, errors.Wrap(
+			err,
+			strings.Concat(errReadAPIKeys, r.tableName))
+	}
+
+	if len(res.Item) == 0 {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+
+	var k APIKey
+
+	if err := attributevalue.UnmarshalMap(res.Item, &k); err != nil {
+		return APIKey{}, errors.Wrap(
+			err,
+			strings.Concat(errUnmarsAPIKeys, r.tableName))
+	}
+
+	return k, nil
+}
+
+// List lists the api keys of dynamodb
+func (r *APIKeyAWSDynamoRepo) List() ([]APIKey, error) {
+	var keys []APIKey
+
+	p := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	})
+
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errReadAPIKeys, r.tableName))
+		}
+
+		var ks []APIKey
+
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &ks); err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errUnmarsAPIKeys, r.tableName))
+		}
+
+		keys = append(keys, ks...)
+	}
+
+	sortAPIKeys(keys)
+
+	return keys, nil
+}
+
+// Save saves the api key into dynamodb
+func (r *APIKeyAWSDynamoRepo) Save(key APIKey) error {
+	r.log.Info(
+		infSavingAPIKey,
+		zap.String(infAPIKey, key.ID),
+		zap.String(infFile, r.tableName))
+
+	item, err := attributevalue.MarshalMap(key)
+	if err != nil {
+		return errors.Wrap(
+			err,
+			strings.Concat(errMarshalAPIKeys, r.tableName))
+	}
+
+	if _, err := r.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(r.tableName),
+		Item:      item,
+	}); err != nil {
+		return errors.Wrap(err, strings.Concat(errSaveAPIKeys, r.tableName))
+	}
+
+	return nil
+}
+
+// Delete deletes the api key from dynamodb
+func (r *APIKeyAWSDynamoRepo) Delete(id string) error {
+	r.log.Info(
+		infDeletingAPIKey,
+		zap.String(infAPIKey, id),
+		zap.String(infFile, r.tableName))
+
+	res, err := r.client.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName:    aws.String(r.tableName),
+		Key:          r.key(id),
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errSaveAPIKeys, r.tableName))
+	}
+
+	if len(res.Attributes) == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *APIKeyAWSDynamoRepo) key(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		dynamoDBTableKeyName: &types.AttributeValueMemberS{Value: id},
+	}
+}
+
+func sortAPIKeys(keys []APIKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created.Before(keys[j].Created)
+	})
+}
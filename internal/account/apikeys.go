@@ -0,0 +1,176 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package account
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+var (
+	// ErrInvalidAPIKey is returned when the api key is malformed,
+	// revoked or its secret does not match
+	ErrInvalidAPIKey = errors.New("The api key is not valid")
+	// ErrAPIKeyName is returned when the name of the api key is empty
+	ErrAPIKeyName = errors.New("The api key name cannot be empty")
+	// ErrScope is returned when the scopes are empty or unknown
+	ErrScope = errors.New("The scopes must be metrics:read, config:read, " +
+		"config:write, sample:read or sample:write")
+)
+
+const (
+	// APIKeyPrefix identifies the api keys in the bearer header
+	APIKeyPrefix = "swpc_"
+	// apiKeySecretLen is the number of random bytes of the secret
+	apiKeySecretLen = 32
+)
+
+// scopes are the permissions that can be granted to the api keys.
+// The administration is never granted
+//
+//nolint:gochecknoglobals
+var scopes = []string{
+	"metrics:read",
+	"config:read",
+	"config:write",
+	"sample:read",
+	"sample:write",
+}
+
+// APIKeys manages the api keys of the third-party integrations
+type APIKeys struct {
+	Repo APIKeyRepo
+}
+
+// Create creates a new api key. The key is only returned here,
+// the repository only stores its hash
+func (a *APIKeys) Create(
+	name string,
+	keyScopes []string) (string, APIKey, error) {
+	//
+	if strings.TrimSpace(name) == "" {
+		return "", APIKey{}, ErrAPIKeyName
+	}
+
+	if !validScopes(keyScopes) {
+		return "", APIKey{}, ErrScope
+	}
+
+	secret := make([]byte, apiKeySecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return "", APIKey{}, errors.Wrap(err, "Generating the api key")
+	}
+
+	id := xid.New().String()
+	raw := APIKeyPrefix + id + "." +
+		base64.RawURLEncoding.EncodeToString(secret)
+
+	k := APIKey{
+		ID:      id,
+		Name:    name,
+		Hash:    hashAPIKey(raw),
+		Scopes:  keyScopes,
+		Created: time.Now().UTC(),
+	}
+
+	if err := a.Repo.Save(k); err != nil {
+		return "", APIKey{}, err
+	}
+
+	return raw, k, nil
+}
+
+// List lists the api keys
+func (a *APIKeys) List() ([]APIKey, error) {
+	return a.Repo.List()
+}
+
+// Revoke deletes the api key so it can no longer be used
+func (a *APIKeys) Revoke(id string) error {
+	return a.Repo.Delete(id)
+}
+
+// Authenticate gets the api key of the raw key sent by the integration
+func (a *APIKeys) Authenticate(raw string) (APIKey, error) {
+	if !strings.HasPrefix(raw, APIKeyPrefix) {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+
+	id, _, ok := strings.Cut(raw[len(APIKeyPrefix):], ".")
+	if !ok || id == "" {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+
+	k, err := a.Repo.Get(id)
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return APIKey{}, ErrInvalidAPIKey
+		}
+
+		return APIKey{}, err
+	}
+
+	if subtle.ConstantTimeCompare(
+		[]byte(hashAPIKey(raw)),
+		[]byte(k.Hash)) != 1 {
+		//
+		return APIKey{}, ErrInvalidAPIKey
+	}
+
+	return k, nil
+}
+
+// hashAPIKey hashes the key. The keys are random with enough entropy,
+// so a slow password hash is not needed
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func validScopes(keyScopes []string) bool {
+	if len(keyScopes) == 0 {
+		return false
+	}
+
+	for _, s := range keyScopes {
+		valid := false
+
+		for _, v := range scopes {
+			if s == v {
+				valid = true
+
+				break
+			}
+		}
+
+		if !valid {
+			return false
+		}
+	}
+
+	return true
+}
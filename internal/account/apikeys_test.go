@@ -0,0 +1,98 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package account_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/account"
+	"go.uber.org/zap"
+)
+
+func newAPIKeys(t *testing.T) *account.APIKeys {
+	t.Helper()
+
+	return &account.APIKeys{
+		Repo: &account.APIKeyFileRepo{
+			Log:      zap.NewExample(),
+			FileName: filepath.Join(t.TempDir(), "apikeys.json"),
+		},
+	}
+}
+
+func TestAPIKeys_Create(t *testing.T) {
+	t.Parallel()
+
+	a := newAPIKeys(t)
+
+	raw, k, err := a.Create("facility", []string{"metrics:read"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, account.APIKeyPrefix))
+	assert.NotContains(t, k.Hash, raw)
+	assert.NotEqual(t, raw, k.Hash)
+
+	keys, err := a.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, k.ID, keys[0].ID)
+	assert.Equal(t, []string{"metrics:read"}, keys[0].Scopes)
+
+	_, _, err = a.Create("", []string{"metrics:read"})
+	require.ErrorIs(t, err, account.ErrAPIKeyName)
+
+	_, _, err = a.Create("facility", nil)
+	require.ErrorIs(t, err, account.ErrScope)
+
+	_, _, err = a.Create("facility", []string{"admin"})
+	require.ErrorIs(t, err, account.ErrScope)
+}
+
+func TestAPIKeys_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	a := newAPIKeys(t)
+
+	raw, k, err := a.Create("facility", []string{"config:read"})
+	require.NoError(t, err)
+
+	got, err := a.Authenticate(raw)
+	require.NoError(t, err)
+	assert.Equal(t, k.ID, got.ID)
+
+	for _, bad := range []string{
+		"",
+		"token",
+		account.APIKeyPrefix + k.ID,
+		account.APIKeyPrefix + k.ID + ".other",
+		account.APIKeyPrefix + "unknown.secret",
+	} {
+		_, err = a.Authenticate(bad)
+		require.ErrorIs(t, err, account.ErrInvalidAPIKey, bad)
+	}
+
+	require.NoError(t, a.Revoke(k.ID))
+
+	_, err = a.Authenticate(raw)
+	require.ErrorIs(t, err, account.ErrInvalidAPIKey)
+
+	require.ErrorIs(t, a.Revoke(k.ID), account.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,237 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package account
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	errSQLUsers   = "Accessing the users of the sql database"
+	errSQLAPIKeys = "Accessing the api keys of the sql database"
+)
+
+// Tables of the accounts in the sql database
+const (
+	sqlUsersTable   = "users"
+	sqlAPIKeysTable = "api_keys"
+)
+
+// sqlUserColumns selects the columns of the users table
+const sqlUserColumns = "SELECT username, hash, role, totp_secret FROM users"
+
+// sqlAPIKeyColumns selects the columns of the api_keys table
+const sqlAPIKeyColumns = "SELECT id, name, hash, scopes, created " +
+	"FROM api_keys"
+
+// rowScanner is implemented by sql.Row and sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// UserSQLRepo stores the users in the users table of the sql database
+type UserSQLRepo struct {
+	Log *zap.Logger
+	DB  *sql.DB
+}
+
+// Get reads the user
+func (r *UserSQLRepo) Get(username string) (User, error) {
+	u, err := scanUser(r.DB.QueryRow(
+		sqlUserColumns+" WHERE username = ?",
+		username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	}
+
+	return u, errors.Wrap(err, errSQLUsers)
+}
+
+// List reads the users sorted by name
+func (r *UserSQLRepo) List() ([]User, error) {
+	rows, err := r.DB.Query(sqlUserColumns + " ORDER BY username")
+	if err != nil {
+		return nil, errors.Wrap(err, errSQLUsers)
+	}
+
+	defer rows.Close()
+
+	users := []User{}
+
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, errSQLUsers)
+		}
+
+		users = append(users, u)
+	}
+
+	return users, errors.Wrap(rows.Err(), errSQLUsers)
+}
+
+// Save inserts or updates the user
+func (r *UserSQLRepo) Save(user User) error {
+	r.Log.Info(
+		infSavingUser,
+		zap.String(infUser, user.Username),
+		zap.String(infFile, sqlUsersTable))
+
+	_, err := r.DB.Exec(
+		"INSERT INTO users (username, hash, role, totp_secret) "+
+			"VALUES (?, ?, ?, ?) ON CONFLICT (username) DO UPDATE SET "+
+			"hash = excluded.hash, role = excluded.role, "+
+			"totp_secret = excluded.totp_secret",
+		user.Username,
+		user.Hash,
+		user.Role,
+		user.TOTPSecret)
+
+	return errors.Wrap(err, errSQLUsers)
+}
+
+func scanUser(row rowScanner) (User, error) {
+	var u User
+
+	err := row.Scan(&u.Username, &u.Hash, &u.Role, &u.TOTPSecret)
+
+	return u, err
+}
+
+// APIKeySQLRepo stores the api keys in the api_keys table
+// of the sql database
+type APIKeySQLRepo struct {
+	Log *zap.Logger
+	DB  *sql.DB
+}
+
+// Get reads the api key
+func (r *APIKeySQLRepo) Get(id string) (APIKey, error) {
+	k, err := scanAPIKey(r.DB.QueryRow(sqlAPIKeyColumns+" WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+
+	return k, errors.Wrap(err, errSQLAPIKeys)
+}
+
+// List reads the api keys sorted by creation time
+func (r *APIKeySQLRepo) List() ([]APIKey, error) {
+	rows, err := r.DB.Query(sqlAPIKeyColumns)
+	if err != nil {
+		return nil, errors.Wrap(err, errSQLAPIKeys)
+	}
+
+	defer rows.Close()
+
+	keys := []APIKey{}
+
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, errSQLAPIKeys)
+		}
+
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, errSQLAPIKeys)
+	}
+
+	sortAPIKeys(keys)
+
+	return keys, nil
+}
+
+// Save inserts or updates the api key
+func (r *APIKeySQLRepo) Save(key APIKey) error {
+	r.Log.Info(
+		infSavingAPIKey,
+		zap.String(infAPIKey, key.ID),
+		zap.String(infFile, sqlAPIKeysTable))
+
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return errors.Wrap(err, errSQLAPIKeys)
+	}
+
+	_, err = r.DB.Exec(
+		"INSERT INTO api_keys (id, name, hash, scopes, created) "+
+			"VALUES (?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET "+
+			"name = excluded.name, hash = excluded.hash, "+
+			"scopes = excluded.scopes, created = excluded.created",
+		key.ID,
+		key.Name,
+		key.Hash,
+		string(scopes),
+		key.Created.UTC().Format(time.RFC3339Nano))
+
+	return errors.Wrap(err, errSQLAPIKeys)
+}
+
+// Delete deletes the api key
+func (r *APIKeySQLRepo) Delete(id string) error {
+	r.Log.Info(
+		infDeletingAPIKey,
+		zap.String(infAPIKey, id),
+		zap.String(infFile, sqlAPIKeysTable))
+
+	res, err := r.DB.Exec("DELETE FROM api_keys WHERE id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, errSQLAPIKeys)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, errSQLAPIKeys)
+	}
+
+	if n == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func scanAPIKey(row rowScanner) (APIKey, error) {
+	var (
+		k       APIKey
+		scopes  string
+		created string
+	)
+
+	if err := row.Scan(&k.ID, &k.Name, &k.Hash, &scopes, &created); err != nil {
+		return APIKey{}, err
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &k.Scopes); err != nil {
+		return APIKey{}, err
+	}
+
+	var err error
+
+	k.Created, err = time.Parse(time.RFC3339Nano, created)
+
+	return k, err
+}
@@ -0,0 +1,95 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package account_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/account"
+	"github.com/swpoolcontroller/internal/sqldb"
+	"go.uber.org/zap"
+)
+
+func TestSQLRepos(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqldb.Open(filepath.Join(t.TempDir(), "swpc.db"))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	users := &account.UserSQLRepo{Log: zap.NewExample(), DB: db}
+
+	_, err = users.Get("admin")
+	require.ErrorIs(t, err, account.ErrUserNotFound)
+
+	admin := account.User{Username: "admin", Hash: "h1", Role: "admin"}
+	viewer := account.User{Username: "ana", Hash: "h2", Role: "viewer"}
+
+	require.NoError(t, users.Save(admin))
+	require.NoError(t, users.Save(viewer))
+
+	admin.TOTPSecret = "secret"
+	require.NoError(t, users.Save(admin), "Update")
+
+	u, err := users.Get("admin")
+
+	require.NoError(t, err)
+	assert.Equal(t, admin, u)
+
+	list, err := users.List()
+
+	require.NoError(t, err)
+	assert.Equal(t, []account.User{admin, viewer}, list, "Sorted by name")
+
+	keys := &account.APIKeySQLRepo{Log: zap.NewExample(), DB: db}
+
+	newer := account.APIKey{
+		ID: "2", Name: "grafana", Hash: "h2", Scopes: []string{"read"},
+		Created: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+	}
+	older := account.APIKey{
+		ID: "1", Name: "ha", Hash: "h1",
+		Scopes:  []string{"read", "sample:read"},
+		Created: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	require.NoError(t, keys.Save(newer))
+	require.NoError(t, keys.Save(older))
+
+	k, err := keys.Get("1")
+
+	require.NoError(t, err)
+	assert.Equal(t, older, k)
+
+	klist, err := keys.List()
+
+	require.NoError(t, err)
+	assert.Equal(t, []account.APIKey{older, newer}, klist, "By creation")
+
+	require.NoError(t, keys.Delete("1"))
+	require.ErrorIs(t, keys.Delete("1"), account.ErrAPIKeyNotFound)
+
+	_, err = keys.Get("1")
+
+	require.ErrorIs(t, err, account.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,278 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/pkg/strings"
+	"go.uber.org/zap"
+)
+
+// ErrUserNotFound is returned when the user does not exist
+var ErrUserNotFound = errors.New("The user does not exist")
+
+const (
+	errReadUsers    = "Reading the users: "
+	errUnmarsUsers  = "Unmarshalling the users: "
+	errMarshalUsers = "Marshalling the users: "
+	errSaveUsers    = "Saving the users: "
+)
+
+const (
+	infSavingUser = "Saving user"
+	infUser       = "User"
+	infFile       = "file"
+)
+
+// AWS dynamodb field
+const (
+	dynamoDBTableKeyName = "id"
+)
+
+// User is a local account
+type User struct {
+	Username string `json:"username" dynamodbav:"id"`
+	// Hash is the password hash including the algorithm and the salt
+	Hash string `json:"hash" dynamodbav:"hash"`
+	// Role is the app role (viewer, operator, admin)
+	Role string `json:"role" dynamodbav:"role"`
+	// TOTPSecret enables the second factor if it is not empty
+	TOTPSecret string `json:"totpSecret,omitempty" dynamodbav:"totpSecret,omitempty"`
+}
+
+// UserRepo defines the users repository
+type UserRepo interface {
+	// Get gets the user. ErrUserNotFound if it does not exist
+	Get(username string) (User, error)
+	// List lists the users sorted by name
+	List() ([]User, error)
+	// Save creates or updates the user
+	Save(user User) error
+}
+
+// UserFileRepo stores the users in a json file.
+// Operations are protected against concurrency
+type UserFileRepo struct {
+	Log      *zap.Logger
+	FileName string
+
+	lock sync.Mutex
+}
+
+// Get gets the user from the file
+func (r *UserFileRepo) Get(username string) (User, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	users, err := r.read()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := users[username]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	return u, nil
+}
+
+// List lists the users of the file
+func (r *UserFileRepo) List() ([]User, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	users, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]User, 0, len(users))
+	for _, u := range users {
+		res = append(res, u)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Username < res[j].Username
+	})
+
+	return res, nil
+}
+
+// Save saves the user into the file
+func (r *UserFileRepo) Save(user User) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.Log.Info(
+		infSavingUser,
+		zap.String(infUser, user.Username),
+		zap.String(infFile, r.FileName))
+
+	users, err := r.read()
+	if err != nil {
+		return err
+	}
+
+	users[user.Username] = user
+
+	data, err := json.Marshal(users)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errMarshalUsers, r.FileName))
+	}
+
+	// Only the owner can read the hashes
+	if err := os.WriteFile(r.FileName, data, os.FileMode(0600)); err != nil {
+		return errors.Wrap(err, strings.Concat(errSaveUsers, r.FileName))
+	}
+
+	return nil
+}
+
+// read reads all the users. If the file not exists there are no users
+func (r *UserFileRepo) read() (map[string]User, error) {
+	users := make(map[string]User)
+
+	data, err := os.ReadFile(r.FileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return users, nil
+		}
+
+		return nil, errors.Wrap(err, strings.Concat(errReadUsers, r.FileName))
+	}
+
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, errors.Wrap(err, strings.Concat(errUnmarsUsers, r.FileName))
+	}
+
+	return users, nil
+}
+
+// UserAWSDynamoRepo stores the users in AWS dynamodb
+type UserAWSDynamoRepo struct {
+	log       *zap.Logger
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewUserAWSDynamoRepo creates the AWS dynamo repository
+func NewUserAWSDynamoRepo(
+	cfg aws.Config,
+	log *zap.Logger,
+	tableName string) *UserAWSDynamoRepo {
+	//
+	return &UserAWSDynamoRepo{
+		log:       log,
+		client:    dynamodb.NewFromConfig(cfg),
+		tableName: tableName,
+	}
+}
+
+// Get gets the user from dynamodb
+func (r *UserAWSDynamoRepo) Get(username string) (User, error) {
+	res, err := r.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			dynamoDBTableKeyName: &types.AttributeValueMemberS{Value: username},
+		},
+	})
+	if err != nil {
+		return User{}, errors.Wrap(err, strings.Concat(errReadUsers, r.tableName))
+	}
+
+	if len(res.Item) == 0 {
+		return User{}, ErrUserNotFound
+	}
+
+	var u User
+
+	if err := attributevalue.UnmarshalMap(res.Item, &u); err != nil {
+		return User{}, errors.Wrap(
+			err,
+			strings.Concat(errUnmarsUsers, r.tableName))
+	}
+
+	return u, nil
+}
+
+// List lists the users of dynamodb
+func (r *UserAWSDynamoRepo) List() ([]User, error) {
+	var users []User
+
+	p := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	})
+
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errReadUsers, r.tableName))
+		}
+
+		var us []User
+
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &us); err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errUnmarsUsers, r.tableName))
+		}
+
+		users = append(users, us...)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	return users, nil
+}
+
+// Save saves the user into dynamodb
+func (r *UserAWSDynamoRepo) Save(user User) error {
+	r.log.Info(
+		infSavingUser,
+		zap.String(infUser, user.Username),
+		zap.String(infFile, r.tableName))
+
+	item, err := attributevalue.MarshalMap(user)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errMarshalUsers, r.tableName))
+	}
+
+	if _, err := r.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(r.tableName),
+		Item:      item,
+	}); err != nil {
+		return errors.Wrap(err, strings.Concat(errSaveUsers, r.tableName))
+	}
+
+	return nil
+}
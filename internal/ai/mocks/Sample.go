@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	io "io"
+
 	mock "github.com/stretchr/testify/mock"
 	"github.com/swpoolcontroller/internal/ai"
 )
@@ -26,6 +28,108 @@ func (_m *SampleRepo) Save(data ai.SampleData) error {
 	return r0
 }
 
+// All provides a mock function with given fields:
+func (_m *SampleRepo) All() ([]ai.SampleData, error) {
+	ret := _m.Called()
+
+	var r0 []ai.SampleData
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]ai.SampleData, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []ai.SampleData); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ai.SampleData)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: filter
+func (_m *SampleRepo) List(filter ai.SampleFilter) (ai.SamplePage, error) {
+	ret := _m.Called(filter)
+
+	var r0 ai.SamplePage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(ai.SampleFilter) (ai.SamplePage, error)); ok {
+		return rf(filter)
+	}
+	if rf, ok := ret.Get(0).(func(ai.SampleFilter) ai.SamplePage); ok {
+		r0 = rf(filter)
+	} else {
+		r0 = ret.Get(0).(ai.SamplePage)
+	}
+
+	if rf, ok := ret.Get(1).(func(ai.SampleFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Get provides a mock function with given fields: id
+func (_m *SampleRepo) Get(id string) (ai.SampleData, error) {
+	ret := _m.Called(id)
+
+	var r0 ai.SampleData
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (ai.SampleData, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) ai.SampleData); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(ai.SampleData)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: id
+func (_m *SampleRepo) Delete(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Export provides a mock function with given fields: w
+func (_m *SampleRepo) Export(w io.Writer) error {
+	ret := _m.Called(w)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(io.Writer) error); ok {
+		r0 = rf(w)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewSampleRepo creates a new instance of SampleRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewSampleRepo(t interface {
@@ -0,0 +1,292 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package ai
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/pkg/strings"
+	"go.uber.org/zap"
+)
+
+// ErrModel is returned when the json of a model is not valid
+var ErrModel = errors.New("The model is not valid")
+
+const (
+	errReadModel  = "Reading the model: "
+	errModelInput = "The metric to predict is not a number: "
+)
+
+const (
+	infModels = "Predicting with the json models"
+)
+
+// Types of the models exported by ai/fit.py
+const (
+	treeModelType   = "decision_tree"
+	linearModelType = "linear_regression"
+)
+
+const (
+	// WQModelJSONFile is the water quality model exported by ai/fit.py
+	WQModelJSONFile = wqModelFile + ".json"
+	// CLModelJSONFile is the chlorine model exported by ai/fit.py
+	CLModelJSONFile = clModelFile + ".json"
+)
+
+// leafNode is the child of the leaves of the tree
+const leafNode = -1
+
+// TreeNode is a node of the decision tree
+type TreeNode struct {
+	// Left and Right are the positions of the children, -1 in the leaves
+	Left  int `json:"left"`
+	Right int `json:"right"`
+	// Feature is the position of the feature that splits the node
+	Feature   int     `json:"feature"`
+	Threshold float64 `json:"threshold"`
+	// Value is the weight of every class in the node
+	Value []float64 `json:"value"`
+}
+
+// TreeModel is the decision tree classifier of the water quality
+type TreeModel struct {
+	Type     string     `json:"type"`
+	Features []string   `json:"features"`
+	Classes  []string   `json:"classes"`
+	Nodes    []TreeNode `json:"nodes"`
+}
+
+// Validate checks that every node is reachable from the root only once,
+// so the prediction always ends in a leaf
+func (m *TreeModel) Validate() error {
+	if m.Type != treeModelType || len(m.Nodes) == 0 || len(m.Classes) == 0 {
+		return ErrModel
+	}
+
+	for i, n := range m.Nodes {
+		if len(n.Value) != len(m.Classes) {
+			return ErrModel
+		}
+
+		if n.Left == leafNode && n.Right == leafNode {
+			continue
+		}
+
+		// The children are after the parent, as in the fitted tree
+		if n.Left <= i || n.Right <= i ||
+			n.Left >= len(m.Nodes) || n.Right >= len(m.Nodes) ||
+			n.Feature < 0 || n.Feature >= len(m.Features) {
+			//
+			return ErrModel
+		}
+	}
+
+	return nil
+}
+
+// Predict goes down the tree to the leaf of the features and returns
+// the class with the greatest value. The features are in the order of
+// Features
+func (m *TreeModel) Predict(x []float64) string {
+	n := m.Nodes[0]
+
+	for n.Left != leafNode {
+		// The tree compares the features as float32, as sklearn does
+		if float64(float32(x[n.Feature])) <= n.Threshold {
+			n = m.Nodes[n.Left]
+		} else {
+			n = m.Nodes[n.Right]
+		}
+	}
+
+	best := 0
+
+	for i, v := range n.Value {
+		if v > n.Value[best] {
+			best = i
+		}
+	}
+
+	return m.Classes[best]
+}
+
+// LinearModel is the linear regression of the chlorine. The features
+// are standardized with the mean and the scale of the fit
+type LinearModel struct {
+	Type         string    `json:"type"`
+	Features     []string  `json:"features"`
+	Mean         []float64 `json:"mean"`
+	Scale        []float64 `json:"scale"`
+	Coefficients []float64 `json:"coefficients"`
+	Intercept    float64   `json:"intercept"`
+}
+
+// Validate checks that there is a mean, a scale and a coefficient
+// for every feature
+func (m *LinearModel) Validate() error {
+	n := len(m.Features)
+
+	if m.Type != linearModelType || n == 0 || len(m.Mean) != n ||
+		len(m.Scale) != n || len(m.Coefficients) != n {
+		//
+		return ErrModel
+	}
+
+	for _, s := range m.Scale {
+		if s == 0 {
+			return ErrModel
+		}
+	}
+
+	return nil
+}
+
+// Predict standardizes the features and applies the regression.
+// The features are in the order of Features
+func (m *LinearModel) Predict(x []float64) float64 {
+	y := m.Intercept
+
+	for i, c := range m.Coefficients {
+		y += c * (x[i] - m.Mean[i]) / m.Scale[i]
+	}
+
+	return y
+}
+
+// readModel reads and validates the json model
+func readModel(file string, model interface{ Validate() error }) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errReadModel, file))
+	}
+
+	if err := json.Unmarshal(data, model); err != nil {
+		return errors.Wrap(err, strings.Concat(errReadModel, file))
+	}
+
+	return errors.Wrap(model.Validate(), strings.Concat(errReadModel, file))
+}
+
+// ModelPrediction predicts with the json models exported by ai/fit.py,
+// without starting python. The models are read once
+type ModelPrediction struct {
+	Log *zap.Logger
+	WQ  *TreeModel
+	CL  *LinearModel
+}
+
+// NewModelPrediction reads the water quality and the chlorine models.
+// The error wraps os.ErrNotExist if any of them does not exist
+func NewModelPrediction(
+	log *zap.Logger,
+	wqFile string,
+	clFile string) (*ModelPrediction, error) {
+	//
+	p := &ModelPrediction{Log: log, WQ: &TreeModel{}, CL: &LinearModel{}}
+
+	if err := readModel(wqFile, p.WQ); err != nil {
+		return nil, err
+	}
+
+	if err := readModel(clFile, p.CL); err != nil {
+		return nil, err
+	}
+
+	// The metrics are the features of the samples
+	known := map[string]float64{
+		dynamoDBTableTemp: 0,
+		dynamoDBTablePH:   0,
+		dynamoDBTableORP:  0,
+	}
+
+	for _, names := range [][]string{p.WQ.Features, p.CL.Features} {
+		if _, err := features(known, names); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Predict predicts the water quality with the temperature, the ph and
+// the orp and the chlorine with the ph and the orp, in the order of
+// ai/fit.py. The result is "<quality>;<chlorine>" as predict.sh prints
+func (p *ModelPrediction) Predict(
+	temp string,
+	ph string,
+	orp string) (string, error) {
+	//
+	values := map[string]float64{}
+
+	for _, m := range []struct {
+		name  string
+		value string
+	}{
+		{dynamoDBTableTemp, temp},
+		{dynamoDBTablePH, ph},
+		{dynamoDBTableORP, orp},
+	} {
+		v, err := strconv.ParseFloat(m.value, 64)
+		if err != nil {
+			return "", errors.Wrap(err, strings.Concat(errModelInput, m.name))
+		}
+
+		values[m.name] = v
+	}
+
+	wq, err := features(values, p.WQ.Features)
+	if err != nil {
+		return "", err
+	}
+
+	cl, err := features(values, p.CL.Features)
+	if err != nil {
+		return "", err
+	}
+
+	p.Log.Info(
+		infModels,
+		zap.String("temp", temp),
+		zap.String("ph", ph),
+		zap.String("orp", orp))
+
+	return strings.Concat(
+		p.WQ.Predict(wq),
+		";",
+		strconv.FormatFloat(p.CL.Predict(cl), 'f', -1, 64)), nil
+}
+
+// features orders the metrics as the features of the model
+func features(values map[string]float64, names []string) ([]float64, error) {
+	x := make([]float64, 0, len(names))
+
+	for _, name := range names {
+		v, ok := values[name]
+		if !ok {
+			return nil, errors.Wrap(ErrModel, name)
+		}
+
+		x = append(x, v)
+	}
+
+	return x, nil
+}
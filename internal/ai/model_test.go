@@ -0,0 +1,197 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package ai_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/ai"
+	"go.uber.org/zap"
+)
+
+// wqModel splits by the ph and then by the orp
+const wqModel = `{
+  "type": "decision_tree",
+  "features": ["temp", "ph", "orp"],
+  "classes": ["bad", "good", "regular"],
+  "nodes": [
+    {"left": 1, "right": 2, "feature": 1, "threshold": 7.0,
+     "value": [0.4, 0.3, 0.3]},
+    {"left": -1, "right": -1, "feature": -2, "threshold": -2,
+     "value": [0.9, 0.05, 0.05]},
+    {"left": 3, "right": 4, "feature": 2, "threshold": 600,
+     "value": [0.1, 0.5, 0.4]},
+    {"left": -1, "right": -1, "feature": -2, "threshold": -2,
+     "value": [0.1, 0.2, 0.7]},
+    {"left": -1, "right": -1, "feature": -2, "threshold": -2,
+     "value": [0.05, 0.9, 0.05]}
+  ]
+}`
+
+const clModel = `{
+  "type": "linear_regression",
+  "features": ["ph", "orp"],
+  "mean": [7, 650],
+  "scale": [0.5, 50],
+  "coefficients": [0.2, 0.4],
+  "intercept": 1
+}`
+
+func writeModels(t *testing.T, wq string, cl string) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	wqFile := filepath.Join(dir, "model_wq.json")
+	clFile := filepath.Join(dir, "model_cl.json")
+
+	require.NoError(t, os.WriteFile(wqFile, []byte(wq), 0600))
+	require.NoError(t, os.WriteFile(clFile, []byte(cl), 0600))
+
+	return wqFile, clFile
+}
+
+func TestModelPrediction_Predict(t *testing.T) {
+	t.Parallel()
+
+	wqFile, clFile := writeModels(t, wqModel, clModel)
+
+	p, err := ai.NewModelPrediction(zap.NewExample(), wqFile, clFile)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		temp string
+		ph   string
+		orp  string
+		wq   string
+		cl   float64
+	}{
+		{
+			name: "Low ph. It should predict the left leaf",
+			temp: "25", ph: "6.5", orp: "650",
+			wq: "bad", cl: 0.8,
+		},
+		{
+			name: "Threshold. It should go to the left node",
+			temp: "25", ph: "7", orp: "600",
+			wq: "bad", cl: 0.6,
+		},
+		{
+			name: "High ph and orp. It should predict the right leaf",
+			temp: "25", ph: "7.5", orp: "700",
+			wq: "good", cl: 1.6,
+		},
+		{
+			name: "High ph and low orp. It should predict regular",
+			temp: "25", ph: "7.5", orp: "550",
+			wq: "regular", cl: 0.4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := p.Predict(tt.temp, tt.ph, tt.orp)
+			require.NoError(t, err)
+
+			wq, cl, ok := strings.Cut(res, ";")
+			require.True(t, ok, "Result as predict.sh")
+			assert.Equal(t, tt.wq, wq, "Water quality")
+
+			v, err := strconv.ParseFloat(cl, 64)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.cl, v, 1e-9, "Chlorine")
+		})
+	}
+
+	_, err = p.Predict("25", "7,5", "700")
+	require.Error(t, err, "The metrics must be numbers")
+}
+
+func TestNewModelPrediction(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		wq   string
+		cl   string
+		err  error
+	}{
+		{
+			name: "Child before its parent. It should fail",
+			wq: strings.Replace(
+				wqModel, `"left": 3, "right": 4`, `"left": 0, "right": 4`, 1),
+			cl:  clModel,
+			err: ai.ErrModel,
+		},
+		{
+			name: "Value without a class. It should fail",
+			wq: strings.Replace(
+				wqModel, "[0.9, 0.05, 0.05]", "[0.9, 0.1]", 1),
+			cl:  clModel,
+			err: ai.ErrModel,
+		},
+		{
+			name: "Coefficient missing. It should fail",
+			wq:   wqModel,
+			cl:   strings.Replace(clModel, "[0.2, 0.4]", "[0.2]", 1),
+			err:  ai.ErrModel,
+		},
+		{
+			name: "Unknown feature. It should fail",
+			wq:   wqModel,
+			cl:   strings.Replace(clModel, `"orp"]`, `"cl"]`, 1),
+			err:  ai.ErrModel,
+		},
+		{
+			name: "Pickled model. It should fail",
+			wq:   "\x80\x04\x95",
+			cl:   clModel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			wqFile, clFile := writeModels(t, tt.wq, tt.cl)
+
+			_, err := ai.NewModelPrediction(zap.NewExample(), wqFile, clFile)
+
+			require.Error(t, err)
+
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+
+	_, err := ai.NewModelPrediction(
+		zap.NewExample(),
+		filepath.Join(t.TempDir(), "model_wq.json"),
+		filepath.Join(t.TempDir(), "model_cl.json"))
+
+	require.ErrorIs(t, err, os.ErrNotExist, "Models not exported")
+}
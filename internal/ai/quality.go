@@ -0,0 +1,85 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package ai
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// ErrQuality is returned when the quality is not one of the labels
+var ErrQuality = errors.New("The quality must be bad, regular or good")
+
+// Quality is the quality of the water judged by the expert.
+// ai/fit.py expects its number in the csv of the samples
+type Quality uint8
+
+const (
+	QualityBad Quality = iota
+	QualityRegular
+	QualityGood
+)
+
+// qualityLabels are the labels of the qualities by number
+var qualityLabels = []string{"bad", "regular", "good"}
+
+// ParseQuality parses the label or the number of the quality
+func ParseQuality(s string) (Quality, error) {
+	for i, l := range qualityLabels {
+		if s == l || s == strconv.Itoa(i) {
+			return Quality(i), nil
+		}
+	}
+
+	return 0, ErrQuality
+}
+
+// Valid checks whether the quality is one of the labels
+func (q Quality) Valid() bool {
+	return int(q) < len(qualityLabels)
+}
+
+func (q Quality) String() string {
+	if !q.Valid() {
+		return strconv.Itoa(int(q))
+	}
+
+	return qualityLabels[q]
+}
+
+// MarshalText marshals the quality as its label
+func (q Quality) MarshalText() ([]byte, error) {
+	if !q.Valid() {
+		return nil, ErrQuality
+	}
+
+	return []byte(q.String()), nil
+}
+
+// UnmarshalText unmarshals the label or the number of the quality
+func (q *Quality) UnmarshalText(text []byte) error {
+	p, err := ParseQuality(string(text))
+	if err != nil {
+		return err
+	}
+
+	*q = p
+
+	return nil
+}
@@ -19,23 +19,50 @@ package ai
 
 import (
 	"context"
+	"database/sql"
 	"encoding/csv"
 	"encoding/json"
+	"io"
 	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/pkg/errors"
 	"github.com/rs/xid"
+	iotc "github.com/swpoolcontroller/internal/iot"
+	"github.com/swpoolcontroller/pkg/iot"
 	"github.com/swpoolcontroller/pkg/strings"
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrSampleNotFound is returned when the sample does not exist
+	ErrSampleNotFound = errors.New("The sample does not exist")
+	// ErrNoReadings is returned when the device has not sent
+	// a recent buffer of readings
+	ErrNoReadings = errors.New("The device has not sent recent readings")
+)
+
 const (
 	errAWSSaveSample = "Saving sample data in AWS dynamo repository"
+	errAWSReadSample = "Reading sample data of AWS dynamo repository"
+	errAWSDelSample  = "Deleting sample data of AWS dynamo repository"
 	errOpenSample    = "Opening sample data: "
 	errWritingFile   = "Writing sample data: "
+	errReadingFile   = "Reading sample data: "
+	errSQLSaveSample = "Saving sample data in the sql database"
+	errSQLReadSample = "Reading sample data of the sql database"
+	errSQLDelSample  = "Deleting sample data of the sql database"
+	errSampleValue   = "The value of the sample is not valid: "
+	errSampleCSV     = "The sample csv must have the columns " +
+		"temp, ph, orp, chlorine, quality"
+	errSampleRecord = "Reading the sample csv record "
 )
 
 const (
@@ -50,17 +77,129 @@ const (
 	dynamoDBTableORP      = "orp"
 	dynamoDBTableQuality  = "quality"
 	dynamoDBTableChlorine = "chlorine"
+	dynamoDBTableTaken    = "taken"
 )
 
+// SampleColumns are the csv columns of the samples
+// in the order that ai/fit.py expects
+var SampleColumns = []string{
+	dynamoDBTableTemp,
+	dynamoDBTablePH,
+	dynamoDBTableORP,
+	dynamoDBTableChlorine,
+	dynamoDBTableQuality,
+}
+
+// SampleRules are the ranges of the readings of the sensors
+// and of the chlorine that the expert can judge
+var SampleRules = []iotc.Rule{
+	{Field: "temp", Label: "Temperature", Unit: "°C", Min: 0, Max: 50},
+	{Field: "ph", Label: "pH", Unit: "pH", Min: 0, Max: 14},
+	{Field: "orp", Label: "ORP", Unit: "mV", Min: -2000, Max: 2000},
+	{Field: "chlorine", Label: "Chlorine", Unit: "mg/L", Min: 0, Max: 5},
+}
+
 // SampleData is a sample of the state of the water
 type SampleData struct {
-	Temp string `json:"temp"`
-	PH   string `json:"ph"`
-	ORP  string `json:"orp"`
+	// ID identifies the sample in the repository. The samples of the
+	// file repository saved without it are identified by their position
+	ID   string  `json:"id,omitempty"`
+	Temp float64 `json:"temp"`
+	PH   float64 `json:"ph"`
+	ORP  float64 `json:"orp"`
 	// Quality is judged by the expert
-	Quality string `json:"quality"`
+	Quality Quality `json:"quality"`
 	// Chlorine is judged by the expert
-	Chlorine string `json:"chlorine"`
+	Chlorine float64 `json:"chlorine"`
+	// Taken is when the hub received the readings of the sensors.
+	// It is zero in the samples saved before it was recorded
+	Taken time.Time `json:"taken"`
+}
+
+// sampleJSON decodes the samples that were saved with the values
+// as strings, such as the ones of the old backups
+type sampleJSON struct {
+	ID       string          `json:"id"`
+	Temp     json.Number     `json:"temp"`
+	PH       json.Number     `json:"ph"`
+	ORP      json.Number     `json:"orp"`
+	Quality  json.RawMessage `json:"quality"`
+	Chlorine json.Number     `json:"chlorine"`
+	Taken    time.Time       `json:"taken"`
+}
+
+// UnmarshalJSON unmarshals the values as numbers or numeric strings
+// and the quality as its label or its number
+func (s *SampleData) UnmarshalJSON(data []byte) error {
+	var dto sampleJSON
+
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return err
+	}
+
+	quality := string(dto.Quality)
+	if len(dto.Quality) > 0 && dto.Quality[0] == '"' {
+		if err := json.Unmarshal(dto.Quality, &quality); err != nil {
+			return err
+		}
+	}
+
+	sample, err := parseSample(
+		jsonValue(string(dto.Temp)),
+		jsonValue(string(dto.PH)),
+		jsonValue(string(dto.ORP)),
+		jsonValue(string(dto.Chlorine)),
+		jsonValue(quality))
+	if err != nil {
+		return err
+	}
+
+	sample.ID = dto.ID
+	sample.Taken = dto.Taken
+	*s = sample
+
+	return nil
+}
+
+// jsonValue is the value of the json field. The missing
+// fields are zero, as the ones of any other struct
+func jsonValue(v string) string {
+	if v == "" {
+		return "0"
+	}
+
+	return v
+}
+
+// parseSample parses the values of the sample
+func parseSample(temp, ph, orp, chlorine, quality string) (SampleData, error) {
+	var (
+		s   SampleData
+		err error
+	)
+
+	for _, v := range []struct {
+		field string
+		text  string
+		value *float64
+	}{
+		{dynamoDBTableTemp, temp, &s.Temp},
+		{dynamoDBTablePH, ph, &s.PH},
+		{dynamoDBTableORP, orp, &s.ORP},
+		{dynamoDBTableChlorine, chlorine, &s.Chlorine},
+	} {
+		if *v.value, err = strconv.ParseFloat(v.text, 64); err != nil {
+			return SampleData{}, errors.Wrap(
+				err,
+				strings.Concat(errSampleValue, v.field))
+		}
+	}
+
+	if s.Quality, err = ParseQuality(quality); err != nil {
+		return SampleData{}, err
+	}
+
+	return s, nil
 }
 
 func (s *SampleData) String() string {
@@ -72,10 +211,294 @@ func (s *SampleData) String() string {
 	return string(m)
 }
 
+// Validate checks the values with SampleRules and the quality.
+// It returns an iot ValidationError with every invalid field
+// or nil if it is valid
+func (s *SampleData) Validate() error {
+	errs := iotc.ValidateFields(*s, SampleRules)
+
+	if !s.Quality.Valid() {
+		errs = append(errs, iotc.FieldError{
+			Field:   dynamoDBTableQuality,
+			Message: ErrQuality.Error(),
+		})
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// record is the csv record of the sample in the SampleColumns order
+func (s *SampleData) record() []string {
+	return []string{
+		formatValue(s.Temp),
+		formatValue(s.PH),
+		formatValue(s.ORP),
+		formatValue(s.Chlorine),
+		strconv.Itoa(int(s.Quality)),
+	}
+}
+
+// fileRecord is the record of the sample in the file repository.
+// It adds the time the readings were taken and the id
+func (s *SampleData) fileRecord() []string {
+	return append(s.record(), formatTaken(s.Taken), s.ID)
+}
+
+// formatValue formats the value with the minimum digits
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatTaken formats the time the readings were taken,
+// empty if it is unknown
+func formatTaken(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseTaken parses the time the readings were taken
+func parseTaken(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+
+	return t, errors.Wrap(err, strings.Concat(errSampleValue, "taken"))
+}
+
+// Readings are the readings of the sensors of a sample, taken
+// by the server from the latest buffer of the device
+type Readings struct {
+	Temp float64
+	PH   float64
+	ORP  float64
+	// Taken is when the hub received the buffer
+	Taken time.Time
+}
+
+// NewReadings averages the latest buffer of the device.
+// ErrNoReadings if there is no buffer or it is older than maxAge
+func NewReadings(
+	m iot.Metrics,
+	now time.Time,
+	maxAge time.Duration) (Readings, error) {
+	//
+	if m.Received.IsZero() || now.Sub(m.Received) > maxAge ||
+		len(m.Temp) == 0 || len(m.PH) == 0 || len(m.ORP) == 0 {
+		//
+		return Readings{}, ErrNoReadings
+	}
+
+	return Readings{
+		Temp:  mean(m.Temp),
+		PH:    mean(m.PH),
+		ORP:   mean(m.ORP),
+		Taken: m.Received,
+	}, nil
+}
+
+// mean is the arithmetic mean of the values
+func mean(values []float64) float64 {
+	var sum float64
+
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
+
+// NewSample creates the sample with the readings of the server and
+// the judgement of the expert. It returns an iot ValidationError with
+// every invalid field, the quality included, if it is not valid
+func NewSample(r Readings, chlorine float64, quality string) (
+	SampleData, error) {
+	//
+	s := SampleData{
+		Temp:     r.Temp,
+		PH:       r.PH,
+		ORP:      r.ORP,
+		Chlorine: chlorine,
+		Taken:    r.Taken,
+	}
+
+	errs := iotc.ValidateFields(s, SampleRules)
+
+	q, err := ParseQuality(quality)
+	if err != nil {
+		errs = append(errs, iotc.FieldError{
+			Field:   dynamoDBTableQuality,
+			Message: err.Error(),
+		})
+	}
+
+	if len(errs) > 0 {
+		return SampleData{}, errs
+	}
+
+	s.Quality = q
+
+	return s, nil
+}
+
+// SampleFilter selects a page of the samples
+type SampleFilter struct {
+	// Quality keeps the samples of the quality if it is set
+	Quality *Quality
+	// Chlorine keeps the samples of the chlorine if it is set
+	Chlorine *float64
+	// Offset is the number of samples that are skipped
+	Offset int
+	// Limit is the maximum of samples of the page. 0 is no limit
+	Limit int
+}
+
+// SamplePage is a page of the samples that pass the filter
+type SamplePage struct {
+	Samples []SampleData `json:"samples"`
+	// Total is the number of samples that pass the filter
+	Total int `json:"total"`
+}
+
+// Match checks whether the sample passes the filter
+func (f SampleFilter) Match(s SampleData) bool {
+	if f.Quality != nil && s.Quality != *f.Quality {
+		return false
+	}
+
+	return f.Chlorine == nil || s.Chlorine == *f.Chlorine
+}
+
+// Apply keeps the samples that pass the filter and cuts the page
+func (f SampleFilter) Apply(samples []SampleData) SamplePage {
+	matched := make([]SampleData, 0, len(samples))
+
+	for _, s := range samples {
+		if f.Match(s) {
+			matched = append(matched, s)
+		}
+	}
+
+	page := SamplePage{Samples: matched, Total: len(matched)}
+
+	if f.Offset >= len(matched) {
+		page.Samples = []SampleData{}
+
+		return page
+	}
+
+	page.Samples = matched[f.Offset:]
+
+	if f.Limit > 0 && len(page.Samples) > f.Limit {
+		page.Samples = page.Samples[:f.Limit]
+	}
+
+	return page
+}
+
 // SampleRepo defines the data repository
 type SampleRepo interface {
 	// Save saves the samples in the db
 	Save(data SampleData) error
+	// All reads all the samples of the db
+	All() ([]SampleData, error)
+	// List reads the page of the samples that pass the filter,
+	// in the order they were saved
+	List(filter SampleFilter) (SamplePage, error)
+	// Get reads the sample. ErrSampleNotFound if it does not exist
+	Get(id string) (SampleData, error)
+	// Delete deletes the sample. ErrSampleNotFound if it does not exist
+	Delete(id string) error
+	// Export writes all the samples as csv in the SampleColumns order
+	Export(w io.Writer) error
+}
+
+// findSample finds the sample of the id
+func findSample(samples []SampleData, id string) (SampleData, error) {
+	for _, s := range samples {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+
+	return SampleData{}, ErrSampleNotFound
+}
+
+// WriteSamplesCSV writes the samples as csv with the SampleColumns header
+func WriteSamplesCSV(w io.Writer, samples []SampleData) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(SampleColumns); err != nil {
+		return errors.Wrap(err, errWritingFile)
+	}
+
+	for _, s := range samples {
+		if err := writer.Write(s.record()); err != nil {
+			return errors.Wrap(err, errWritingFile)
+		}
+	}
+
+	writer.Flush()
+
+	return errors.Wrap(writer.Error(), errWritingFile)
+}
+
+// ReadSamplesCSV reads the samples of a csv in the SampleColumns order,
+// optionally followed by the time the readings were taken and the id.
+// The header is optional. The values are parsed but not validated
+func ReadSamplesCSV(r io.Reader) ([]SampleData, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, errSampleCSV)
+	}
+
+	samples := make([]SampleData, 0, len(records))
+
+	for i, rec := range records {
+		if len(rec) < len(SampleColumns) ||
+			len(rec) > len(SampleColumns)+2 {
+			//
+			return nil, errors.New(errSampleCSV)
+		}
+
+		if i == 0 && rec[0] == SampleColumns[0] {
+			continue
+		}
+
+		sample, err := parseSample(rec[0], rec[1], rec[2], rec[3], rec[4])
+		if err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errSampleRecord, strconv.Itoa(i+1)))
+		}
+
+		if len(rec) > len(SampleColumns) {
+			if sample.Taken, err = parseTaken(rec[5]); err != nil {
+				return nil, errors.Wrap(
+					err,
+					strings.Concat(errSampleRecord, strconv.Itoa(i+1)))
+			}
+		}
+
+		if len(rec) > len(SampleColumns)+1 {
+			sample.ID = rec[6]
+		}
+
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
 }
 
 // SampleAWSDynamoRepo defines the AWS dynamo repository
@@ -98,10 +521,40 @@ func NewSampleAWSDynamoRepo(
 	}
 }
 
+// dynamoSample is the item of the sample. The values are strings,
+// as the ones of the items saved before they were typed
+type dynamoSample struct {
+	ID       string `dynamodbav:"id"`
+	Temp     string `dynamodbav:"temp"`
+	PH       string `dynamodbav:"ph"`
+	ORP      string `dynamodbav:"orp"`
+	Quality  string `dynamodbav:"quality"`
+	Chlorine string `dynamodbav:"chlorine"`
+	Taken    string `dynamodbav:"taken"`
+}
+
+// sample parses the item
+func (d dynamoSample) sample() (SampleData, error) {
+	s, err := parseSample(d.Temp, d.PH, d.ORP, d.Chlorine, d.Quality)
+	if err != nil {
+		return SampleData{}, err
+	}
+
+	if s.Taken, err = parseTaken(d.Taken); err != nil {
+		return SampleData{}, err
+	}
+
+	s.ID = d.ID
+
+	return s, nil
+}
+
 // save saves the samples data into AWS dynamo repository
 func (s *SampleAWSDynamoRepo) Save(data SampleData) error {
 	s.log.Info(infSaving, zap.String("sample", data.String()))
 
+	rec := data.fileRecord()
+
 	_, err := s.client.PutItem(
 		context.TODO(),
 		&dynamodb.PutItemInput{
@@ -110,15 +563,17 @@ func (s *SampleAWSDynamoRepo) Save(data SampleData) error {
 				dynamoDBTableKeyName: &types.AttributeValueMemberS{
 					Value: xid.New().String()},
 				dynamoDBTableTemp: &types.AttributeValueMemberS{
-					Value: data.Temp},
+					Value: rec[0]},
 				dynamoDBTablePH: &types.AttributeValueMemberS{
-					Value: data.PH},
+					Value: rec[1]},
 				dynamoDBTableORP: &types.AttributeValueMemberS{
-					Value: data.ORP},
-				dynamoDBTableQuality: &types.AttributeValueMemberS{
-					Value: data.Quality},
+					Value: rec[2]},
 				dynamoDBTableChlorine: &types.AttributeValueMemberS{
-					Value: data.Chlorine},
+					Value: rec[3]},
+				dynamoDBTableQuality: &types.AttributeValueMemberS{
+					Value: rec[4]},
+				dynamoDBTableTaken: &types.AttributeValueMemberS{
+					Value: rec[5]},
 			},
 		})
 
@@ -129,10 +584,133 @@ func (s *SampleAWSDynamoRepo) Save(data SampleData) error {
 	return nil
 }
 
-// SampleFileRepo defines the file repository
+// All reads all the samples of the dynamo table
+func (s *SampleAWSDynamoRepo) All() ([]SampleData, error) {
+	var samples []SampleData
+
+	p := dynamodb.NewScanPaginator(s.client, &dynamodb.ScanInput{
+		TableName: aws.String(s.tableName),
+	})
+
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errAWSReadSample, s.tableName))
+		}
+
+		var items []dynamoSample
+
+		if err := attributevalue.UnmarshalListOfMaps(
+			page.Items, &items); err != nil {
+			//
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errAWSReadSample, s.tableName))
+		}
+
+		for _, item := range items {
+			sample, err := item.sample()
+			if err != nil {
+				return nil, errors.Wrap(
+					err,
+					strings.Concat(errAWSReadSample, s.tableName))
+			}
+
+			samples = append(samples, sample)
+		}
+	}
+
+	// The ids are xids, so they are sorted by creation time
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].ID < samples[j].ID
+	})
+
+	return samples, nil
+}
+
+// List scans the samples and cuts the page of the filter
+func (s *SampleAWSDynamoRepo) List(filter SampleFilter) (SamplePage, error) {
+	samples, err := s.All()
+	if err != nil {
+		return SamplePage{}, err
+	}
+
+	return filter.Apply(samples), nil
+}
+
+// Get reads the item of the sample
+func (s *SampleAWSDynamoRepo) Get(id string) (SampleData, error) {
+	out, err := s.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			dynamoDBTableKeyName: &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return SampleData{}, errors.Wrap(
+			err,
+			strings.Concat(errAWSReadSample, s.tableName))
+	}
+
+	if len(out.Item) == 0 {
+		return SampleData{}, ErrSampleNotFound
+	}
+
+	var item dynamoSample
+
+	if err := attributevalue.UnmarshalMap(out.Item, &item); err != nil {
+		return SampleData{}, errors.Wrap(
+			err,
+			strings.Concat(errAWSReadSample, s.tableName))
+	}
+
+	sample, err := item.sample()
+
+	return sample, errors.Wrap(
+		err,
+		strings.Concat(errAWSReadSample, s.tableName))
+}
+
+// Delete deletes the item of the sample if it exists
+func (s *SampleAWSDynamoRepo) Delete(id string) error {
+	_, err := s.client.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			dynamoDBTableKeyName: &types.AttributeValueMemberS{Value: id},
+		},
+		ConditionExpression: aws.String("attribute_exists(#id)"),
+		ExpressionAttributeNames: map[string]string{
+			"#id": dynamoDBTableKeyName,
+		},
+	})
+
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return ErrSampleNotFound
+	}
+
+	return errors.Wrap(err, strings.Concat(errAWSDelSample, s.tableName))
+}
+
+// Export scans the samples and writes them as csv
+func (s *SampleAWSDynamoRepo) Export(w io.Writer) error {
+	samples, err := s.All()
+	if err != nil {
+		return err
+	}
+
+	return WriteSamplesCSV(w, samples)
+}
+
+// SampleFileRepo defines the file repository.
+// Operations are protected against concurrency
 type SampleFileRepo struct {
 	Log      *zap.Logger
 	FileName string
+
+	lock sync.Mutex
 }
 
 // save saves the samples data into AWS dynamo repository
@@ -142,6 +720,11 @@ func (s *SampleFileRepo) Save(data SampleData) error {
 		zap.String("sample", data.String()),
 		zap.String("file", s.FileName))
 
+	data.ID = xid.New().String()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	file, err := os.OpenFile(
 		s.FileName,
 		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
@@ -156,10 +739,260 @@ func (s *SampleFileRepo) Save(data SampleData) error {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	record := []string{data.Temp, data.PH, data.ORP, data.Chlorine, data.Quality}
-	if err := writer.Write(record); err != nil {
+	if err := writer.Write(data.fileRecord()); err != nil {
 		return errors.Wrap(err, strings.Concat(errWritingFile, s.FileName))
 	}
 
 	return nil
 }
+
+// All reads all the samples of the file.
+// If the file not exists there are no samples
+func (s *SampleFileRepo) All() ([]SampleData, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.read()
+}
+
+// List reads the samples of the file and cuts the page of the filter
+func (s *SampleFileRepo) List(filter SampleFilter) (SamplePage, error) {
+	samples, err := s.All()
+	if err != nil {
+		return SamplePage{}, err
+	}
+
+	return filter.Apply(samples), nil
+}
+
+// Get reads the sample of the id
+func (s *SampleFileRepo) Get(id string) (SampleData, error) {
+	samples, err := s.All()
+	if err != nil {
+		return SampleData{}, err
+	}
+
+	return findSample(samples, id)
+}
+
+// Delete writes the file again without the sample. The other samples
+// keep their ids, the ones identified by their position included
+func (s *SampleFileRepo) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	samples, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	if _, err := findSample(samples, id); err != nil {
+		return err
+	}
+
+	file, err := os.Create(s.FileName)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errOpenSample, s.FileName))
+	}
+
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	for _, sample := range samples {
+		if sample.ID == id {
+			continue
+		}
+
+		if err := writer.Write(sample.fileRecord()); err != nil {
+			return errors.Wrap(err, strings.Concat(errWritingFile, s.FileName))
+		}
+	}
+
+	writer.Flush()
+
+	return errors.Wrap(
+		writer.Error(),
+		strings.Concat(errWritingFile, s.FileName))
+}
+
+// Export writes the samples of the file as csv with the header
+func (s *SampleFileRepo) Export(w io.Writer) error {
+	samples, err := s.All()
+	if err != nil {
+		return err
+	}
+
+	return WriteSamplesCSV(w, samples)
+}
+
+// read reads the samples of the file. The samples saved without id
+// are identified by their position, which is kept by Delete
+func (s *SampleFileRepo) read() ([]SampleData, error) {
+	file, err := os.Open(s.FileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []SampleData{}, nil
+		}
+
+		return nil, errors.Wrap(err, strings.Concat(errOpenSample, s.FileName))
+	}
+
+	defer file.Close()
+
+	samples, err := ReadSamplesCSV(file)
+	if err != nil {
+		return nil, errors.Wrap(err, strings.Concat(errReadingFile, s.FileName))
+	}
+
+	for i := range samples {
+		if samples[i].ID == "" {
+			samples[i].ID = strconv.Itoa(i + 1)
+		}
+	}
+
+	return samples, nil
+}
+
+// SampleSQLRepo stores the samples in the samples table of the sql database
+type SampleSQLRepo struct {
+	Log *zap.Logger
+	DB  *sql.DB
+}
+
+// Save inserts the sample
+func (s *SampleSQLRepo) Save(data SampleData) error {
+	s.Log.Info(infSaving, zap.String("sample", data.String()))
+
+	if _, err := s.DB.Exec(
+		"INSERT INTO samples "+
+			"(id, temp, ph, orp, chlorine, quality, taken) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?)",
+		xid.New().String(),
+		data.Temp,
+		data.PH,
+		data.ORP,
+		data.Chlorine,
+		data.Quality,
+		formatTaken(data.Taken)); err != nil {
+		//
+		return errors.Wrap(err, errSQLSaveSample)
+	}
+
+	return nil
+}
+
+// All reads all the samples in the order they were saved
+func (s *SampleSQLRepo) All() ([]SampleData, error) {
+	return s.query(sqlSampleColumns + " ORDER BY rowid")
+}
+
+// List counts the samples of the filter and reads the page
+func (s *SampleSQLRepo) List(filter SampleFilter) (SamplePage, error) {
+	where := " WHERE (? IS NULL OR quality = ?) " +
+		"AND (? IS NULL OR chlorine = ?)"
+	args := []any{
+		filter.Quality, filter.Quality,
+		filter.Chlorine, filter.Chlorine,
+	}
+
+	var total int
+
+	if err := s.DB.QueryRow(
+		"SELECT COUNT(*) FROM samples"+where, args...).
+		Scan(&total); err != nil {
+		//
+		return SamplePage{}, errors.Wrap(err, errSQLReadSample)
+	}
+
+	limit := -1
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	samples, err := s.query(
+		sqlSampleColumns+where+" ORDER BY rowid LIMIT ? OFFSET ?",
+		append(args, limit, filter.Offset)...)
+	if err != nil {
+		return SamplePage{}, err
+	}
+
+	return SamplePage{Samples: samples, Total: total}, nil
+}
+
+// Get reads the sample
+func (s *SampleSQLRepo) Get(id string) (SampleData, error) {
+	samples, err := s.query(sqlSampleColumns+" WHERE id = ?", id)
+	if err != nil {
+		return SampleData{}, err
+	}
+
+	return findSample(samples, id)
+}
+
+// Delete deletes the sample
+func (s *SampleSQLRepo) Delete(id string) error {
+	res, err := s.DB.Exec("DELETE FROM samples WHERE id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, errSQLDelSample)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, errSQLDelSample)
+	}
+
+	if n == 0 {
+		return ErrSampleNotFound
+	}
+
+	return nil
+}
+
+// Export writes all the samples as csv
+func (s *SampleSQLRepo) Export(w io.Writer) error {
+	samples, err := s.All()
+	if err != nil {
+		return err
+	}
+
+	return WriteSamplesCSV(w, samples)
+}
+
+// sqlSampleColumns selects the columns of the samples table
+const sqlSampleColumns = "SELECT id, temp, ph, orp, chlorine, quality, " +
+	"taken FROM samples"
+
+func (s *SampleSQLRepo) query(query string, args ...any) ([]SampleData, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, errSQLReadSample)
+	}
+
+	defer rows.Close()
+
+	samples := []SampleData{}
+
+	for rows.Next() {
+		var (
+			d     SampleData
+			taken string
+		)
+
+		if err := rows.Scan(
+			&d.ID, &d.Temp, &d.PH, &d.ORP, &d.Chlorine, &d.Quality,
+			&taken); err != nil {
+			//
+			return nil, errors.Wrap(err, errSQLReadSample)
+		}
+
+		var err error
+		if d.Taken, err = parseTaken(taken); err != nil {
+			return nil, errors.Wrap(err, errSQLReadSample)
+		}
+
+		samples = append(samples, d)
+	}
+
+	return samples, errors.Wrap(rows.Err(), errSQLReadSample)
+}
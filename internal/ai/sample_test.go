@@ -18,12 +18,22 @@
 package ai_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/swpoolcontroller/internal/ai"
+	iotc "github.com/swpoolcontroller/internal/iot"
+	"github.com/swpoolcontroller/internal/sqldb"
+	"github.com/swpoolcontroller/pkg/iot"
 	"go.uber.org/zap"
 )
 
@@ -38,22 +48,22 @@ func TestSampleFileRepo_Save(t *testing.T) {
 		{
 			name: "Save sample data to file successfully",
 			sample: ai.SampleData{
-				Temp:     "12.1",
-				PH:       "2.1",
-				ORP:      "-12.1",
-				Quality:  "0",
-				Chlorine: "-23.1",
+				Temp:     12.1,
+				PH:       2.1,
+				ORP:      -12.1,
+				Quality:  ai.QualityBad,
+				Chlorine: 0.1,
 			},
 			wantErr: false,
 		},
 		{
 			name: "Save sample data to file with error",
 			sample: ai.SampleData{
-				Temp:     "12.1",
-				PH:       "2.1",
-				ORP:      "-12.1",
-				Quality:  "0",
-				Chlorine: "-23.1",
+				Temp:     12.1,
+				PH:       2.1,
+				ORP:      -12.1,
+				Quality:  ai.QualityBad,
+				Chlorine: 0.1,
 			},
 			wantErr: true,
 		},
@@ -88,3 +98,459 @@ func TestSampleFileRepo_Save(t *testing.T) {
 		})
 	}
 }
+
+func TestSampleFileRepo_All(t *testing.T) {
+	t.Parallel()
+
+	repo := ai.SampleFileRepo{
+		Log:      zap.NewExample(),
+		FileName: filepath.Join(t.TempDir(), "sample.csv"),
+	}
+
+	samples, err := repo.All()
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+
+	sample := ai.SampleData{
+		Temp:     12.1,
+		PH:       7.1,
+		ORP:      650,
+		Quality:  ai.QualityGood,
+		Chlorine: 1.2,
+		Taken:    time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+	}
+
+	require.NoError(t, repo.Save(sample))
+	require.NoError(t, repo.Save(sample))
+
+	samples, err = repo.All()
+	require.NoError(t, err)
+
+	require.Len(t, samples, 2)
+	assert.NotEqual(t, samples[0].ID, samples[1].ID, "Id")
+
+	first, second := sample, sample
+	first.ID, second.ID = samples[0].ID, samples[1].ID
+
+	assert.Equal(t, []ai.SampleData{first, second}, samples)
+}
+
+func TestSampleFileRepo_Delete(t *testing.T) {
+	t.Parallel()
+
+	repo := &ai.SampleFileRepo{
+		Log:      zap.NewExample(),
+		FileName: filepath.Join(t.TempDir(), "sample.csv"),
+	}
+
+	// The samples saved without id are identified by their position
+	require.NoError(t, os.WriteFile(
+		repo.FileName,
+		[]byte("24,7.2,650,1,0\n24,7.2,650,1,1\n"),
+		0600))
+	require.NoError(t, repo.Save(ai.SampleData{
+		Temp: 24, PH: 7.2, ORP: 650, Chlorine: 1, Quality: ai.QualityGood,
+	}))
+
+	samples, err := repo.All()
+
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	assert.Equal(t, "1", samples[0].ID, "Position")
+	assert.Equal(t, "2", samples[1].ID, "Position")
+
+	require.NoError(t, repo.Delete("1"))
+	require.ErrorIs(t,
+		repo.Delete("1"),
+		ai.ErrSampleNotFound,
+		"A stale id does not delete the next sample")
+
+	s, err := repo.Get("2")
+
+	require.NoError(t, err)
+	assert.Equal(t, ai.QualityRegular, s.Quality, "The ids are kept")
+
+	require.NoError(t, repo.Delete(samples[2].ID))
+
+	_, err = repo.Get(samples[2].ID)
+
+	require.ErrorIs(t, err, ai.ErrSampleNotFound)
+
+	samples, err = repo.All()
+
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "2", samples[0].ID, "The position is kept as its id")
+
+	var buf bytes.Buffer
+
+	require.NoError(t, repo.Export(&buf))
+	assert.Equal(t,
+		"temp,ph,orp,chlorine,quality\n24,7.2,650,1,1\n",
+		buf.String())
+}
+
+func TestSampleFilter_Apply(t *testing.T) {
+	t.Parallel()
+
+	samples := []ai.SampleData{
+		{ID: "1", Quality: ai.QualityBad, Chlorine: 1},
+		{ID: "2", Quality: ai.QualityGood, Chlorine: 1},
+		{ID: "3", Quality: ai.QualityGood, Chlorine: 1.5},
+		{ID: "4", Quality: ai.QualityGood, Chlorine: 1},
+	}
+
+	good, chlorine := ai.QualityGood, 1.0
+
+	tests := []struct {
+		name   string
+		filter ai.SampleFilter
+		ids    []string
+		total  int
+	}{
+		{
+			name:   "Without filter. It should return all",
+			filter: ai.SampleFilter{},
+			ids:    []string{"1", "2", "3", "4"},
+			total:  4,
+		},
+		{
+			name:   "By quality and chlorine. It should return the matches",
+			filter: ai.SampleFilter{Quality: &good, Chlorine: &chlorine},
+			ids:    []string{"2", "4"},
+			total:  2,
+		},
+		{
+			name:   "Page. It should skip the offset and cut the limit",
+			filter: ai.SampleFilter{Offset: 1, Limit: 2},
+			ids:    []string{"2", "3"},
+			total:  4,
+		},
+		{
+			name:   "Offset after the end. It should return an empty page",
+			filter: ai.SampleFilter{Offset: 10},
+			ids:    []string{},
+			total:  4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			page := tt.filter.Apply(samples)
+
+			ids := make([]string, 0, len(page.Samples))
+			for _, s := range page.Samples {
+				ids = append(ids, s.ID)
+			}
+
+			assert.Equal(t, tt.ids, ids)
+			assert.Equal(t, tt.total, page.Total, "Total")
+		})
+	}
+}
+
+func TestSamplesCSV(t *testing.T) {
+	t.Parallel()
+
+	samples := []ai.SampleData{
+		{Temp: 12.1, PH: 7.1, ORP: 650, Quality: ai.QualityGood, Chlorine: 1.2},
+		{Temp: 25, PH: 6.9, ORP: 700, Quality: ai.QualityBad, Chlorine: 0.8},
+	}
+
+	var b bytes.Buffer
+
+	require.NoError(t, ai.WriteSamplesCSV(&b, samples))
+	assert.True(t,
+		strings.HasPrefix(b.String(), "temp,ph,orp,chlorine,quality\n"))
+
+	res, err := ai.ReadSamplesCSV(&b)
+	require.NoError(t, err)
+	assert.Equal(t, samples, res)
+
+	res, err = ai.ReadSamplesCSV(strings.NewReader("25,6.9,700,0.8,0\n"))
+	require.NoError(t, err)
+	assert.Equal(t, samples[1:], res)
+
+	res, err = ai.ReadSamplesCSV(strings.NewReader(
+		"25,6.9,700,0.8,0,2024-05-01T10:30:00Z\n"))
+	require.NoError(t, err)
+	assert.Equal(t,
+		time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		res[0].Taken,
+		"Time the readings were taken")
+
+	_, err = ai.ReadSamplesCSV(strings.NewReader("25,6.9\n"))
+	require.Error(t, err)
+
+	_, err = ai.ReadSamplesCSV(strings.NewReader("25,6.9,700,0.8,great\n"))
+	require.ErrorIs(t, err, ai.ErrQuality)
+
+	_, err = ai.ReadSamplesCSV(strings.NewReader("25,6.9,7OO,0.8,0\n"))
+	require.Error(t, err, "Typo in a value")
+}
+
+func TestSampleSQLRepo(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqldb.Open(filepath.Join(t.TempDir(), "swpc.db"))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	r := &ai.SampleSQLRepo{Log: zap.NewExample(), DB: db}
+
+	samples, err := r.All()
+
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+
+	saved := []ai.SampleData{
+		{
+			Temp: 24, PH: 7.2, ORP: 650, Chlorine: 1,
+			Quality: ai.QualityGood,
+			Taken:   time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{Temp: 27, PH: 7.8, ORP: 550, Chlorine: 0.5, Quality: ai.QualityBad},
+	}
+
+	for _, s := range saved {
+		require.NoError(t, r.Save(s))
+	}
+
+	samples, err = r.All()
+
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+
+	for i := range samples {
+		assert.NotEmpty(t, samples[i].ID, "Id")
+
+		saved[i].ID = samples[i].ID
+	}
+
+	assert.Equal(t, saved, samples, "In the order they were saved")
+
+	bad := ai.QualityBad
+
+	page, err := r.List(ai.SampleFilter{Quality: &bad})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Total, "Total")
+	assert.Equal(t, saved[1:], page.Samples, "By quality")
+
+	page, err = r.List(ai.SampleFilter{Offset: 1, Limit: 5})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total, "Total")
+	assert.Equal(t, saved[1:], page.Samples, "Page")
+
+	s, err := r.Get(saved[0].ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, saved[0], s)
+
+	require.NoError(t, r.Delete(saved[0].ID))
+	require.ErrorIs(t, r.Delete(saved[0].ID), ai.ErrSampleNotFound)
+
+	_, err = r.Get(saved[0].ID)
+
+	require.ErrorIs(t, err, ai.ErrSampleNotFound)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, r.Export(&buf))
+	assert.Equal(t,
+		"temp,ph,orp,chlorine,quality\n27,7.8,550,0.5,0\n",
+		buf.String())
+}
+
+func TestParseQuality(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		text    string
+		quality ai.Quality
+		err     error
+	}{
+		{
+			name:    "Label. It should parse it",
+			text:    "regular",
+			quality: ai.QualityRegular,
+		},
+		{
+			name:    "Number. It should parse it",
+			text:    "2",
+			quality: ai.QualityGood,
+		},
+		{
+			name: "Typo. It should fail",
+			text: "god",
+			err:  ai.ErrQuality,
+		},
+		{
+			name: "Out of range. It should fail",
+			text: "3",
+			err:  ai.ErrQuality,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			q, err := ai.ParseQuality(tt.text)
+
+			require.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.quality, q)
+		})
+	}
+}
+
+func TestSampleData_JSON(t *testing.T) {
+	t.Parallel()
+
+	sample := ai.SampleData{
+		ID:       "1",
+		Temp:     24.5,
+		PH:       7.2,
+		ORP:      650,
+		Quality:  ai.QualityGood,
+		Chlorine: 1.2,
+		Taken:    time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+	}
+
+	b, err := json.Marshal(sample)
+
+	require.NoError(t, err)
+	assert.JSONEq(t,
+		`{"id":"1","temp":24.5,"ph":7.2,"orp":650,"quality":"good",`+
+			`"chlorine":1.2,"taken":"2024-05-01T10:30:00Z"}`,
+		string(b))
+
+	var res ai.SampleData
+
+	require.NoError(t, json.Unmarshal(b, &res))
+	assert.Equal(t, sample, res)
+
+	old := `{"temp":"24.5","ph":"7.2","orp":"650",` +
+		`"quality":"2","chlorine":"1.2"}`
+
+	require.NoError(t, json.Unmarshal([]byte(old), &res))
+
+	sample.ID, sample.Taken = "", time.Time{}
+
+	assert.Equal(t, sample, res, "The values of the old samples are strings")
+
+	require.ErrorIs(t,
+		json.Unmarshal([]byte(`{"temp":24,"quality":"great"}`), &res),
+		ai.ErrQuality)
+}
+
+func TestNewReadings(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	metrics := iot.Metrics{
+		Temp:     []float64{24, 25},
+		PH:       []float64{7.1, 7.3},
+		ORP:      []float64{640, 660},
+		Received: now.Add(-time.Minute),
+	}
+
+	r, err := ai.NewReadings(metrics, now, 2*time.Minute)
+
+	require.NoError(t, err)
+	assert.Equal(t, 24.5, r.Temp, "Temp")
+	assert.InDelta(t, 7.2, r.PH, 1e-9, "pH")
+	assert.Equal(t, 650.0, r.ORP, "ORP")
+	assert.Equal(t, metrics.Received, r.Taken, "Taken")
+
+	_, err = ai.NewReadings(metrics, now, 30*time.Second)
+	require.ErrorIs(t, err, ai.ErrNoReadings, "Old buffer")
+
+	_, err = ai.NewReadings(iot.Metrics{}, now, 2*time.Minute)
+	require.ErrorIs(t, err, ai.ErrNoReadings, "No buffer")
+}
+
+func TestNewSample(t *testing.T) {
+	t.Parallel()
+
+	readings := ai.Readings{
+		Temp:  24,
+		PH:    7.2,
+		ORP:   650,
+		Taken: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+	}
+
+	s, err := ai.NewSample(readings, 1.5, "good")
+
+	require.NoError(t, err)
+	assert.Equal(t, ai.SampleData{
+		Temp:     24,
+		PH:       7.2,
+		ORP:      650,
+		Chlorine: 1.5,
+		Quality:  ai.QualityGood,
+		Taken:    readings.Taken,
+	}, s)
+
+	readings.PH = 15
+
+	_, err = ai.NewSample(readings, 12, "god")
+
+	var errs iotc.ValidationError
+
+	require.ErrorAs(t, err, &errs)
+
+	fields := make([]string, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+
+	assert.Equal(t, []string{"ph", "chlorine", "quality"}, fields)
+}
+
+func TestSampleRules_Generator(t *testing.T) {
+	t.Parallel()
+
+	src, err := os.ReadFile("../../ai/sample.py")
+	require.NoError(t, err)
+
+	names := map[string]string{}
+
+	for _, m := range regexp.MustCompile(
+		`(?m)^(field_\w+) = '(\w+)'`).FindAllStringSubmatch(string(src), -1) {
+		//
+		names[m[1]] = m[2]
+	}
+
+	ranges := regexp.MustCompile(
+		`(field_\w+): np\.random\.uniform\(([\d.-]+), ([\d.-]+),`).
+		FindAllStringSubmatch(string(src), -1)
+	require.Len(t, ranges, len(ai.SampleRules), "A range per rule")
+
+	for _, m := range ranges {
+		field := names[m[1]]
+
+		lo, err := strconv.ParseFloat(m[2], 64)
+		require.NoError(t, err)
+
+		hi, err := strconv.ParseFloat(m[3], 64)
+		require.NoError(t, err)
+
+		var rule *iotc.Rule
+
+		for i := range ai.SampleRules {
+			if ai.SampleRules[i].Field == field {
+				rule = &ai.SampleRules[i]
+			}
+		}
+
+		require.NotNil(t, rule, field)
+		assert.GreaterOrEqual(t, lo, rule.Min, "Min of %s", field)
+		assert.LessOrEqual(t, hi, rule.Max, "Max of %s", field)
+	}
+}
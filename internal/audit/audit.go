@@ -0,0 +1,254 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ErrNoStore is returned when querying the entries of a repository
+// that does not store them
+var ErrNoStore = errors.New("The audit log is not stored. " +
+	"Configure the audit file or the audit table")
+
+const (
+	errWriteCSV = "Writing the audit entries as csv"
+)
+
+const (
+	infAudit = "Audit"
+)
+
+const (
+	// ResultSuccess is the result of the completed actions
+	ResultSuccess = "success"
+	// ResultFailure is the result of the rejected or failed actions
+	ResultFailure = "failure"
+)
+
+// Change is the before and after value of a modified field
+type Change struct {
+	Field  string      `json:"field" dynamodbav:"field"`
+	Before interface{} `json:"before" dynamodbav:"before"`
+	After  interface{} `json:"after" dynamodbav:"after"`
+}
+
+// Entry is an audited action
+type Entry struct {
+	ID   string    `json:"id" dynamodbav:"id"`
+	Time time.Time `json:"time" dynamodbav:"time"`
+	// Action identifies the audited operation. For example config.save
+	Action string `json:"action" dynamodbav:"action"`
+	// Result is success or failure
+	Result string `json:"result" dynamodbav:"result"`
+	// Status is the http status of the response
+	Status int `json:"status" dynamodbav:"status"`
+	// User is the readable name of the user
+	User string `json:"user" dynamodbav:"user"`
+	// Subject is the unique identifier of the user
+	Subject string `json:"sub,omitempty" dynamodbav:"sub,omitempty"`
+	Role    string `json:"role,omitempty" dynamodbav:"role,omitempty"`
+	// IP is the source ip of the request
+	IP      string   `json:"ip" dynamodbav:"ip"`
+	Method  string   `json:"method" dynamodbav:"method"`
+	Path    string   `json:"path" dynamodbav:"path"`
+	Changes []Change `json:"changes,omitempty" dynamodbav:"changes,omitempty"`
+}
+
+// Filter filters the audit entries. The empty fields do not filter
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	User   string
+	Action string
+	// Limit is the maximum of entries. The most recent are kept
+	Limit int
+}
+
+// Repo defines the append-only audit repository
+type Repo interface {
+	// Append adds the entry
+	Append(entry Entry) error
+	// Query gets the entries of the filter, the most recent first
+	Query(filter Filter) ([]Entry, error)
+}
+
+// LogRepo writes the entries in the log when there is no store.
+// They cannot be queried
+type LogRepo struct {
+	Log *zap.Logger
+}
+
+// Append writes the entry in the log
+func (r *LogRepo) Append(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, infAudit)
+	}
+
+	r.Log.Info(infAudit, zap.String("Entry", string(data)))
+
+	return nil
+}
+
+// Query always returns ErrNoStore
+func (r *LogRepo) Query(_ Filter) ([]Entry, error) {
+	return nil, ErrNoStore
+}
+
+// Match checks whether the entry passes the filter
+func (f Filter) Match(e Entry) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+
+	if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	}
+
+	if f.User != "" && e.User != f.User && e.Subject != f.User {
+		return false
+	}
+
+	return f.Action == "" || e.Action == f.Action
+}
+
+// Apply sorts the entries, the most recent first, and applies the limit
+func (f Filter) Apply(entries []Entry) []Entry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[:f.Limit]
+	}
+
+	return entries
+}
+
+// Diff compares two structs of the same type field by field.
+// The fields are named by their json tag. If before is nil
+// all the fields of after are changes
+func Diff(before interface{}, after interface{}) []Change {
+	va := reflect.Indirect(reflect.ValueOf(after))
+	if va.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var vb reflect.Value
+	if before != nil {
+		vb = reflect.Indirect(reflect.ValueOf(before))
+		if vb.Type() != va.Type() {
+			return nil
+		}
+	}
+
+	var changes []Change
+
+	for i := 0; i < va.NumField(); i++ {
+		f := va.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		a := va.Field(i).Interface()
+
+		var b interface{}
+		if vb.IsValid() {
+			b = vb.Field(i).Interface()
+			if reflect.DeepEqual(a, b) {
+				continue
+			}
+		}
+
+		changes = append(changes, Change{Field: fieldName(f), Before: b, After: a})
+	}
+
+	return changes
+}
+
+// WriteCSV writes the entries as csv with a header
+func WriteCSV(w io.Writer, entries []Entry) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{
+		"time", "action", "result", "status", "user", "sub", "role",
+		"ip", "method", "path", "changes"}); err != nil {
+		return errors.Wrap(err, errWriteCSV)
+	}
+
+	for _, e := range entries {
+		changes := ""
+
+		if len(e.Changes) > 0 {
+			data, err := json.Marshal(e.Changes)
+			if err != nil {
+				return errors.Wrap(err, errWriteCSV)
+			}
+
+			changes = string(data)
+		}
+
+		if err := writer.Write([]string{
+			e.Time.Format(time.RFC3339),
+			e.Action,
+			e.Result,
+			strconv.Itoa(e.Status),
+			e.User,
+			e.Subject,
+			e.Role,
+			e.IP,
+			e.Method,
+			e.Path,
+			changes,
+		}); err != nil {
+			return errors.Wrap(err, errWriteCSV)
+		}
+	}
+
+	writer.Flush()
+
+	return errors.Wrap(writer.Error(), errWriteCSV)
+}
+
+func fieldName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+
+	for i, c := range tag {
+		if c == ',' {
+			tag = tag[:i]
+
+			break
+		}
+	}
+
+	if tag == "" || tag == "-" {
+		return f.Name
+	}
+
+	return tag
+}
@@ -0,0 +1,194 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package audit_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/audit"
+	"github.com/swpoolcontroller/internal/iot"
+	"github.com/swpoolcontroller/internal/sqldb"
+	"go.uber.org/zap"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	before := iot.DefaultConfig()
+	after := before
+	after.Wakeup = 10
+	after.CalibratingORP = true
+
+	assert.Equal(
+		t,
+		[]audit.Change{
+			{Field: "wakeup", Before: uint8(30), After: uint8(10)},
+			{Field: "calibratingOrp", Before: false, After: true},
+		},
+		audit.Diff(before, after))
+
+	assert.Empty(t, audit.Diff(before, before))
+	assert.Len(t, audit.Diff(nil, after), 11)
+}
+
+func TestFileRepo(t *testing.T) {
+	t.Parallel()
+
+	r := &audit.FileRepo{
+		Log:      zap.NewExample(),
+		FileName: filepath.Join(t.TempDir(), "audit.log"),
+	}
+
+	res, err := r.Query(audit.Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, e := range []audit.Entry{
+		{Time: now.Add(-2 * time.Hour), User: "alice", Action: "auth.login"},
+		{Time: now.Add(-time.Hour), User: "bob", Action: "config.save"},
+		{Time: now, User: "alice", Action: "config.save"},
+	} {
+		e.ID = string(rune('a' + i))
+		require.NoError(t, r.Append(e))
+	}
+
+	tests := []struct {
+		name   string
+		filter audit.Filter
+		users  []string
+	}{
+		{
+			name:   "Without filter. It should return all, the most recent first",
+			filter: audit.Filter{},
+			users:  []string{"alice", "bob", "alice"},
+		},
+		{
+			name:   "By user. It should return the entries of the user",
+			filter: audit.Filter{User: "bob"},
+			users:  []string{"bob"},
+		},
+		{
+			name:   "By action and date. It should return the matches",
+			filter: audit.Filter{Action: "config.save", From: now},
+			users:  []string{"alice"},
+		},
+		{
+			name:   "With limit. It should return the most recent",
+			filter: audit.Filter{Limit: 2},
+			users:  []string{"alice", "bob"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := r.Query(tt.filter)
+			require.NoError(t, err)
+
+			users := make([]string, 0, len(res))
+			for _, e := range res {
+				users = append(users, e.User)
+			}
+
+			assert.Equal(t, tt.users, users)
+		})
+	}
+}
+
+func TestSQLRepo(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqldb.Open(filepath.Join(t.TempDir(), "swpc.db"))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	r := &audit.SQLRepo{Log: zap.NewExample(), DB: db}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, e := range []audit.Entry{
+		{Time: now.Add(-2 * time.Hour), User: "alice", Action: "auth.login"},
+		{Time: now.Add(-time.Hour), User: "bob", Action: "config.save"},
+		{Time: now.Add(time.Millisecond), User: "alice", Action: "config.save",
+			Changes: []audit.Change{{Field: "buffer", Before: 3, After: 5}}},
+	} {
+		e.ID = string(rune('a' + i))
+		require.NoError(t, r.Append(e))
+	}
+
+	res, err := r.Query(audit.Filter{})
+
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+	assert.Equal(t, "c", res[0].ID, "The most recent first")
+	assert.Equal(t, "buffer", res[0].Changes[0].Field, "Changes")
+
+	res, err = r.Query(audit.Filter{
+		From: now.Add(-time.Hour),
+		To:   now,
+		User: "bob",
+	})
+
+	require.NoError(t, err)
+	require.Len(t, res, 1, "By time range and user")
+	assert.Equal(t, "b", res[0].ID)
+}
+
+func TestWriteCSV(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+
+	err := audit.WriteCSV(&b, []audit.Entry{{
+		Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Action:  "config.save",
+		Result:  audit.ResultSuccess,
+		Status:  200,
+		User:    "alice",
+		IP:      "10.0.0.1",
+		Changes: []audit.Change{{Field: "wakeup", Before: 30, After: 10}},
+	}})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "time,action,result"))
+	assert.Contains(t, lines[1], "2024-01-02T03:04:05Z,config.save,success,200,alice")
+	assert.Contains(t, lines[1], `""field"":""wakeup""`)
+}
+
+func TestLogRepo_Query(t *testing.T) {
+	t.Parallel()
+
+	r := &audit.LogRepo{Log: zap.NewExample()}
+
+	require.NoError(t, r.Append(audit.Entry{User: "alice"}))
+
+	_, err := r.Query(audit.Filter{})
+	require.ErrorIs(t, err, audit.ErrNoStore)
+}
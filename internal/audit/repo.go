@@ -0,0 +1,282 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/pkg/strings"
+	"go.uber.org/zap"
+)
+
+const (
+	errOpenAudit    = "Opening the audit log: "
+	errReadAudit    = "Reading the audit log: "
+	errUnmarsAudit  = "Unmarshalling the audit log: "
+	errMarshalAudit = "Marshalling the audit entry: "
+	errWriteAudit   = "Writing the audit log: "
+	errSQLAudit     = "Reading the audit log of the sql database"
+)
+
+// maxLineSize is the maximum size of an entry of the audit file
+const maxLineSize = 1024 * 1024
+
+// FileRepo appends the entries to a file, one json per line.
+// Operations are protected against concurrency
+type FileRepo struct {
+	Log      *zap.Logger
+	FileName string
+
+	lock sync.Mutex
+}
+
+// Append appends the entry at the end of the file
+func (r *FileRepo) Append(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errMarshalAudit, r.FileName))
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	file, err := os.OpenFile(
+		r.FileName,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0600)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errOpenAudit, r.FileName))
+	}
+
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, strings.Concat(errWriteAudit, r.FileName))
+	}
+
+	return nil
+}
+
+// Query reads the entries of the file that pass the filter
+func (r *FileRepo) Query(filter Filter) ([]Entry, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	file, err := os.Open(r.FileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Entry{}, nil
+		}
+
+		return nil, errors.Wrap(err, strings.Concat(errOpenAudit, r.FileName))
+	}
+
+	defer file.Close()
+
+	entries := []Entry{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+
+	for scanner.Scan() {
+		var e Entry
+
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errUnmarsAudit, r.FileName))
+		}
+
+		if filter.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, strings.Concat(errReadAudit, r.FileName))
+	}
+
+	return filter.Apply(entries), nil
+}
+
+// AWSDynamoRepo stores the entries in AWS dynamodb
+type AWSDynamoRepo struct {
+	log       *zap.Logger
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewAWSDynamoRepo creates the AWS dynamo repository
+func NewAWSDynamoRepo(
+	cfg aws.Config,
+	log *zap.Logger,
+	tableName string) *AWSDynamoRepo {
+	//
+	return &AWSDynamoRepo{
+		log:       log,
+		client:    dynamodb.NewFromConfig(cfg),
+		tableName: tableName,
+	}
+}
+
+// Append puts the entry into dynamodb
+func (r *AWSDynamoRepo) Append(entry Entry) error {
+	item, err := attributevalue.MarshalMap(entry)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errMarshalAudit, r.tableName))
+	}
+
+	if _, err := r.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(r.tableName),
+		Item:      item,
+	}); err != nil {
+		return errors.Wrap(err, strings.Concat(errWriteAudit, r.tableName))
+	}
+
+	return nil
+}
+
+// Query scans the entries of dynamodb that pass the filter
+func (r *AWSDynamoRepo) Query(filter Filter) ([]Entry, error) {
+	entries := []Entry{}
+
+	p := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	})
+
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errReadAudit, r.tableName))
+		}
+
+		var es []Entry
+
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &es); err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errUnmarsAudit, r.tableName))
+		}
+
+		for _, e := range es {
+			if filter.Match(e) {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	return filter.Apply(entries), nil
+}
+
+// SQLRepo stores the entries in the audit table of the sql database.
+// The entry is kept as json next to the columns to sort it
+type SQLRepo struct {
+	Log *zap.Logger
+	DB  *sql.DB
+}
+
+// sqlAuditTable is the table of the audit log
+const sqlAuditTable = "audit"
+
+// sqlTimeFormat has a fixed width so that the times sort as text
+const sqlTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// Append inserts the entry
+func (r *SQLRepo) Append(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errMarshalAudit, sqlAuditTable))
+	}
+
+	if _, err := r.DB.Exec(
+		"INSERT INTO audit (id, time, action, user, entry) "+
+			"VALUES (?, ?, ?, ?, ?)",
+		entry.ID,
+		entry.Time.UTC().Format(sqlTimeFormat),
+		entry.Action,
+		entry.User,
+		string(data)); err != nil {
+		//
+		return errors.Wrap(err, strings.Concat(errWriteAudit, sqlAuditTable))
+	}
+
+	return nil
+}
+
+// Query reads the entries of the time range that pass the filter
+func (r *SQLRepo) Query(filter Filter) ([]Entry, error) {
+	from, to := "", "9999"
+
+	if !filter.From.IsZero() {
+		from = filter.From.UTC().Format(sqlTimeFormat)
+	}
+
+	if !filter.To.IsZero() {
+		to = filter.To.UTC().Format(sqlTimeFormat)
+	}
+
+	rows, err := r.DB.Query(
+		"SELECT entry FROM audit WHERE time >= ? AND time <= ? ORDER BY time",
+		from,
+		to)
+	if err != nil {
+		return nil, errors.Wrap(err, errSQLAudit)
+	}
+
+	defer rows.Close()
+
+	entries := []Entry{}
+
+	for rows.Next() {
+		var (
+			data string
+			e    Entry
+		)
+
+		if err := rows.Scan(&data); err != nil {
+			return nil, errors.Wrap(err, errSQLAudit)
+		}
+
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errUnmarsAudit, sqlAuditTable))
+		}
+
+		if filter.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, errSQLAudit)
+	}
+
+	return filter.Apply(entries), nil
+}
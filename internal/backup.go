@@ -0,0 +1,133 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package internal
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/account"
+	"github.com/swpoolcontroller/internal/ai"
+	iotc "github.com/swpoolcontroller/internal/iot"
+)
+
+// BackupVersion is the format version of the backups
+const BackupVersion = 1
+
+// restoreAuthor is the author of the restored configuration revision
+const restoreAuthor = "restore"
+
+var (
+	errBackupVersion = errors.New("The backup version is not supported")
+	errNoUsersStore  = errors.New("The backup has users " +
+		"and the users store is not configured")
+	errNoAPIKeysStore = errors.New("The backup has api keys " +
+		"and the api keys store is not configured")
+)
+
+const (
+	errBackupConfig  = "Backup. Reading the micro controller configuration"
+	errBackupSamples = "Backup. Reading the samples"
+	errBackupUsers   = "Backup. Reading the users"
+	errBackupAPIKeys = "Backup. Reading the api keys"
+	errRestoreConfig = "Restore. Saving the micro controller configuration"
+	errRestoreSample = "Restore. Saving the samples"
+	errRestoreUser   = "Restore. Saving the users"
+	errRestoreAPIKey = "Restore. Saving the api keys"
+)
+
+// Backup is the data of the stores. The app configuration and the audit
+// log are not included
+type Backup struct {
+	Version int              `json:"version"`
+	Created time.Time        `json:"created"`
+	Config  iotc.Config      `json:"config"`
+	Samples []ai.SampleData  `json:"samples"`
+	Users   []account.User   `json:"users,omitempty"`
+	APIKeys []account.APIKey `json:"apiKeys,omitempty"`
+}
+
+// Backup reads the data of the stores.
+// The users and the api keys are included if there are stores
+func (s Stores) Backup() (Backup, error) {
+	b := Backup{
+		Version: BackupVersion,
+		Created: time.Now().UTC(),
+	}
+
+	var err error
+
+	if b.Config, err = s.ConfigRead.Read(); err != nil {
+		return b, errors.Wrap(err, errBackupConfig)
+	}
+
+	if b.Samples, err = s.Samples.All(); err != nil {
+		return b, errors.Wrap(err, errBackupSamples)
+	}
+
+	if s.Users != nil {
+		if b.Users, err = s.Users.List(); err != nil {
+			return b, errors.Wrap(err, errBackupUsers)
+		}
+	}
+
+	if s.APIKeys != nil {
+		if b.APIKeys, err = s.APIKeys.List(); err != nil {
+			return b, errors.Wrap(err, errBackupAPIKeys)
+		}
+	}
+
+	return b, nil
+}
+
+// Restore saves the data of the backup in the stores.
+// The users and the api keys are replaced by id and the samples
+// are added, so it is intended for empty stores
+func (s Stores) Restore(b Backup) error {
+	switch {
+	case b.Version != BackupVersion:
+		return errBackupVersion
+	case len(b.Users) > 0 && s.Users == nil:
+		return errNoUsersStore
+	case len(b.APIKeys) > 0 && s.APIKeys == nil:
+		return errNoAPIKeysStore
+	}
+
+	if _, err := s.ConfigWrite.Save(b.Config, restoreAuthor); err != nil {
+		return errors.Wrap(err, errRestoreConfig)
+	}
+
+	for _, sample := range b.Samples {
+		if err := s.Samples.Save(sample); err != nil {
+			return errors.Wrap(err, errRestoreSample)
+		}
+	}
+
+	for _, u := range b.Users {
+		if err := s.Users.Save(u); err != nil {
+			return errors.Wrap(err, errRestoreUser)
+		}
+	}
+
+	for _, k := range b.APIKeys {
+		if err := s.APIKeys.Save(k); err != nil {
+			return errors.Wrap(err, errRestoreAPIKey)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package internal_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal"
+	"github.com/swpoolcontroller/internal/account"
+	"github.com/swpoolcontroller/internal/ai"
+	"github.com/swpoolcontroller/internal/config"
+	iotc "github.com/swpoolcontroller/internal/iot"
+	"github.com/swpoolcontroller/internal/iot/mocks"
+	"go.uber.org/zap"
+)
+
+// fileStores are the stores of the file data provider in the dir
+func fileStores(t *testing.T, dir string) internal.Stores {
+	t.Helper()
+
+	log := zap.NewExample()
+
+	hub := mocks.NewHub(t)
+	hub.On("Config", mock.Anything).Maybe()
+
+	return internal.Stores{
+		ConfigRead: &iotc.FileConfigRead{
+			Log:      log,
+			DataFile: filepath.Join(dir, "config.dat"),
+		},
+		ConfigWrite: &iotc.FileConfigWrite{
+			Log:      log,
+			Hub:      hub,
+			Config:   config.Default(),
+			DataFile: filepath.Join(dir, "config.dat"),
+		},
+		Samples: &ai.SampleFileRepo{
+			Log:      log,
+			FileName: filepath.Join(dir, "sample.csv"),
+		},
+		Users: &account.UserFileRepo{
+			Log:      log,
+			FileName: filepath.Join(dir, "users.json"),
+		},
+		APIKeys: &account.APIKeyFileRepo{
+			Log:      log,
+			FileName: filepath.Join(dir, "apikeys.json"),
+		},
+	}
+}
+
+func TestStores_Restore(t *testing.T) {
+	t.Parallel()
+
+	src := fileStores(t, t.TempDir())
+
+	cnf := iotc.DefaultConfig()
+	cnf.Wakeup = 15
+
+	_, err := src.ConfigWrite.Save(cnf, "admin")
+	require.NoError(t, err)
+	require.NoError(t, src.Samples.Save(ai.SampleData{
+		Temp: 25, PH: 7.1, ORP: 650, Quality: ai.QualityGood, Chlorine: 1.2,
+	}))
+	require.NoError(t, src.Users.Save(account.User{
+		Username: "admin", Hash: "hash", Role: "admin",
+	}))
+	require.NoError(t, src.APIKeys.Save(account.APIKey{
+		ID:      "id",
+		Name:    "grafana",
+		Hash:    "hash",
+		Scopes:  []string{"read"},
+		Created: time.Now().UTC().Truncate(time.Second),
+	}))
+
+	b, err := src.Backup()
+	require.NoError(t, err)
+	assert.Equal(t, internal.BackupVersion, b.Version)
+	assert.Len(t, b.Samples, 1)
+	assert.Len(t, b.Users, 1)
+	assert.Len(t, b.APIKeys, 1)
+
+	dst := fileStores(t, t.TempDir())
+	require.NoError(t, dst.Restore(b))
+
+	res, err := dst.Backup()
+	require.NoError(t, err)
+
+	res.Created = b.Created
+
+	// The repositories give a new id to the restored samples.
+	require.Len(t, res.Samples, 1)
+	assert.NotEmpty(t, res.Samples[0].ID)
+	res.Samples[0].ID = b.Samples[0].ID
+
+	assert.Equal(t, b, res)
+}
+
+func TestStores_RestoreError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		backup internal.Backup
+	}{
+		{
+			name:   "Unknown version. It should return an error",
+			backup: internal.Backup{Version: 0},
+		},
+		{
+			name: "No users store. It should return an error",
+			backup: internal.Backup{
+				Version: internal.BackupVersion,
+				Users:   []account.User{{Username: "admin"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := fileStores(t, t.TempDir())
+			s.Users = nil
+
+			require.Error(t, s.Restore(tt.backup))
+		})
+	}
+}
@@ -19,31 +19,28 @@ package config
 
 import (
 	"encoding/json"
+	"net"
 	"os"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
 	strs "github.com/swpoolcontroller/pkg/strings"
 )
 
 const ENVConfig = "SW_POOL_CONTROLLER_CONFIG"
 
+// secretRef is a reference to a secret key in a config value
+var secretRef = regexp.MustCompile(`@@[a-zA-Z0-9_]+`)
+
 const (
-	errEnvConfig   = "Environment configuration variable cannot be loaded"
-	errLogEncoding = "The log encoding param must be configured to " +
-		"('json' or 'console')"
-	errLogLevel = "The log level param must be configured to " +
-		"(-1: debug, 0: info, 1: Warn, 2: Error, 3: DPanic, 4: Panic, 5: Fatal)"
-	errAuthProvider = "The auth provider param must be configured to " +
-		"(test, oauth2)"
-	errCloudProvider = "The cloud provider param must be configured to " +
-		"(none, aws)"
-	errDataProvider = "The data provider param must be configured to " +
-		"(file, cloud)"
-	errHeatbeat = "The heratbeat must be confgured"
-	errGets     = "Cannot obtain supplier's secret"
-	errUnsetEnv = "Cannot unset environment variable"
+	errEnvConfig = "Environment configuration variable cannot be loaded"
+	errGets      = "Cannot obtain supplier's secret"
+	errSecretRef = "The secret is not found: "
+	errUnsetEnv  = "Cannot unset environment variable"
+	errProxy     = "The trusted proxy is not an IP or a CIDR range: "
 )
 
 type AuthProvider string
@@ -51,6 +48,8 @@ type AuthProvider string
 const (
 	AuthProviderDev    AuthProvider = "dev"
 	AuthProviderOauth2 AuthProvider = "oauth2"
+	AuthProviderOIDC   AuthProvider = "oidc"
+	AuthProviderLocal  AuthProvider = "local"
 )
 
 type CloudProvider string
@@ -66,6 +65,17 @@ const (
 	NoneDataProvider  DataProvider = "none"
 	FileDataProvider  DataProvider = "file"
 	CloudDataProvider DataProvider = "cloud"
+	SQLDataProvider   DataProvider = "sql"
+)
+
+type SecretProvider string
+
+const (
+	NoneSecretProvider  SecretProvider = "none"
+	AWSSecretProvider   SecretProvider = "aws"
+	FileSecretProvider  SecretProvider = "file"
+	EnvSecretProvider   SecretProvider = "env"
+	VaultSecretProvider SecretProvider = "vault"
 )
 
 // Secret manages the secrets
@@ -100,6 +110,50 @@ type Server struct {
 
 	// External address on the web
 	External Address `json:"external,omitempty"`
+
+	// TrustedProxies are the IPs or CIDR ranges, separated by commas,
+	// of the proxies whose X-Forwarded-For header is trusted.
+	// Without proxies the client IP is the address of the connection
+	TrustedProxies string `json:"trustedProxies,omitempty"`
+}
+
+// Proxies parses the IPs and the CIDR ranges of the trusted proxies
+func (s *Server) Proxies() ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+
+	for _, p := range strings.Split(s.TrustedProxies, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, errors.New(strs.Concat(errProxy, p))
+			}
+
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			proxies = append(proxies,
+				&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, ipnet, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, errors.Wrap(err, strs.Concat(errProxy, p))
+		}
+
+		proxies = append(proxies, ipnet)
+	}
+
+	return proxies, nil
 }
 
 // InternalURL returns internal URL address
@@ -135,12 +189,24 @@ func url(addr Address, fragment string) string {
 // Auth defines the auth external system based on oauth2
 type Auth struct {
 	// AuthProvider defines the auth provider. Possible values:
-	// test: Only for dev
-	// oauth2: Use oauth2
+	// dev: Only for dev
+	// oauth2: Use oauth2 with the URL templates
+	// oidc: Use OpenID Connect with discovery and PKCE
+	// local: Use the user accounts stored in the data provider
 	Provider AuthProvider `json:"provider,omitempty"`
 	// ClientID identify the client for oauth2
 	// Secrets can be applied
 	ClientID string `json:"clientId,omitempty"`
+	// ClientSecret is the secret of confidential clients.
+	// Only for oidc. Public clients leave it empty
+	// Secrets can be applied
+	ClientSecret string `json:"clientSecret,omitempty" secret:"true"`
+	// Issuer is the OpenID issuer URL. Only for oidc.
+	// The endpoints are read from .well-known/openid-configuration,
+	// so LoginURL, LogoutURL, JWKURL and TokenURL are not used
+	Issuer string `json:"issuer,omitempty"`
+	// Scopes are the scopes requested separated by space. Only for oidc
+	Scopes string `json:"scopes,omitempty"`
 	// LoginURL defines the page to login
 	// The "%redirect_uri" must be added to the signing URL fragment
 	// so that the authentication provider knows where to redirect
@@ -166,10 +232,45 @@ type Auth struct {
 	JWKURL string `json:"jwkUrl,omitempty"`
 	// TokenURL is the URL to get token
 	TokenURL string `json:"tokenUrl,omitempty"`
+	// RevokeURL is the URL to revoke the tokens on logout (RFC 7009).
+	// For example, https://<domain>/oauth2/revoke for AWS Cognito.
+	// If it is empty, the tokens are not revoked at the provider
+	RevokeURL string `json:"revokeUrl,omitempty"`
 	// RedirectURL is the base URL for redirecting provider requests
 	// If not defined,
 	// it will be formed based on the information in the Server configuration.
 	RedirectURL string `json:"redirectProxy,omitempty"`
+	// RolesClaim is the JWT claim that contains the user groups.
+	// For example, "cognito:groups" for AWS Cognito
+	RolesClaim string `json:"rolesClaim,omitempty"`
+	// Roles maps a group of the RolesClaim to an app role
+	// (viewer, operator, admin). If the group is not mapped,
+	// the group name is used as role
+	Roles map[string]string `json:"roles,omitempty"`
+	// DefaultRole is the role assigned to the authenticated users
+	// that do not belong to any known group.
+	// Empty does not grant any permission
+	DefaultRole string `json:"defaultRole,omitempty"`
+	// Local defines the local accounts. Only for local
+	Local LocalAuth `json:"local,omitempty"`
+}
+
+// LocalAuth defines the user accounts managed by the app
+type LocalAuth struct {
+	// Hash is the algorithm of the new password hashes (argon2, bcrypt).
+	// The existing hashes are checked with their own algorithm
+	Hash string `json:"hash,omitempty"`
+	// MaxAttempts is the number of failed logins allowed
+	// for an user from the same IP before it is locked
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// MaxOriginAttempts is the number of failed logins allowed
+	// from the same IP with any user before it is locked
+	MaxOriginAttempts int `json:"maxOriginAttempts,omitempty"`
+	// LockTime is the time in minutes an user or an IP is locked
+	// after the failed logins
+	LockTime int `json:"lockTime,omitempty"`
+	// Issuer is the name shown in the authenticator apps for TOTP
+	Issuer string `json:"issuer,omitempty"`
 }
 
 type API struct {
@@ -181,12 +282,12 @@ type API struct {
 	// ClientID is a identifier that allows the device
 	// and the hub to communicate securely.
 	// Secrets can be applied
-	ClientID string `json:"clientId,omitempty"`
+	ClientID string `json:"clientId,omitempty" secret:"true"`
 	// TokenSecretKey defines the secret key to generate the token
 	// that allows the device
 	// and the hub to communicate securely.
 	// Secrets can be applied
-	TokenSecretKey string `json:"tokenSecretKey,omitempty"`
+	TokenSecretKey string `json:"tokenSecretKey,omitempty" secret:"true"`
 	// HeartbeatInterval is the interval in seconds that
 	// the iot device sends a ping for heartbeat
 	HeartbeatInterval uint8 `json:"heartbeatInterval"`
@@ -203,11 +304,17 @@ type API struct {
 type Web struct {
 	// SessionExpiration defines the session expiration in minutes
 	SessionExpiration int `json:"expirationSession,omitempty"`
+	// RefreshExpiration defines in minutes how long the provider
+	// refresh token is kept to renew the session. 0 disables the renewal
+	RefreshExpiration int `json:"expirationRefresh,omitempty"`
+	// StateExpiration defines in minutes how long the state
+	// of a login is valid. Each state can only be used once
+	StateExpiration int `json:"expirationState,omitempty"`
 	// SecretKey defines a secret key to AES.
 	// It's used in state dance to avoid CRSF.
 	// Must be of 32 bytes
 	// Secrets can be applied
-	SecretKey string `json:"secretKey,omitempty"`
+	SecretKey string `json:"secretKey,omitempty" secret:"true"`
 	// Auth defines the auth external system
 	Auth Auth `json:"auth,omitempty"`
 }
@@ -242,7 +349,7 @@ type AWS struct {
 	// Access key ID
 	AKID string `json:"akid,omitempty"`
 	// Secret key ID
-	SecretKey string `json:"secretKey,omitempty"`
+	SecretKey string `json:"secretKey,omitempty" secret:"true"`
 	// Region is the region identifier
 	Region string `json:"region,omitempty"`
 }
@@ -253,6 +360,12 @@ type AWSData struct {
 	ConfigTableName string `json:"configTableName,omitempty"`
 	// SamplesTableName is the name samples table dynamodb
 	SamplesTableName string `json:"samplesTableName,omitempty"`
+	// UsersTableName is the name of the local users table dynamodb
+	UsersTableName string `json:"usersTableName,omitempty"`
+	// APIKeysTableName is the name of the api keys table dynamodb
+	APIKeysTableName string `json:"apiKeysTableName,omitempty"`
+	// AuditTableName is the name of the audit log table dynamodb
+	AuditTableName string `json:"auditTableName,omitempty"`
 }
 
 // FileData defines file data configuration
@@ -261,6 +374,19 @@ type FileData struct {
 	ConfigFile string `json:"config,omitempty"`
 	// SampleFile is the sample file path
 	SampleFile string `json:"sample,omitempty"`
+	// UsersFile is the local users file path
+	UsersFile string `json:"users,omitempty"`
+	// APIKeysFile is the api keys file path
+	APIKeysFile string `json:"apiKeys,omitempty"`
+	// AuditFile is the audit log file path
+	AuditFile string `json:"audit,omitempty"`
+}
+
+// SQLData defines the embedded sql database configuration
+type SQLData struct {
+	// File is the SQLite database file path. It is created
+	// and migrated at startup
+	File string `json:"file,omitempty"`
 }
 
 // Data defines the data configuration
@@ -271,12 +397,45 @@ type Data struct {
 	File FileData `json:"file,omitempty"`
 	// AWS is the aws data provider
 	AWS AWSData `json:"aws,omitempty"`
+	// SQL is the sql data provider
+	SQL SQLData `json:"sql,omitempty"`
 }
 
 // Secrets defines the secrets configuration
 type Secrets struct {
-	// Name is the secrets name
+	// Provider is the secret provider. Possible values:
+	// none, aws, file, env, vault.
+	// If it is empty, aws is used when the cloud provider is aws
+	// and the name is configured
+	Provider SecretProvider `json:"provider,omitempty"`
+	// Name is the secrets name. For aws, the secret name.
+	// For vault, the secret path in the KV v2 engine
 	Name string `json:"name,omitempty"`
+	// Dir is the directory of the secrets mounted as files
+	// for the file provider. Each file is a secret key
+	Dir string `json:"dir,omitempty"`
+	// EnvPrefix prefixes the environment variables of the env provider.
+	// The variable name without the prefix is the secret key
+	EnvPrefix string `json:"envPrefix,omitempty"`
+	// Vault defines the vault provider
+	Vault Vault `json:"vault,omitempty"`
+}
+
+// Vault defines the HashiCorp Vault connection. It authenticates with
+// the token or, if it is empty, with the AppRole role and secret IDs
+type Vault struct {
+	// Address is the vault server URL, for example http://127.0.0.1:8200
+	Address string `json:"address,omitempty"`
+	// Mount is the path of the KV v2 secrets engine
+	Mount string `json:"mount,omitempty"`
+	// Namespace is the vault enterprise namespace
+	Namespace string `json:"namespace,omitempty"`
+	// Token is the vault token
+	Token string `json:"token,omitempty" secret:"true"`
+	// RoleID is the AppRole role ID
+	RoleID string `json:"roleId,omitempty"`
+	// SecretID is the AppRole secret ID
+	SecretID string `json:"secretId,omitempty" secret:"true"`
 }
 
 // Location defines the location info
@@ -302,7 +461,7 @@ type Config struct {
 	API      `json:"api,omitempty"`
 	Hub      `json:"hub,omitempty"`
 	Cloud    Cloud   `json:"cloud,omitempty"`
-	Secret   Secrets `json:"secret,omitempty"`
+	Secret   Secrets `json:"secret,omitempty" secretref:"-"`
 	Data     Data    `json:"data,omitempty"`
 	IOT      IOT     `json:"iot,omitempty"`
 }
@@ -343,9 +502,21 @@ func Default() Config {
 		},
 		Web: Web{
 			SessionExpiration: 10,
+			RefreshExpiration: 1440,
+			StateExpiration:   10,
 			SecretKey:         "123456789asdfghjklzxcvbnmqwertyu", // Only for dev
 			Auth: Auth{
-				Provider: AuthProviderDev,
+				Provider:    AuthProviderDev,
+				Scopes:      "openid email profile",
+				RolesClaim:  "cognito:groups",
+				DefaultRole: "viewer",
+				Local: LocalAuth{
+					Hash:              "argon2",
+					MaxAttempts:       5,
+					MaxOriginAttempts: 20,
+					LockTime:          15,
+					Issuer:            "swpc",
+				},
 			},
 		},
 		API: API{
@@ -364,6 +535,13 @@ func Default() Config {
 		Cloud: Cloud{
 			Provider: NoneCloudProvider,
 		},
+		Secret: Secrets{
+			Dir:       "/run/secrets",
+			EnvPrefix: "SECRET_",
+			Vault: Vault{
+				Mount: "secret",
+			},
+		},
 		Data: Data{
 			Provider: NoneDataProvider,
 		},
@@ -384,111 +562,95 @@ func (c *Config) String() string {
 	return string(r)
 }
 
-// LoadConfig loads the configuration from environment variable
-func LoadConfig() Config { //nolint:cyclop
-	cnf := Default()
-
-	env := os.Getenv(ENVConfig)
-
-	if len(env) != 0 {
-		if err := json.Unmarshal([]byte(env), &cnf); err != nil {
-			panic(
-				strs.Format(errEnvConfig, strs.FMTValue("Description", err.Error())))
-		}
-	}
-
-	if err := os.Unsetenv(ENVConfig); err != nil {
-		panic(errUnsetEnv)
+// LoadConfig loads the configuration of the sources over the defaults
+// and validates it. The error lists every invalid field
+func LoadConfig(src Sources) (Config, error) {
+	cnf, err := Load(src)
+	if err != nil {
+		return cnf, err
 	}
 
-	if cnf.Encoding != "json" && cnf.Encoding != "console" {
-		panic(errLogEncoding)
+	for _, env := range []string{ENVConfig, ENVMasterKey} {
+		if err := os.Unsetenv(env); err != nil {
+			return cnf, errors.Wrap(err, errUnsetEnv)
+		}
 	}
 
-	if !(cnf.Level >= -1 && cnf.Level <= 5) {
-		panic(errLogLevel)
-	}
+	return cnf, cnf.Validate()
+}
 
-	if cnf.Auth.Provider != AuthProviderDev &&
-		cnf.Auth.Provider != AuthProviderOauth2 {
-		panic(errAuthProvider)
-	}
+// ApplySecret replaces the secret references, a secret key preceded
+// by "@@", of every string field of the configuration with the value
+// of the secret. The fields tagged secretref:"-", as the secret provider
+// settings, are not replaced. The provider is only called if there are
+// references, and the references that are not found are returned
+// as a ValidationError
+func ApplySecret(s Secret, config *Config) error {
+	v := reflect.ValueOf(config).Elem()
 
-	if cnf.Cloud.Provider != NoneCloudProvider &&
-		cnf.Cloud.Provider != CloudAWSProvider {
-		panic(errCloudProvider)
-	}
+	refs := false
 
-	if cnf.Data.Provider != FileDataProvider &&
-		cnf.Data.Provider != CloudDataProvider &&
-		cnf.Data.Provider != NoneDataProvider {
-		panic(errCloudProvider)
-	}
+	stringFields(v, "", "secretref", func(_ string, f reflect.Value) {
+		refs = refs || secretRef.MatchString(f.String())
+	})
 
-	if cnf.HeartbeatInterval == 0 ||
-		cnf.HeartbeatTimeoutCount == 0 {
-		panic(errHeatbeat)
+	if !refs {
+		return nil
 	}
 
-	return cnf
-}
-
-// ApplySecret calls the secret provider and if the configuration
-// value exists contains a secret name preceded by "@@",
-// applies the value of the secret to the configuration key
-func ApplySecret(s Secret, config *Config) {
 	secrets, err := s.Get(config.Secret.Name)
 	if err != nil {
-		panic(strs.Format(errGets, strs.FMTValue("Name", err.Error())))
-	}
-
-	if len(secrets) == 0 {
-		return
+		return errors.Wrap(err, errGets)
 	}
 
-	re := regexp.MustCompile(`@@[a-zA-Z0-9_]+`)
+	var errs ValidationError
 
-	config.Auth.ClientID = getSecretValue(re, secrets, config.Auth.ClientID)
-	config.Auth.JWKURL = getSecretValue(re, secrets, config.Auth.JWKURL)
+	stringFields(v, "", "secretref", func(path string, f reflect.Value) {
+		f.SetString(secretRef.ReplaceAllStringFunc(
+			f.String(),
+			func(ref string) string {
+				value, ok := secrets[ref[2:]]
+				if !ok {
+					errs = append(errs, FieldError{
+						Field:   path,
+						Message: errSecretRef + ref,
+					})
 
-	config.API.ClientID = getSecretValue(re, secrets, config.API.ClientID)
-	config.API.TokenSecretKey = getSecretValue(
-		re,
-		secrets,
-		config.API.TokenSecretKey)
+					return ref
+				}
 
-	config.Web.SecretKey = getSecretValue(re, secrets, config.Web.SecretKey)
-}
-
-func getSecretValue(
-	re *regexp.Regexp,
-	secrets map[string]string,
-	value string) string {
-	//
-	matchs := re.FindAllString(value, -1)
+				return value
+			}))
+	})
 
-	if matchs == nil {
-		return value
+	if len(errs) == 0 {
+		return nil
 	}
 
-	r := make([]string, len(secrets)*2)
-
-	var i uint8
+	return errs
+}
 
-	for _, m := range matchs {
-		k := m[2:]
-		s, ok := secrets[k]
+// stringFields calls fn with the json path and the value of every
+// string field. The fields tagged with skip:"-" are not visited
+func stringFields(
+	v reflect.Value,
+	prefix string,
+	skip string,
+	fn func(path string, f reflect.Value)) {
+	//
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
 
-		if ok {
-			r[i] = m
-			i++
+		name := jsonName(sf)
+		if name == "-" || (skip != "" && sf.Tag.Get(skip) == "-") {
+			continue
+		}
 
-			r[i] = s
-			i++
+		switch sf.Type.Kind() { //nolint:exhaustive
+		case reflect.Struct:
+			stringFields(v.Field(i), prefix+name+".", skip, fn)
+		case reflect.String:
+			fn(prefix+name, v.Field(i))
 		}
 	}
-
-	replacer := strings.NewReplacer(r...)
-
-	return replacer.Replace(value)
 }
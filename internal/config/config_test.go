@@ -18,9 +18,11 @@
 package config_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/swpoolcontroller/internal/config"
 	"github.com/swpoolcontroller/internal/config/mocks"
 )
@@ -57,7 +59,7 @@ func TestLoadConfig(t *testing.T) {
 				"log": {"development": false, "level": 3, "hide": true},
 				"web": {
 					"expirationSession": 15,
-					"secretKey": "123",
+					"secretKey": "@@secretKey",
 					"auth": {
 						"provider": "oauth2",
 						"clientId": "clientId",
@@ -129,7 +131,9 @@ func TestLoadConfig(t *testing.T) {
 				},
 				Web: config.Web{
 					SessionExpiration: 15,
-					SecretKey:         "123",
+					RefreshExpiration: 1440,
+					StateExpiration:   10,
+					SecretKey:         "@@secretKey",
 					Auth: config.Auth{
 						Provider:    "oauth2",
 						ClientID:    "clientId",
@@ -138,6 +142,16 @@ func TestLoadConfig(t *testing.T) {
 						JWKURL:      "jwkUrl",
 						TokenURL:    "tokenUrl",
 						RedirectURL: "redirectProxy",
+						Scopes:      "openid email profile",
+						RolesClaim:  "cognito:groups",
+						DefaultRole: "viewer",
+						Local: config.LocalAuth{
+							Hash:              "argon2",
+							MaxAttempts:       5,
+							MaxOriginAttempts: 20,
+							LockTime:          15,
+							Issuer:            "swpc",
+						},
 					},
 				},
 				API: config.API{
@@ -162,7 +176,12 @@ func TestLoadConfig(t *testing.T) {
 					},
 				},
 				Secret: config.Secrets{
-					Name: "name",
+					Name:      "name",
+					Dir:       "/run/secrets",
+					EnvPrefix: "SECRET_",
+					Vault: config.Vault{
+						Mount: "secret",
+					},
 				},
 				Data: config.Data{
 					Provider: config.CloudDataProvider,
@@ -185,14 +204,16 @@ func TestLoadConfig(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			t.Setenv(config.ENVConfig, tt.env)
+			src := config.Sources{Env: []string{config.ENVConfig + "=" + tt.env}}
 
-			assert.Equal(t, tt.res, config.LoadConfig())
+			c, err := config.LoadConfig(src)
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, c)
 		})
 	}
 }
 
-func TestLoadConfig_Panic(t *testing.T) {
+func TestLoadConfig_Error(t *testing.T) {
 	tests := []struct {
 		name string
 		env  string
@@ -213,6 +234,20 @@ func TestLoadConfig_Panic(t *testing.T) {
 			name: "Config. Auth provider incorrect",
 			env:  `{"web": {"auth": { "provider": "no_exist"}}}`,
 		},
+		{
+			name: "Config. OIDC provider without issuer",
+			env:  `{"web": {"auth": { "provider": "oidc"}}}`,
+		},
+		{
+			name: "Config. Local provider without users store",
+			env:  `{"web": {"auth": { "provider": "local"}}}`,
+		},
+		{
+			name: "Config. Local provider with wrong hash",
+			env: `{"web": {"auth": { "provider": "local", ` +
+				`"local": {"hash": "md5"}}}, ` +
+				`"data": {"provider": "file", "file": {"users": "./users.json"}}}`,
+		},
 		{
 			name: "Config. Cloud provider incorrect",
 			env:  `{"cloud": { "provider": "no_exist"}}`,
@@ -233,9 +268,10 @@ func TestLoadConfig_Panic(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			t.Setenv(config.ENVConfig, tt.env)
+			src := config.Sources{Env: []string{config.ENVConfig + "=" + tt.env}}
 
-			assert.Panics(t, func() { config.LoadConfig() })
+			_, err := config.LoadConfig(src)
+			assert.Error(t, err)
 		})
 	}
 }
@@ -300,6 +336,33 @@ func TestServer_ExternalURL(t *testing.T) {
 	assert.Equal(t, "https://localhost:2020/fragment", res)
 }
 
+func TestServer_Proxies(t *testing.T) {
+	t.Parallel()
+
+	s := config.Server{TrustedProxies: "10.0.0.1, 10.1.0.0/16,::1"}
+
+	proxies, err := s.Proxies()
+
+	require.NoError(t, err)
+	require.Len(t, proxies, 3)
+	assert.Equal(t, "10.0.0.1/32", proxies[0].String())
+	assert.Equal(t, "10.1.0.0/16", proxies[1].String())
+	assert.Equal(t, "::1/128", proxies[2].String())
+
+	s.TrustedProxies = ""
+
+	proxies, err = s.Proxies()
+
+	require.NoError(t, err)
+	assert.Empty(t, proxies, "Without proxies")
+
+	s.TrustedProxies = "10.0.0.1/40"
+
+	_, err = s.Proxies()
+
+	require.Error(t, err)
+}
+
 func TestConfig_AuthRedirectURI(t *testing.T) {
 	type fields struct {
 		RedirectURL string
@@ -368,44 +431,26 @@ func TestApplySecret(t *testing.T) {
 	type args struct {
 		config  config.Config
 		secrets map[string]string
+		err     error
 	}
 
 	tests := []struct {
 		name     string
 		args     args
 		expected config.Config
+		fields   []string
+		wantErr  bool
 	}{
 		{
-			name: `Apply Secret. Secret name not exists.
-			 			It should return the same configuration values`,
+			name: `Apply Secret. No references.
+						It should not call the provider`,
 			args: args{
 				config: config.Config{
-					Web: config.Web{
-						SecretKey: "@@SecretKey",
-						Auth: config.Auth{
-							ClientID: "@@ClientID",
-							JWKURL:   "http://@@ID",
-						},
-					},
-					API: config.API{
-						ClientID:       "@@ClientID",
-						TokenSecretKey: "@@TokenSecretKey",
-					},
+					Web: config.Web{SecretKey: "SecretKey"},
 				},
-				secrets: map[string]string{},
 			},
 			expected: config.Config{
-				Web: config.Web{
-					SecretKey: "@@SecretKey",
-					Auth: config.Auth{
-						ClientID: "@@ClientID",
-						JWKURL:   "http://@@ID",
-					},
-				},
-				API: config.API{
-					ClientID:       "@@ClientID",
-					TokenSecretKey: "@@TokenSecretKey",
-				},
+				Web: config.Web{SecretKey: "SecretKey"},
 			},
 		},
 		{
@@ -416,48 +461,140 @@ func TestApplySecret(t *testing.T) {
 					Web: config.Web{
 						SecretKey: "@@SecretKey",
 						Auth: config.Auth{
-							ClientID: "@@ClientID",
 							JWKURL:   "http://@@ID/part",
+							TokenURL: "http://@@ID/@@Path",
 						},
 					},
 					API: config.API{
 						ClientID:       "ClientID",
 						TokenSecretKey: "@@TokenSecretKey",
 					},
+					Cloud: config.Cloud{
+						AWS: config.AWS{SecretKey: "@@AWSKey"},
+					},
+					Secret: config.Secrets{Name: "swpc"},
+					Data: config.Data{
+						AWS: config.AWSData{ConfigTableName: "@@Table"},
+					},
 				},
 				secrets: map[string]string{
 					"SecretKey":      "123",
 					"TokenSecretKey": "1234",
 					"ID":             "12345",
+					"Path":           "token",
+					"AWSKey":         "aws",
+					"Table":          "config",
 				},
 			},
 			expected: config.Config{
 				Web: config.Web{
 					SecretKey: "123",
 					Auth: config.Auth{
-						ClientID: "@@ClientID",
 						JWKURL:   "http://12345/part",
+						TokenURL: "http://12345/token",
 					},
 				},
 				API: config.API{
 					ClientID:       "ClientID",
 					TokenSecretKey: "1234",
 				},
+				Cloud: config.Cloud{
+					AWS: config.AWS{SecretKey: "aws"},
+				},
+				Secret: config.Secrets{Name: "swpc"},
+				Data: config.Data{
+					AWS: config.AWSData{ConfigTableName: "config"},
+				},
 			},
 		},
+		{
+			name: `Apply Secret. Secret settings.
+						It should not replace the secret provider settings`,
+			args: args{
+				config: config.Config{
+					Web: config.Web{SecretKey: "@@SecretKey"},
+					Secret: config.Secrets{
+						Name:  "@@SecretKey",
+						Vault: config.Vault{Token: "@@SecretKey"},
+					},
+				},
+				secrets: map[string]string{"SecretKey": "123"},
+			},
+			expected: config.Config{
+				Web: config.Web{SecretKey: "123"},
+				Secret: config.Secrets{
+					Name:  "@@SecretKey",
+					Vault: config.Vault{Token: "@@SecretKey"},
+				},
+			},
+		},
+		{
+			name: `Apply Secret. Secret name not exists.
+						It should return the unresolved fields`,
+			args: args{
+				config: config.Config{
+					Web: config.Web{
+						SecretKey: "@@SecretKey",
+						Auth: config.Auth{
+							ClientID: "@@ClientID",
+							JWKURL:   "http://@@ID",
+						},
+					},
+				},
+				secrets: map[string]string{"ID": "12345"},
+			},
+			fields:  []string{"web.secretKey", "web.auth.clientId"},
+			wantErr: true,
+		},
+		{
+			name: `Apply Secret. Provider error.
+						It should return an error`,
+			args: args{
+				config: config.Config{
+					Web: config.Web{SecretKey: "@@SecretKey"},
+				},
+				err: errors.New("provider"),
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
 			s := mocks.NewSecret(t)
-			s.On("Get", tt.args.config.Secret.Name).Return(tt.args.secrets, nil)
+
+			if tt.args.secrets != nil || tt.args.err != nil {
+				s.On("Get", tt.args.config.Secret.Name).
+					Return(tt.args.secrets, tt.args.err)
+			}
 
 			c := tt.args.config
-			config.ApplySecret(s, &c)
+			err := config.ApplySecret(s, &c)
+
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, c)
+
+				return
+			}
 
-			assert.Equal(t, tt.expected, c)
+			require.Error(t, err)
+
+			if tt.fields == nil {
+				return
+			}
+
+			var verr config.ValidationError
+			require.ErrorAs(t, err, &verr)
+
+			fields := make([]string, 0, len(verr))
+			for _, f := range verr {
+				fields = append(fields, f.Field)
+			}
 
-			s.AssertExpectations(t)
+			assert.Equal(t, tt.fields, fields)
 		})
 	}
 }
@@ -0,0 +1,165 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/pkg/crypto"
+)
+
+// EncryptedPrefix prefixes the config values encrypted with the master key.
+// The value is enc:<base64 of the AES-GCM ciphertext>
+const EncryptedPrefix = "enc:"
+
+const (
+	// ENVMasterKey is the environment variable of the base64 master key
+	ENVMasterKey = "SW_POOL_CONTROLLER_MASTER_KEY"
+	// ENVMasterKeyFile is the environment variable of the file
+	// that contains the base64 master key
+	ENVMasterKeyFile = "SW_POOL_CONTROLLER_MASTER_KEY_FILE"
+)
+
+const (
+	errMasterKey     = "The master key cannot be read"
+	errMasterKeySize = "The master key must be 32 bytes in base64"
+	errNoMasterKey   = "The value is encrypted and the master key " +
+		"is not configured"
+	errDecryptValue = "The value cannot be decrypted"
+	errEncryptValue = "The value cannot be encrypted"
+)
+
+// MasterKey reads the master key of the ENVMasterKey variable or,
+// if it is not set, of the file of the ENVMasterKeyFile variable.
+// env are the environment variables in the form key=value.
+// It returns nil if none is set
+func MasterKey(env []string) ([]byte, error) {
+	var key, file string
+
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+
+		switch k {
+		case ENVMasterKey:
+			key = v
+		case ENVMasterKeyFile:
+			file = v
+		}
+	}
+
+	if key == "" && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, errMasterKey)
+		}
+
+		key = string(data)
+	}
+
+	return ParseMasterKey(key)
+}
+
+// ParseMasterKey decodes the base64 master key.
+// It returns nil if the key is empty
+func ParseMasterKey(key string) ([]byte, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.Wrap(err, errMasterKeySize)
+	}
+
+	if len(data) != secretKeySize {
+		return nil, errors.New(errMasterKeySize)
+	}
+
+	return data, nil
+}
+
+// EncryptValue encrypts the value with the master key
+// and returns it with the EncryptedPrefix
+func EncryptValue(key []byte, value string) (string, error) {
+	ciphertext, err := crypto.Encrypt(key, []byte(value))
+	if err != nil {
+		return "", errors.Wrap(err, errEncryptValue)
+	}
+
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptValue decrypts the value encrypted by EncryptValue.
+// The EncryptedPrefix is optional
+func DecryptValue(key []byte, value string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(
+		strings.TrimPrefix(value, EncryptedPrefix))
+	if err != nil {
+		return "", errors.Wrap(err, errDecryptValue)
+	}
+
+	plaintext, err := crypto.Decrypt(key, ciphertext)
+	if err != nil {
+		return "", errors.Wrap(err, errDecryptValue)
+	}
+
+	return string(plaintext), nil
+}
+
+// decryptValues decrypts every string field with the EncryptedPrefix.
+// The fields that cannot be decrypted are returned as a ValidationError
+func decryptValues(cnf *Config, key []byte) error {
+	var errs ValidationError
+
+	stringFields(
+		reflect.ValueOf(cnf).Elem(),
+		"",
+		"",
+		func(path string, f reflect.Value) {
+			if !strings.HasPrefix(f.String(), EncryptedPrefix) {
+				return
+			}
+
+			if key == nil {
+				errs = append(errs,
+					FieldError{Field: path, Message: errNoMasterKey})
+
+				return
+			}
+
+			value, err := DecryptValue(key, f.String())
+			if err != nil {
+				errs = append(errs,
+					FieldError{Field: path, Message: err.Error()})
+
+				return
+			}
+
+			f.SetString(value)
+		})
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
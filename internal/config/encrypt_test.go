@@ -0,0 +1,180 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package config_test
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/config"
+)
+
+const masterKey = "MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI="
+
+func TestMasterKey(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	keyFile := filepath.Join(dir, "master.key")
+	require.NoError(t,
+		os.WriteFile(keyFile, []byte(masterKey+"\n"), 0o600))
+
+	want, _ := base64.StdEncoding.DecodeString(masterKey)
+
+	tests := []struct {
+		name string
+		env  []string
+		want []byte
+		err  bool
+	}{
+		{
+			name: "Env var. It should return the key",
+			env:  []string{config.ENVMasterKey + "=" + masterKey},
+			want: want,
+		},
+		{
+			name: "Key file. It should return the key",
+			env:  []string{config.ENVMasterKeyFile + "=" + keyFile},
+			want: want,
+		},
+		{
+			name: "Not set. It should return nil",
+			env:  []string{"OTHER=1"},
+		},
+		{
+			name: "Short key. It should return an error",
+			env:  []string{config.ENVMasterKey + "=MTIz"},
+			err:  true,
+		},
+		{
+			name: "Unknown file. It should return an error",
+			env: []string{
+				config.ENVMasterKeyFile + "=" + filepath.Join(dir, "none"),
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := config.MasterKey(tt.env)
+
+			if tt.err {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, key)
+		})
+	}
+}
+
+func TestEncryptValue(t *testing.T) {
+	t.Parallel()
+
+	key, err := config.ParseMasterKey(masterKey)
+	require.NoError(t, err)
+
+	value, err := config.EncryptValue(key, "secret")
+	require.NoError(t, err)
+	assert.Contains(t, value, config.EncryptedPrefix)
+
+	plaintext, err := config.DecryptValue(key, value)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", plaintext)
+
+	other, err := config.ParseMasterKey(
+		base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+
+	_, err = config.DecryptValue(other, value)
+	require.Error(t, err)
+}
+
+func TestLoad_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	key, err := config.ParseMasterKey(masterKey)
+	require.NoError(t, err)
+
+	secretKey, err := config.EncryptValue(
+		key, "12345678901234567890123456789012")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		env    []string
+		fields []string
+	}{
+		{
+			name: "Master key. It should decrypt the values",
+			env: []string{
+				config.ENVMasterKey + "=" + masterKey,
+				"SWPC_WEB_SECRETKEY=" + secretKey,
+			},
+		},
+		{
+			name:   "No master key. It should return the field",
+			env:    []string{"SWPC_WEB_SECRETKEY=" + secretKey},
+			fields: []string{"web.secretKey"},
+		},
+		{
+			name: "Invalid value. It should return the field",
+			env: []string{
+				config.ENVMasterKey + "=" + masterKey,
+				"SWPC_WEB_SECRETKEY=" + secretKey,
+				"SWPC_API_CLIENTID=enc:invalid",
+			},
+			fields: []string{"api.clientId"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := config.Load(config.Sources{Env: tt.env})
+
+			if len(tt.fields) == 0 {
+				require.NoError(t, err)
+				assert.Equal(t,
+					"12345678901234567890123456789012", c.Web.SecretKey)
+
+				return
+			}
+
+			var verr config.ValidationError
+			require.ErrorAs(t, err, &verr)
+
+			fields := make([]string, 0, len(verr))
+			for _, f := range verr {
+				fields = append(fields, f.Field)
+			}
+
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables that override a config key.
+// The key is the path of json names in upper case separated by "_".
+// For example, SWPC_API_HEARTBEATINTERVAL overrides api.heartbeatInterval
+const EnvPrefix = "SWPC_"
+
+const (
+	errConfigFile = "Config file cannot be loaded"
+	errConfigKey  = "Config key cannot be set"
+	errUnknownKey = "Unknown config key"
+)
+
+// redacted replaces the secret values when the config is printed
+const redacted = "******"
+
+// Sources are the layers of the configuration applied over the defaults.
+// The latter override the former: the config file, the ENVConfig json,
+// the SWPC_ environment variables and the flags
+type Sources struct {
+	// File is the json or yaml config file. If it is empty,
+	// no file is read
+	File string
+	// Env are the environment variables in the form key=value
+	Env []string
+	// Flags are the config keys set in the command line.
+	// The keys are the json path separated by ".",
+	// for example api.heartbeatInterval. They are case insensitive
+	Flags map[string]string
+}
+
+// Load layers the sources over the default configuration.
+// The encrypted values are decrypted with the master key of the environment
+func Load(src Sources) (Config, error) {
+	cnf := Default()
+
+	if src.File != "" {
+		if err := loadFile(src.File, &cnf); err != nil {
+			return cnf, err
+		}
+	}
+
+	keys := make(map[string]string)
+
+	for _, kv := range src.Env {
+		k, v, _ := strings.Cut(kv, "=")
+
+		switch {
+		case k == ENVConfig && v != "":
+			if err := json.Unmarshal([]byte(v), &cnf); err != nil {
+				return cnf, errors.Wrap(err, errEnvConfig)
+			}
+		case strings.HasPrefix(k, EnvPrefix):
+			keys[k] = v
+		}
+	}
+
+	envKey := func(k string) string {
+		return strings.TrimPrefix(k, EnvPrefix)
+	}
+
+	if err := setKeys(&cnf, keys, envKey); err != nil {
+		return cnf, err
+	}
+
+	flagKey := func(k string) string {
+		return strings.ReplaceAll(k, ".", "_")
+	}
+
+	if err := setKeys(&cnf, src.Flags, flagKey); err != nil {
+		return cnf, err
+	}
+
+	key, err := MasterKey(src.Env)
+	if err != nil {
+		return cnf, err
+	}
+
+	return cnf, decryptValues(&cnf, key)
+}
+
+// Redacted returns a copy of the configuration without the values
+// of the fields tagged as secret, so it can be printed
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+
+	return c
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+
+		switch {
+		case f.Kind() == reflect.Struct:
+			redact(f)
+		case v.Type().Field(i).Tag.Get("secret") == "true" &&
+			f.Kind() == reflect.String && f.Len() > 0:
+			f.SetString(redacted)
+		}
+	}
+}
+
+// loadFile reads the json or yaml config file over the configuration.
+// The yaml keys are the same as the json keys
+func loadFile(name string, cnf *Config) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return errors.Wrap(err, errConfigFile)
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".yaml" || ext == ".yml" {
+		var m map[string]interface{}
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return errors.Wrap(err, errConfigFile)
+		}
+
+		if data, err = json.Marshal(m); err != nil {
+			return errors.Wrap(err, errConfigFile)
+		}
+	}
+
+	if err := json.Unmarshal(data, cnf); err != nil {
+		return errors.Wrap(err, errConfigFile)
+	}
+
+	return nil
+}
+
+// setKeys sets the values of the keys. path converts each key
+// to the path of json names separated by "_", which is case insensitive
+func setKeys(
+	cnf *Config,
+	keys map[string]string,
+	path func(key string) string) error {
+	//
+	if len(keys) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Value)
+	indexFields(reflect.ValueOf(cnf).Elem(), "", fields)
+
+	for k, v := range keys {
+		f, ok := fields[strings.ToUpper(path(k))]
+		if !ok {
+			return errors.Errorf("%s: %s", errUnknownKey, k)
+		}
+
+		if err := setValue(f, v); err != nil {
+			return errors.Wrap(err, errConfigKey+": "+k)
+		}
+	}
+
+	return nil
+}
+
+// indexFields maps the key of every field, including the structs,
+// to its value
+func indexFields(
+	v reflect.Value,
+	prefix string,
+	fields map[string]reflect.Value) {
+	//
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+
+		name := jsonName(sf)
+		if name == "-" {
+			continue
+		}
+
+		key := prefix + strings.ToUpper(name)
+		fields[key] = v.Field(i)
+
+		if sf.Type.Kind() == reflect.Struct {
+			indexFields(v.Field(i), key+"_", fields)
+		}
+	}
+}
+
+// Changes returns the json path of the fields that differ,
+// for example api.heartbeatInterval
+func Changes(before Config, after Config) []string {
+	var changes []string
+
+	changedFields(reflect.ValueOf(before), reflect.ValueOf(after), "", &changes)
+
+	return changes
+}
+
+func changedFields(
+	before reflect.Value,
+	after reflect.Value,
+	prefix string,
+	changes *[]string) {
+	//
+	for i := 0; i < before.NumField(); i++ {
+		sf := before.Type().Field(i)
+
+		name := jsonName(sf)
+		if name == "-" {
+			continue
+		}
+
+		path := prefix + name
+
+		if sf.Type.Kind() == reflect.Struct {
+			changedFields(before.Field(i), after.Field(i), path+".", changes)
+
+			continue
+		}
+
+		if !reflect.DeepEqual(
+			before.Field(i).Interface(),
+			after.Field(i).Interface()) {
+			//
+			*changes = append(*changes, path)
+		}
+	}
+}
+
+// jsonName is the json name of the field or the field name
+// if it has not json tag
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+
+	return name
+}
+
+// setValue parses the value by the kind of the field.
+// The structs and the maps are json
+func setValue(f reflect.Value, value string) error {
+	var err error
+
+	switch f.Kind() { //nolint:exhaustive
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(value); err == nil {
+			f.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(value, 10, f.Type().Bits()); err == nil {
+			f.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(value, 10, f.Type().Bits()); err == nil {
+			f.SetUint(n)
+		}
+	default:
+		err = json.Unmarshal([]byte(value), f.Addr().Interface())
+	}
+
+	return err //nolint:wrapcheck
+}
@@ -0,0 +1,173 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/config"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	yamlFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte(`
+api:
+  heartbeatInterval: 20
+  heartbeatPingTime: 8
+web:
+  auth:
+    roles:
+      pool-admins: admin
+`), 0o600))
+
+	jsonFile := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(
+		jsonFile,
+		[]byte(`{"api": {"heartbeatInterval": 20}}`),
+		0o600))
+
+	tests := []struct {
+		name  string
+		src   config.Sources
+		check func(t *testing.T, c config.Config)
+		err   bool
+	}{
+		{
+			name: "Yaml file. It should override the defaults",
+			src:  config.Sources{File: yamlFile},
+			check: func(t *testing.T, c config.Config) {
+				t.Helper()
+				assert.Equal(t, uint8(20), c.HeartbeatInterval)
+				assert.Equal(t, uint8(8), c.HeartbeatPingTime)
+				assert.Equal(t, "admin", c.Auth.Roles["pool-admins"])
+				assert.Equal(t, config.Default().HeartbeatTimeoutCount,
+					c.HeartbeatTimeoutCount)
+			},
+		},
+		{
+			name: "Env vars. They should override the file",
+			src: config.Sources{
+				File: jsonFile,
+				Env: []string{
+					config.ENVConfig + `={"api": {"heartbeatPingTime": 9}}`,
+					"SWPC_API_HEARTBEATINTERVAL=30",
+					"SWPC_WEB_AUTH_LOCAL_HASH=bcrypt",
+					"SWPC_IOT_SAMPLEUI=true",
+					"SWPC_WEB_AUTH_ROLES={\"admins\":\"admin\"}",
+					"OTHER=1",
+				},
+			},
+			check: func(t *testing.T, c config.Config) {
+				t.Helper()
+				assert.Equal(t, uint8(30), c.HeartbeatInterval)
+				assert.Equal(t, uint8(9), c.HeartbeatPingTime)
+				assert.Equal(t, "bcrypt", c.Auth.Local.Hash)
+				assert.True(t, c.IOT.SampleUI)
+				assert.Equal(t, "admin", c.Auth.Roles["admins"])
+			},
+		},
+		{
+			name: "Flags. They should override the env vars",
+			src: config.Sources{
+				Env:   []string{"SWPC_API_HEARTBEATINTERVAL=30"},
+				Flags: map[string]string{"api.heartbeatInterval": "40"},
+			},
+			check: func(t *testing.T, c config.Config) {
+				t.Helper()
+				assert.Equal(t, uint8(40), c.HeartbeatInterval)
+			},
+		},
+		{
+			name: "Unknown env var. It should return error",
+			src:  config.Sources{Env: []string{"SWPC_API_NOPE=1"}},
+			err:  true,
+		},
+		{
+			name: "Wrong value. It should return error",
+			src: config.Sources{
+				Flags: map[string]string{"api.heartbeatInterval": "300"},
+			},
+			err: true,
+		},
+		{
+			name: "File not found. It should return error",
+			src:  config.Sources{File: filepath.Join(dir, "none.yaml")},
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := config.Load(tt.src)
+			if tt.err {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	t.Parallel()
+
+	c := config.Default()
+	c.Cloud.AWS.SecretKey = "aws"
+
+	r := c.Redacted()
+
+	assert.Equal(t, "******", r.Web.SecretKey)
+	assert.Equal(t, "******", r.API.TokenSecretKey)
+	assert.Equal(t, "******", r.Cloud.AWS.SecretKey)
+	assert.Empty(t, r.Auth.ClientSecret, "Empty values are kept")
+	assert.Equal(t, c.HeartbeatInterval, r.HeartbeatInterval)
+	assert.Equal(t, "aws", c.Cloud.AWS.SecretKey, "The config is not modified")
+}
+
+func TestChanges(t *testing.T) {
+	t.Parallel()
+
+	before := config.Default()
+	after := before
+	after.HeartbeatInterval = 60
+	after.Auth.Roles = map[string]string{"admins": "admin"}
+	after.Internal.Port = 6000
+
+	assert.Equal(
+		t,
+		[]string{
+			"server.internal.port",
+			"web.auth.roles",
+			"api.heartbeatInterval",
+		},
+		config.Changes(before, after))
+	assert.Empty(t, config.Changes(before, before))
+}
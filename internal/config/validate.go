@@ -0,0 +1,227 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	errLogEncoding = "The log encoding param must be configured to " +
+		"('json' or 'console')"
+	errLogLevel = "The log level param must be configured to " +
+		"(-1: debug, 0: info, 1: Warn, 2: Error, 3: DPanic, 4: Panic, 5: Fatal)"
+	errAuthProvider = "The auth provider param must be configured to " +
+		"(dev, oauth2, oidc, local)"
+	errAuthIssuer = "The auth issuer param must be configured " +
+		"for the oidc provider"
+	errAuthLocalData = "The users file, the users table or the sql " +
+		"database must be configured in the data provider for the local " +
+		"auth provider"
+	errAuthLocalHash = "The password hash param must be configured to " +
+		"(argon2, bcrypt)"
+	errCloudProvider = "The cloud provider param must be configured to " +
+		"(none, aws)"
+	errDataProvider = "The data provider param must be configured to " +
+		"(none, file, cloud, sql)"
+	errDataCloud = "The cloud data provider requires the aws " +
+		"cloud provider"
+	errDataSQLFile = "The database file must be configured " +
+		"for the sql data provider"
+	errSecretProvider = "The secret provider param must be configured to " +
+		"(none, aws, file, env, vault)"
+	errSecretAWS = "The aws secret provider requires the aws " +
+		"cloud provider"
+	errSecretName   = "The secret name must be configured"
+	errSecretDir    = "The secrets directory must be configured"
+	errSecretEnv    = "The secrets environment prefix must be configured"
+	errVaultAddr    = "The vault address must be configured"
+	errVaultAuth    = "The vault token or the AppRole IDs must be configured"
+	errHeatbeat     = "The heartbeat must be configured"
+	errPort         = "The port must be between 1 and 65535"
+	errExternalPort = "The port must be between 0 and 65535. " +
+		"0 does not add the port to the URLs"
+	errTLSHost = "The host must be configured to use TLS"
+	errProxies = "The trusted proxies must be IPs or CIDR ranges " +
+		"separated by commas"
+	errSecretKey = "The secret key must be 32 bytes"
+	errTableName = "The table name must have 3 to 255 letters, " +
+		"digits, '_', '-' or '.'"
+	errConfigBase = "The configuration is not valid"
+)
+
+// secretKeySize is the key size of AES-256 required by pkg/crypto
+const secretKeySize = 32
+
+// tableName is the dynamodb table name format
+var tableName = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,255}$`)
+
+// FieldError is an invalid value of a config field
+type FieldError struct {
+	// Field is the json path of the field, for example web.secretKey
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every invalid field of the configuration
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+
+	b.WriteString(errConfigBase)
+
+	for _, f := range e {
+		b.WriteString("\n  ")
+		b.WriteString(f.Error())
+	}
+
+	return b.String()
+}
+
+// Validate checks the configuration. It returns a ValidationError
+// with every invalid field or nil if it is valid
+func (c *Config) Validate() error {
+	var errs ValidationError
+
+	check := func(ok bool, field string, message string) {
+		if !ok {
+			errs = append(errs, FieldError{Field: field, Message: message})
+		}
+	}
+
+	check(c.Encoding == "json" || c.Encoding == "console",
+		"log.encoding", errLogEncoding)
+	check(c.Level >= -1 && c.Level <= 5, "log.level", errLogLevel)
+
+	check(c.Internal.Port > 0 && c.Internal.Port <= 65535,
+		"server.internal.port", errPort)
+	check(c.External.Port >= 0 && c.External.Port <= 65535,
+		"server.external.port", errExternalPort)
+	check(!c.Internal.TLS || c.Internal.Host != "",
+		"server.internal.host", errTLSHost)
+	check(!c.External.TLS || c.External.Host != "",
+		"server.external.host", errTLSHost)
+
+	_, err := c.Server.Proxies()
+	check(err == nil, "server.trustedProxies", errProxies)
+
+	// The secret references are resolved after loading
+	check(isSecretRef(c.Web.SecretKey) ||
+		len(c.Web.SecretKey) == secretKeySize,
+		"web.secretKey", errSecretKey)
+
+	c.validateAuth(check)
+
+	check(c.Cloud.Provider == NoneCloudProvider ||
+		c.Cloud.Provider == CloudAWSProvider,
+		"cloud.provider", errCloudProvider)
+
+	c.validateSecret(check)
+
+	c.validateData(check)
+
+	check(c.HeartbeatInterval > 0, "api.heartbeatInterval", errHeatbeat)
+	check(c.HeartbeatTimeoutCount > 0,
+		"api.heartbeatTimeoutCount", errHeatbeat)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+func (c *Config) validateAuth(check func(bool, string, string)) {
+	switch c.Auth.Provider {
+	case AuthProviderDev, AuthProviderOauth2:
+	case AuthProviderOIDC:
+		check(c.Auth.Issuer != "", "web.auth.issuer", errAuthIssuer)
+	case AuthProviderLocal:
+		check(c.Auth.Local.Hash == "argon2" || c.Auth.Local.Hash == "bcrypt",
+			"web.auth.local.hash", errAuthLocalHash)
+		check((c.Data.Provider == FileDataProvider &&
+			c.Data.File.UsersFile != "") ||
+			(c.Data.Provider == CloudDataProvider &&
+				c.Data.AWS.UsersTableName != "") ||
+			c.Data.Provider == SQLDataProvider,
+			"data", errAuthLocalData)
+	default:
+		check(false, "web.auth.provider", errAuthProvider)
+	}
+}
+
+func (c *Config) validateSecret(check func(bool, string, string)) {
+	switch c.Secret.Provider {
+	case "", NoneSecretProvider:
+	case AWSSecretProvider:
+		check(c.Cloud.Provider == CloudAWSProvider,
+			"secret.provider", errSecretAWS)
+		check(c.Secret.Name != "", "secret.name", errSecretName)
+	case FileSecretProvider:
+		check(c.Secret.Dir != "", "secret.dir", errSecretDir)
+	case EnvSecretProvider:
+		check(c.Secret.EnvPrefix != "", "secret.envPrefix", errSecretEnv)
+	case VaultSecretProvider:
+		check(c.Secret.Name != "", "secret.name", errSecretName)
+		check(c.Secret.Vault.Address != "",
+			"secret.vault.address", errVaultAddr)
+		check(c.Secret.Vault.Token != "" ||
+			(c.Secret.Vault.RoleID != "" && c.Secret.Vault.SecretID != ""),
+			"secret.vault", errVaultAuth)
+	default:
+		check(false, "secret.provider", errSecretProvider)
+	}
+}
+
+func (c *Config) validateData(check func(bool, string, string)) {
+	switch c.Data.Provider {
+	case NoneDataProvider, FileDataProvider:
+	case CloudDataProvider:
+		check(c.Cloud.Provider == CloudAWSProvider,
+			"data.provider", errDataCloud)
+
+		tables := []struct{ field, name string }{
+			{"data.aws.configTableName", c.Data.AWS.ConfigTableName},
+			{"data.aws.samplesTableName", c.Data.AWS.SamplesTableName},
+			{"data.aws.usersTableName", c.Data.AWS.UsersTableName},
+			{"data.aws.apiKeysTableName", c.Data.AWS.APIKeysTableName},
+			{"data.aws.auditTableName", c.Data.AWS.AuditTableName},
+		}
+
+		for _, t := range tables {
+			check(t.name == "" || isSecretRef(t.name) ||
+				tableName.MatchString(t.name),
+				t.field, errTableName)
+		}
+	case SQLDataProvider:
+		check(c.Data.SQL.File != "", "data.sql.file", errDataSQLFile)
+	default:
+		check(false, "data.provider", errDataProvider)
+	}
+}
+
+// isSecretRef checks whether the value is resolved by the secret provider
+func isSecretRef(value string) bool {
+	return secretRef.MatchString(value)
+}
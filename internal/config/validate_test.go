@@ -0,0 +1,183 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/config"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		modify func(c *config.Config)
+		fields []string
+	}{
+		{
+			name:   "Default. It should be valid",
+			modify: func(c *config.Config) {},
+		},
+		{
+			name: "Secret reference. The key length should not be checked",
+			modify: func(c *config.Config) {
+				c.Web.SecretKey = "@@key"
+			},
+		},
+		{
+			name: "Several errors. It should return all of them",
+			modify: func(c *config.Config) {
+				c.Encoding = "xml"
+				c.Internal.Port = 0
+				c.External.Port = 70000
+				c.External.TLS = true
+				c.External.Host = ""
+				c.Web.SecretKey = "123"
+				c.HeartbeatInterval = 0
+			},
+			fields: []string{
+				"log.encoding",
+				"server.internal.port",
+				"server.external.port",
+				"server.external.host",
+				"web.secretKey",
+				"api.heartbeatInterval",
+			},
+		},
+		{
+			name: "Trusted proxies. It should check the IPs and the ranges",
+			modify: func(c *config.Config) {
+				c.Server.TrustedProxies = "10.0.0.1, 10.1.0.0/16, proxy"
+			},
+			fields: []string{"server.trustedProxies"},
+		},
+		{
+			name: "Data provider. It should not be reported as cloud provider",
+			modify: func(c *config.Config) {
+				c.Data.Provider = "no_exist"
+			},
+			fields: []string{"data.provider"},
+		},
+		{
+			name: "Cloud data. It should check the provider and the tables",
+			modify: func(c *config.Config) {
+				c.Data.Provider = config.CloudDataProvider
+				c.Data.AWS.ConfigTableName = "config"
+				c.Data.AWS.SamplesTableName = "s"
+				c.Data.AWS.AuditTableName = "audit log"
+			},
+			fields: []string{
+				"data.provider",
+				"data.aws.samplesTableName",
+				"data.aws.auditTableName",
+			},
+		},
+		{
+			name: "SQL data. It should check the database file",
+			modify: func(c *config.Config) {
+				c.Data.Provider = config.SQLDataProvider
+			},
+			fields: []string{"data.sql.file"},
+		},
+		{
+			name: "Local auth. It should check the hash and the store",
+			modify: func(c *config.Config) {
+				c.Auth.Provider = config.AuthProviderLocal
+				c.Auth.Local.Hash = "md5"
+			},
+			fields: []string{"web.auth.local.hash", "data"},
+		},
+		{
+			name: "Local auth in SQL. It should store the users in the database",
+			modify: func(c *config.Config) {
+				c.Auth.Provider = config.AuthProviderLocal
+				c.Data.Provider = config.SQLDataProvider
+				c.Data.SQL.File = "swpc.db"
+			},
+		},
+		{
+			name: "Secret provider. It should check the provider",
+			modify: func(c *config.Config) {
+				c.Secret.Provider = "no_exist"
+			},
+			fields: []string{"secret.provider"},
+		},
+		{
+			name: "AWS secret. It should check the cloud and the name",
+			modify: func(c *config.Config) {
+				c.Secret.Provider = config.AWSSecretProvider
+			},
+			fields: []string{"secret.provider", "secret.name"},
+		},
+		{
+			name: "File secret. It should be valid with the default dir",
+			modify: func(c *config.Config) {
+				c.Secret.Provider = config.FileSecretProvider
+			},
+		},
+		{
+			name: "Vault secret. It should check the address and the auth",
+			modify: func(c *config.Config) {
+				c.Secret.Provider = config.VaultSecretProvider
+				c.Secret.Name = "swpc"
+				c.Secret.Vault.RoleID = "role"
+			},
+			fields: []string{"secret.vault.address", "secret.vault"},
+		},
+		{
+			name: "Vault AppRole. It should be valid",
+			modify: func(c *config.Config) {
+				c.Secret.Provider = config.VaultSecretProvider
+				c.Secret.Name = "swpc"
+				c.Secret.Vault.Address = "http://127.0.0.1:8200"
+				c.Secret.Vault.RoleID = "role"
+				c.Secret.Vault.SecretID = "secret"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := config.Default()
+			tt.modify(&c)
+
+			err := c.Validate()
+			if len(tt.fields) == 0 {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var verr config.ValidationError
+			require.ErrorAs(t, err, &verr)
+
+			fields := make([]string, 0, len(verr))
+			for _, f := range verr {
+				fields = append(fields, f.Field)
+			}
+
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package internal
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/swpoolcontroller/internal/config"
+)
+
+// AWSCredentials gets the credentials of the AWS services
+// created with the configuration after applying the secrets
+func AWSCredentials(cnf config.Config) (aws.Credentials, error) {
+	log, _ := newLogger(cnf)
+
+	return applySecrets(log, &cnf).get().Credentials.
+		Retrieve(context.Background())
+}
@@ -19,16 +19,21 @@ package internal
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsc "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/labstack/echo/v4"
+	"github.com/swpoolcontroller/internal/account"
 	"github.com/swpoolcontroller/internal/ai"
+	"github.com/swpoolcontroller/internal/audit"
 	"github.com/swpoolcontroller/internal/config"
 	"github.com/swpoolcontroller/internal/hub"
 	iotc "github.com/swpoolcontroller/internal/iot"
+	"github.com/swpoolcontroller/internal/session"
+	"github.com/swpoolcontroller/internal/sqldb"
 	"github.com/swpoolcontroller/internal/web"
 	"github.com/swpoolcontroller/pkg/auth"
 	"github.com/swpoolcontroller/pkg/crypto"
@@ -41,13 +46,18 @@ import (
 const (
 	errReadConfig = "Reading the configuration of the micro controller " +
 		"from config file"
-	errCreateZap = "Error creating zap logger"
-	errAWSConfig = "Error creating secret maanger"
+	errCreateZap   = "Error creating zap logger"
+	errAWSConfig   = "Error creating secret maanger"
+	errOIDC        = "Discovering the OpenID Connect provider"
+	errApplySecret = "Applying the secrets of the configuration"
+	errOpenDB      = "Opening the sql database of the data provider"
 )
 
 const (
 	infConfigLoaded = "The configuration is loaded"
 	inflog          = "Log configuration"
+	warnJSONModels  = "The json models cannot be read. " +
+		"The predictions use the predict.sh script"
 )
 
 // APIHandler is the device API handler
@@ -58,45 +68,189 @@ type APIHandler struct {
 
 // WebHandler Web handler
 type WebHandler struct {
-	AppConfig  web.AppConfigurator
-	Auth       web.Auth
+	AppConfig web.AppConfigurator
+	Auth      web.Auth
+	Authz     *web.Authorizer
+	// APIKey is nil if there is no api keys store
+	APIKey     *web.APIKeyWeb
+	Audit      *web.Auditor
+	Sessions   *web.Sessions
 	Config     *web.ConfigWeb
 	Sample     *web.SampleWeb
 	Prediction *web.PredictionWeb
 	WS         *web.WS
 }
 
+// Stores are the data repositories of the data provider.
+// The commands use them without starting the server
+type Stores struct {
+	ConfigRead  iotc.ConfigRead
+	ConfigWrite iotc.ConfigWrite
+	// ConfigHistory reads the revisions saved by ConfigWrite
+	ConfigHistory iotc.ConfigHistory
+	Samples       ai.SampleRepo
+	// Users is nil if there is no users store
+	Users account.UserRepo
+	// APIKeys is nil if there is no api keys store
+	APIKeys account.APIKeyRepo
+	Audit   audit.Repo
+}
+
 // Factory is the objects factory of the app
 type Factory struct {
 	Config config.Config
 
 	Webs *echo.Echo
 	Log  *zap.Logger
+	// Level changes the log level while running
+	Level zap.AtomicLevel
 
-	JWT *auth.JWT
+	// JWT parses the session tokens of the auth provider
+	JWT web.TokenParser
 
 	Hubt *hub.Trace
 	Hub  *iot.Hub
 
 	WebHandler *WebHandler
 	APIHandler *APIHandler
+
+	Stores Stores
+
+	// loaded is the running configuration before applying the secrets.
+	// The reloaded configuration is compared with it
+	loaded config.Config
+
+	// db is the database of the sql data provider
+	db *sqlDB
 }
 
 // NewFactory creates the horizontal services of the app
-func NewFactory() *Factory {
-	cnf := config.LoadConfig()
-	awscnf := newAWSConfig(cnf)
+// with the loaded configuration
+func NewFactory(cnf config.Config) *Factory {
+	loaded := cnf
 
-	log := newLogger(cnf)
+	log, level := newLogger(cnf)
 
 	if !cnf.Hide {
 		log.Info(infConfigLoaded, zap.String("Config", cnf.String()))
 	}
 
-	s := secretProvider(cnf, awscnf)
-	config.ApplySecret(s, &cnf)
+	awscnf := applySecrets(log, &cnf)
+
+	db := newSQLDB(cnf, log)
+
+	hubt, hub, stores := newStores(cnf, awscnf, db, log)
+
+	auths := newAuthServices(log, cnf, awscnf, db)
+
+	return &Factory{
+		Config:     cnf,
+		Webs:       newEcho(log, cnf),
+		Log:        log,
+		Level:      level,
+		JWT:        auths.parser(),
+		Hubt:       hubt,
+		Hub:        hub,
+		WebHandler: newWeb(log, cnf, hub, auths, stores),
+		APIHandler: &APIHandler{
+			Auth: iotc.NewAuth(log, cnf.API),
+			WS:   iotc.NewWS(log, hub),
+		},
+		Stores: stores,
+		loaded: loaded,
+		db:     db,
+	}
+}
+
+// newEcho creates the web server. The client IP is the address
+// of the connection or, behind the trusted proxies, the first IP
+// of the X-Forwarded-For header that is not a trusted proxy
+func newEcho(log *zap.Logger, cnf config.Config) *echo.Echo {
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+
+	proxies, err := cnf.Server.Proxies()
+	if err != nil {
+		log.Panic(err.Error())
+	}
+
+	if len(proxies) == 0 {
+		return e
+	}
+
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+
+	for _, p := range proxies {
+		opts = append(opts, echo.TrustIPRange(p))
+	}
+
+	e.IPExtractor = echo.ExtractIPFromXFFHeader(opts...)
+
+	return e
+}
+
+// Close closes the database of the sql data provider if it is open
+func (f *Factory) Close() error {
+	return f.db.close()
+}
+
+// Tools are the services of the administration commands. Unlike the
+// factory, they do not create the auth provider, so they work offline
+type Tools struct {
+	Log *zap.Logger
+
+	// Hub is not running. It is run to notify the saved configurations
+	Hubt *hub.Trace
+	Hub  *iot.Hub
+
+	// DeviceAuth signs the tokens of the device
+	DeviceAuth *iotc.Auth
+
+	Stores Stores
+
+	// db is the database of the sql data provider
+	db *sqlDB
+}
+
+// NewTools creates the services of the administration commands
+// with the loaded configuration
+func NewTools(cnf config.Config) *Tools {
+	log, _ := newLogger(cnf)
 
-	mconfigRead := microConfigRead(cnf, awscnf, log)
+	awscnf := applySecrets(log, &cnf)
+
+	db := newSQLDB(cnf, log)
+
+	hubt, hub, stores := newStores(cnf, awscnf, db, log)
+
+	return &Tools{
+		Log:        log,
+		Hubt:       hubt,
+		Hub:        hub,
+		DeviceAuth: iotc.NewAuth(log, cnf.API),
+		Stores:     stores,
+		db:         db,
+	}
+}
+
+// Close closes the database of the sql data provider if it is open
+func (t *Tools) Close() error {
+	return t.db.close()
+}
+
+// newStores creates the stores of the data provider and the hub,
+// which is notified of the configurations saved by them
+func newStores(
+	cnf config.Config,
+	cnfaws *awsConfig,
+	db *sqlDB,
+	log *zap.Logger) (*hub.Trace, *iot.Hub, Stores) {
+	//
+	mconfigRead := microConfigRead(cnf, cnfaws, db, log)
 
 	microc, err := mconfigRead.Read()
 	if err != nil {
@@ -110,30 +264,214 @@ func NewFactory() *Factory {
 
 	hubt, hub := newHub(log, cnf, microc, loc)
 
-	jwt := &auth.JWT{
-		JWKFetch: auth.NewJWKFetch(cnf.Auth.JWKURL),
+	mconfigWrite := microConfigWrite(cnf, cnfaws, db, log, hub)
+
+	stores := Stores{
+		ConfigRead:    mconfigRead,
+		ConfigWrite:   mconfigWrite,
+		ConfigHistory: mconfigWrite,
+		Samples:       buildSampleRepo(cnf, cnfaws, db, log),
+		APIKeys:       buildAPIKeyRepo(cnf, cnfaws, db, log),
+		Audit:         buildAuditRepo(cnf, cnfaws, db, log),
 	}
 
-	mconfigWrite := microConfigWrite(cnf, awscnf, log, hub)
+	if hasUserRepo(cnf) {
+		stores.Users = buildUserRepo(cnf, cnfaws, db, log)
+	}
 
-	return &Factory{
-		Config:     cnf,
-		Webs:       echo.New(),
-		Log:        log,
-		JWT:        jwt,
-		Hubt:       hubt,
-		Hub:        hub,
-		WebHandler: newWeb(log, cnf, awscnf, hub, jwt, mconfigRead, mconfigWrite),
-		APIHandler: &APIHandler{
-			Auth: iotc.NewAuth(log, cnf.API),
-			WS:   iotc.NewWS(log, hub),
+	return hubt, hub, stores
+}
+
+// NewAccounts creates the local accounts service of the configuration.
+// It is used by the administration commands, which close the database
+// of the sql data provider with the returned function
+func NewAccounts(cnf config.Config) (*account.Accounts, func() error) {
+	log, _ := newLogger(cnf)
+
+	awscnf := applySecrets(log, &cnf)
+
+	db := newSQLDB(cnf, log)
+
+	return account.NewAccounts(
+		buildUserRepo(cnf, awscnf, db, log),
+		cnf.Auth.Local), db.close
+}
+
+// authServices are the services of the configured auth provider
+type authServices struct {
+	jwt      *auth.JWT
+	oidc     *auth.OIDC
+	accounts *account.Accounts
+	signer   *auth.HMACJWT
+	// tokens rejects the session tokens revoked by a logout
+	tokens *web.DenylistParser
+	// states issues the single-use states of the oauth2 logins
+	states *auth.StateIssuer
+}
+
+func newAuthServices(
+	log *zap.Logger,
+	cnf config.Config,
+	cnfaws *awsConfig,
+	db *sqlDB) authServices {
+	//
+	auths := authServices{
+		jwt: &auth.JWT{
+			JWKFetch: auth.NewJWKFetch(cnf.Auth.JWKURL),
 		},
 	}
+
+	switch cnf.Auth.Provider { //nolint:exhaustive
+	case config.AuthProviderOIDC:
+		oidc, err := auth.NewOIDC(
+			cnf.Auth.Issuer,
+			cnf.Auth.ClientID,
+			cnf.Auth.ClientSecret,
+			cnf.Auth.Scopes)
+		if err != nil {
+			log.Panic(errOIDC, zap.Error(err))
+		}
+
+		auths.oidc = oidc
+		auths.jwt = oidc.JWT
+	case config.AuthProviderLocal:
+		auths.accounts = account.NewAccounts(
+			buildUserRepo(cnf, cnfaws, db, log),
+			cnf.Auth.Local)
+		auths.signer = &auth.HMACJWT{
+			Key:    []byte(cnf.Web.SecretKey),
+			Issuer: cnf.Server.ExternalURL(""),
+		}
+	}
+
+	var parser web.TokenParser = auths.jwt
+	if auths.signer != nil {
+		parser = auths.signer
+	}
+
+	auths.tokens = &web.DenylistParser{
+		Parser:   parser,
+		Denylist: auth.NewDenylist(),
+	}
+
+	auths.states = &auth.StateIssuer{
+		Key:    []byte(cnf.Web.SecretKey),
+		TTL:    time.Duration(cnf.Web.StateExpiration) * time.Minute,
+		Nonces: auth.NewNonceStore(),
+	}
+
+	return auths
+}
+
+// parser returns the parser of the session tokens
+func (a authServices) parser() web.TokenParser {
+	return a.tokens
+}
+
+// hasUserRepo checks whether the users are stored by the data provider
+func hasUserRepo(cnf config.Config) bool {
+	switch cnf.Data.Provider { //nolint:exhaustive
+	case config.CloudDataProvider:
+		return cnf.Data.AWS.UsersTableName != ""
+	case config.FileDataProvider:
+		return cnf.Data.File.UsersFile != ""
+	case config.SQLDataProvider:
+		return true
+	}
+
+	return false
+}
+
+func buildUserRepo(
+	cnf config.Config,
+	cnfaws *awsConfig,
+	db *sqlDB,
+	log *zap.Logger) account.UserRepo {
+	//
+	if cnf.Data.Provider == config.CloudDataProvider &&
+		cnf.Data.AWS.UsersTableName != "" {
+		return account.NewUserAWSDynamoRepo(
+			cnfaws.get(),
+			log,
+			cnf.Data.AWS.UsersTableName)
+	}
+
+	if cnf.Data.Provider == config.SQLDataProvider {
+		return &account.UserSQLRepo{Log: log, DB: db.get()}
+	}
+
+	return &account.UserFileRepo{
+		Log:      log,
+		FileName: cnf.Data.File.UsersFile,
+	}
+}
+
+// buildAuditRepo writes the audit log in the log
+// if there is no audit store
+func buildAuditRepo(
+	cnf config.Config,
+	cnfaws *awsConfig,
+	db *sqlDB,
+	log *zap.Logger) audit.Repo {
+	//
+	switch cnf.Data.Provider { //nolint:exhaustive
+	case config.CloudDataProvider:
+		if cnf.Data.AWS.AuditTableName != "" {
+			return audit.NewAWSDynamoRepo(
+				cnfaws.get(),
+				log,
+				cnf.Data.AWS.AuditTableName)
+		}
+	case config.FileDataProvider:
+		if cnf.Data.File.AuditFile != "" {
+			return &audit.FileRepo{
+				Log:      log,
+				FileName: cnf.Data.File.AuditFile,
+			}
+		}
+	case config.SQLDataProvider:
+		return &audit.SQLRepo{
+			Log: log,
+			DB:  db.get(),
+		}
+	}
+
+	return &audit.LogRepo{Log: log}
+}
+
+// buildAPIKeyRepo returns nil if the api keys are not configured
+func buildAPIKeyRepo(
+	cnf config.Config,
+	cnfaws *awsConfig,
+	db *sqlDB,
+	log *zap.Logger) account.APIKeyRepo {
+	//
+	switch cnf.Data.Provider { //nolint:exhaustive
+	case config.CloudDataProvider:
+		if cnf.Data.AWS.APIKeysTableName != "" {
+			return account.NewAPIKeyAWSDynamoRepo(
+				cnfaws.get(),
+				log,
+				cnf.Data.AWS.APIKeysTableName)
+		}
+	case config.FileDataProvider:
+		if cnf.Data.File.APIKeysFile != "" {
+			return &account.APIKeyFileRepo{
+				Log:      log,
+				FileName: cnf.Data.File.APIKeysFile,
+			}
+		}
+	case config.SQLDataProvider:
+		return &account.APIKeySQLRepo{Log: log, DB: db.get()}
+	}
+
+	return nil
 }
 
 func microConfigRead(
 	cnf config.Config,
 	cnfaws *awsConfig,
+	db *sqlDB,
 	log *zap.Logger) iotc.ConfigRead {
 	//
 	if cnf.Data.Provider == config.CloudDataProvider &&
@@ -153,16 +491,30 @@ func microConfigRead(
 		}
 	}
 
+	if cnf.Data.Provider == config.SQLDataProvider {
+		return &iotc.SQLConfigRead{
+			Log: log,
+			DB:  db.get(),
+		}
+	}
+
 	return &iotc.DefaultConfigRead{
 		Log: log,
 	}
 }
 
+// configStore writes the configuration and keeps its revisions
+type configStore interface {
+	iotc.ConfigWrite
+	iotc.ConfigHistory
+}
+
 func microConfigWrite(
 	cnf config.Config,
 	cnfaws *awsConfig,
+	db *sqlDB,
 	log *zap.Logger,
-	hub *iot.Hub) iotc.ConfigWrite {
+	hub *iot.Hub) configStore {
 	//
 	if cnf.Data.Provider == config.CloudDataProvider &&
 		cnf.Cloud.Provider != config.NoneCloudProvider &&
@@ -184,14 +536,54 @@ func microConfigWrite(
 		}
 	}
 
+	if cnf.Data.Provider == config.SQLDataProvider {
+		return &iotc.SQLConfigWrite{
+			Log:    log,
+			Hub:    hub,
+			Config: cnf,
+			DB:     db.get(),
+		}
+	}
+
 	return &iotc.DefaultConfigSave{
 		Log: log,
 	}
 }
 
+// applySecrets resolves the secret references of the configuration and
+// returns the AWS config of the services with the resolved credentials.
+// Only the secrets provider uses the credentials before resolving them
+func applySecrets(log *zap.Logger, cnf *config.Config) *awsConfig {
+	s := secretProvider(*cnf, newAWSConfig(*cnf))
+	if err := config.ApplySecret(s, cnf); err != nil {
+		log.Panic(errApplySecret, zap.Error(err))
+	}
+
+	return newAWSConfig(*cnf)
+}
+
 func secretProvider(cnf config.Config, cnfaws *awsConfig) config.Secret {
-	if cnf.Cloud.Provider == config.CloudAWSProvider && len(cnf.Secret.Name) > 0 {
+	switch cnf.Secret.Provider {
+	case config.AWSSecretProvider:
 		return crypto.NewAWSSecret(cnfaws.get())
+	case config.FileSecretProvider:
+		return &crypto.FileSecret{Dir: cnf.Secret.Dir}
+	case config.EnvSecretProvider:
+		return &crypto.EnvSecret{Prefix: cnf.Secret.EnvPrefix}
+	case config.VaultSecretProvider:
+		return &crypto.VaultSecret{
+			Address:   cnf.Secret.Vault.Address,
+			Mount:     cnf.Secret.Vault.Mount,
+			Namespace: cnf.Secret.Vault.Namespace,
+			Token:     cnf.Secret.Vault.Token,
+			RoleID:    cnf.Secret.Vault.RoleID,
+			SecretID:  cnf.Secret.Vault.SecretID,
+		}
+	case "":
+		if cnf.Cloud.Provider == config.CloudAWSProvider &&
+			len(cnf.Secret.Name) > 0 {
+			return crypto.NewAWSSecret(cnfaws.get())
+		}
 	}
 
 	return &config.DummySecret{}
@@ -201,34 +593,72 @@ func secretProvider(cnf config.Config, cnfaws *awsConfig) config.Secret {
 func newWeb(
 	log *zap.Logger,
 	cnf config.Config,
-	cnfaws *awsConfig,
 	hub *iot.Hub,
-	jwt *auth.JWT,
-	mconfigRead iotc.ConfigRead,
-	mconfigWrite iotc.ConfigWrite) *WebHandler {
+	auths authServices,
+	stores Stores) *WebHandler {
 	//
 	var oauth2 web.Auth
 
 	var appConfig web.AppConfigurator
 
-	repoSample := buildSampleRepo(cnf, cnfaws, log)
+	authz := &web.Authorizer{
+		Log:    log,
+		Config: cnf,
+		Parser: auths.parser(),
+	}
+
+	switch cnf.Auth.Provider {
+	case config.AuthProviderOIDC:
+		oauth2 = &web.AuthFlowOIDC{
+			Log:     log,
+			Service: auths.oidc,
+			States:  auths.states,
+			Hub:     hub,
+			Config:  cnf,
+			Tokens:  auths.tokens,
+		}
 
-	if cnf.Auth.Provider == config.AuthProviderOauth2 {
+		appConfig = &web.AppConfigOIDC{
+			Log:     log,
+			Config:  cnf,
+			Authz:   authz,
+			Service: auths.oidc,
+		}
+	case config.AuthProviderOauth2:
 		oauth2 = &web.AuthFlow{
 			Log: log,
 			Service: &auth.OAuth2{
 				ClientID: cnf.Auth.ClientID,
-				JWT:      jwt,
+				JWT:      auths.jwt,
 			},
+			States: auths.states,
 			Hub:    hub,
 			Config: cnf,
+			Tokens: auths.tokens,
 		}
 
 		appConfig = &web.AppConfig{
 			Log:    log,
 			Config: cnf,
+			Authz:  authz,
+			States: auths.states,
 		}
-	} else {
+	case config.AuthProviderLocal:
+		oauth2 = &web.AuthFlowLocal{
+			Log:      log,
+			Accounts: auths.accounts,
+			Signer:   auths.signer,
+			Hub:      hub,
+			Config:   cnf,
+			Tokens:   auths.tokens,
+		}
+
+		appConfig = &web.AppConfigLocal{
+			Log:    log,
+			Config: cnf,
+			Authz:  authz,
+		}
+	default:
 		log.Warn("Authentication has been configured in development mode. " +
 			"Never use this configuration in production.")
 
@@ -241,26 +671,54 @@ func newWeb(
 		appConfig = &web.AppConfigDev{
 			Log:    log,
 			Config: cnf,
+			Authz:  authz,
+		}
+	}
+
+	var apiKey *web.APIKeyWeb
+
+	if stores.APIKeys != nil {
+		apiKey = &web.APIKeyWeb{
+			Log:  log,
+			Keys: &account.APIKeys{Repo: stores.APIKeys},
 		}
 	}
 
 	return &WebHandler{
 		AppConfig: appConfig,
 		Auth:      oauth2,
+		Authz:     authz,
+		APIKey:    apiKey,
+		Audit: &web.Auditor{
+			Log:   log,
+			Repo:  stores.Audit,
+			Authz: authz,
+		},
+		Sessions: &web.Sessions{
+			Log: log,
+			// The terminated sessions are rejected while they could
+			// be renewed, the cookies last 5 minutes longer
+			Registry: session.NewRegistry(
+				time.Duration(cnf.Web.RefreshExpiration+5) * time.Minute),
+			Hub:    hub,
+			Config: cnf,
+			Tokens: auths.tokens,
+		},
 		Config: &web.ConfigWeb{
-			Log:    log,
-			MicroR: mconfigRead,
-			MicroW: mconfigWrite,
+			Log:     log,
+			MicroR:  stores.ConfigRead,
+			MicroW:  stores.ConfigWrite,
+			History: stores.ConfigHistory,
+			Status:  hub,
 		},
 		Sample: &web.SampleWeb{
-			Log:  log,
-			Repo: repoSample,
+			Log:     log,
+			Repo:    stores.Samples,
+			Metrics: hub,
 		},
 		Prediction: &web.PredictionWeb{
-			Preder: &ai.Prediction{
-				Log: log,
-			},
-			Log: log,
+			Preder: buildPredicter(log),
+			Log:    log,
 		},
 		WS: web.NewWS(log, cnf.Web, hub),
 	}
@@ -269,6 +727,7 @@ func newWeb(
 func buildSampleRepo(
 	cnf config.Config,
 	cnfaws *awsConfig,
+	db *sqlDB,
 	log *zap.Logger) ai.SampleRepo {
 	//
 	switch cnf.Data.Provider {
@@ -286,6 +745,11 @@ func buildSampleRepo(
 				FileName: cnf.Data.File.SampleFile,
 			}
 		}
+	case config.SQLDataProvider:
+		return &ai.SampleSQLRepo{
+			Log: log,
+			DB:  db.get(),
+		}
 	case config.NoneDataProvider:
 		return &web.SampleDummyRepo{Log: log}
 	}
@@ -293,23 +757,28 @@ func buildSampleRepo(
 	return &web.SampleDummyRepo{Log: log}
 }
 
+// buildPredicter predicts with the json models exported by ai/fit.py.
+// If they cannot be read it falls back to the ai/predict.sh script
+func buildPredicter(log *zap.Logger) ai.Predicter {
+	p, err := ai.NewModelPrediction(
+		log,
+		ai.WQModelJSONFile,
+		ai.CLModelJSONFile)
+	if err != nil {
+		log.Warn(warnJSONModels, zap.Error(err))
+
+		return &ai.Prediction{Log: log}
+	}
+
+	return p
+}
+
 func newHub(
 	log *zap.Logger,
 	config config.Config,
 	microc iotc.Config,
 	loc *time.Location) (*hub.Trace, *iot.Hub) {
 	//
-	hubTraceLevel := iot.NoneLevel
-
-	switch log.Level() { //nolint:exhaustive
-	case zap.DebugLevel:
-		hubTraceLevel = iot.DebugLevel
-	case zap.InfoLevel:
-		hubTraceLevel = iot.InfoLevel
-	case zap.WarnLevel:
-		hubTraceLevel = iot.WarnLevel
-	}
-
 	hubt := hub.NewTrace(log)
 	hub := iot.NewHub(
 		iot.Config{
@@ -331,22 +800,51 @@ func newHub(
 			CommLatency:      time.Duration(config.CommLatencyTime) * time.Second,
 			TaskTime:         time.Duration(config.TaskTime) * time.Second,
 			NotificationTime: time.Duration(config.NotificationTime) * time.Second,
-			HeartbeatConfig: iot.HeartbeatConfig{
-				HeartbeatInterval: time.Duration(config.HeartbeatInterval) *
-					time.Second,
-				HeartbeatPingTime: time.Duration(config.HeartbeatPingTime) *
-					time.Second,
-				HeartbeatTimeoutCount: config.HeartbeatTimeoutCount,
-			},
+			HeartbeatConfig:  heartbeatConfig(config),
 		},
-		hubTraceLevel,
+		hubTraceLevel(log.Level()),
 		hubt.Trace,
 		hubt.Error)
 
 	return hubt, hub
 }
 
-func newLogger(ctx config.Config) *zap.Logger {
+// hubSettings are the settings of the hub that can be reloaded
+func hubSettings(cnf config.Config) iot.Settings {
+	return iot.Settings{
+		HeartbeatConfig:  heartbeatConfig(cnf),
+		CommLatency:      time.Duration(cnf.CommLatencyTime) * time.Second,
+		TaskTime:         time.Duration(cnf.TaskTime) * time.Second,
+		NotificationTime: time.Duration(cnf.NotificationTime) * time.Second,
+		TraceLevel:       hubTraceLevel(zapcore.Level(cnf.Level)),
+	}
+}
+
+func heartbeatConfig(cnf config.Config) iot.HeartbeatConfig {
+	return iot.HeartbeatConfig{
+		HeartbeatInterval:     time.Duration(cnf.HeartbeatInterval) * time.Second,
+		HeartbeatPingTime:     time.Duration(cnf.HeartbeatPingTime) * time.Second,
+		HeartbeatTimeoutCount: cnf.HeartbeatTimeoutCount,
+	}
+}
+
+// hubTraceLevel only sends the traces of the hub that are logged
+func hubTraceLevel(level zapcore.Level) iot.TraceLevel {
+	switch level { //nolint:exhaustive
+	case zap.DebugLevel:
+		return iot.DebugLevel
+	case zap.InfoLevel:
+		return iot.InfoLevel
+	case zap.WarnLevel:
+		return iot.WarnLevel
+	default:
+		return iot.NoneLevel
+	}
+}
+
+// newLogger builds the logger. The atomic level changes
+// the level of the logger while running
+func newLogger(ctx config.Config) (*zap.Logger, zap.AtomicLevel) {
 	var log zap.Config
 
 	if ctx.Development {
@@ -377,7 +875,7 @@ func newLogger(ctx config.Config) *zap.Logger {
 		zap.String("Encoding", log.Encoding),
 	)
 
-	return l
+	return l, log.Level
 }
 
 type awsConfig struct {
@@ -426,3 +924,41 @@ func (ac *awsConfig) get() aws.Config {
 
 	return ac.c
 }
+
+// sqlDB opens the database of the sql data provider on first use,
+// so it is shared by the stores
+type sqlDB struct {
+	file string
+	log  *zap.Logger
+	db   *sql.DB
+}
+
+func newSQLDB(cnf config.Config, log *zap.Logger) *sqlDB {
+	return &sqlDB{
+		file: cnf.Data.SQL.File,
+		log:  log,
+	}
+}
+
+func (s *sqlDB) get() *sql.DB {
+	if s.db != nil {
+		return s.db
+	}
+
+	db, err := sqldb.Open(s.file)
+	if err != nil {
+		s.log.Panic(errOpenDB, zap.String("file", s.file), zap.Error(err))
+	}
+
+	s.db = db
+
+	return s.db
+}
+
+func (s *sqlDB) close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+
+	return s.db.Close()
+}
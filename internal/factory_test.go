@@ -18,16 +18,24 @@
 package internal_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/swpoolcontroller/internal"
+	"github.com/swpoolcontroller/internal/ai"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/internal/iot"
 )
 
 func TestNewFactory(t *testing.T) {
 	t.Parallel()
 
-	f := internal.NewFactory()
+	f := internal.NewFactory(config.Default())
 
 	assert.NotNil(t, f.APIHandler, "APIHandler")
 	assert.NotNil(t, f.APIHandler.Auth, "APIHandler.Auth")
@@ -43,4 +51,142 @@ func TestNewFactory(t *testing.T) {
 	assert.NotNil(t, f.WebHandler.Config, "WebHandler.Config")
 	assert.NotNil(t, f.WebHandler.WS, "WebHandler.WS")
 	assert.NotNil(t, f.Webs, "Webs")
+	assert.NotNil(t, f.Stores.ConfigRead, "Stores.ConfigRead")
+	assert.NotNil(t, f.Stores.ConfigWrite, "Stores.ConfigWrite")
+	assert.NotNil(t, f.Stores.Samples, "Stores.Samples")
+	assert.NotNil(t, f.Stores.Audit, "Stores.Audit")
+}
+
+func TestNewFactory_SQL(t *testing.T) {
+	t.Parallel()
+
+	cnf := config.Default()
+	cnf.Data.Provider = config.SQLDataProvider
+	cnf.Data.SQL.File = filepath.Join(t.TempDir(), "swpc.db")
+
+	f := internal.NewFactory(cnf)
+
+	defer f.Close()
+
+	micro, err := f.Stores.ConfigRead.Read()
+
+	require.NoError(t, err)
+	assert.Equal(t, iot.DefaultConfig(), micro, "Default without revisions")
+
+	cur, err := f.Stores.ConfigHistory.Current()
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, cur)
+
+	sample := ai.SampleData{Temp: 24, PH: 7.2, ORP: 650, Quality: ai.QualityGood}
+
+	require.NoError(t, f.Stores.Samples.Save(sample))
+
+	samples, err := f.Stores.Samples.All()
+
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, sample.Quality, samples[0].Quality)
+
+	require.NotNil(t, f.Stores.Users, "Users")
+	require.NotNil(t, f.Stores.APIKeys, "Api keys")
+
+	keys, err := f.Stores.APIKeys.List()
+
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestNewTools(t *testing.T) {
+	t.Parallel()
+
+	cnf := config.Default()
+	cnf.Auth.Provider = config.AuthProviderOIDC
+	cnf.Auth.Issuer = "http://127.0.0.1:1/unreachable"
+	cnf.Data.Provider = config.SQLDataProvider
+	cnf.Data.SQL.File = filepath.Join(t.TempDir(), "swpc.db")
+
+	tools := internal.NewTools(cnf)
+
+	assert.NotNil(t, tools.Hub, "Hub")
+	assert.NotNil(t, tools.Hubt, "Hubt")
+	assert.NotNil(t, tools.DeviceAuth, "DeviceAuth")
+	assert.NotNil(t, tools.Stores.ConfigWrite, "Stores.ConfigWrite")
+	assert.NotNil(t, tools.Stores.Users, "Stores.Users")
+
+	samples, err := tools.Stores.Samples.All()
+
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+	require.NoError(t, tools.Close())
+}
+
+func TestNewFactory_ClientIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		proxies string
+		remote  string
+		ip      string
+	}{
+		{
+			name:   "Without proxies. The forwarded IP should be ignored",
+			remote: "10.0.0.1:4000",
+			ip:     "10.0.0.1",
+		},
+		{
+			name:    "Trusted proxy. It should use the forwarded IP",
+			proxies: "10.0.0.1",
+			remote:  "10.0.0.1:4000",
+			ip:      "203.0.113.9",
+		},
+		{
+			name:    "Untrusted proxy. The forwarded IP should be ignored",
+			proxies: "10.0.0.1",
+			remote:  "10.0.0.2:4000",
+			ip:      "10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cnf := config.Default()
+			cnf.Server.TrustedProxies = tt.proxies
+
+			f := internal.NewFactory(cnf)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			req.Header.Set("X-Real-Ip", "198.51.100.7")
+
+			ctx := f.Webs.NewContext(req, httptest.NewRecorder())
+
+			assert.Equal(t, tt.ip, ctx.RealIP())
+		})
+	}
+}
+
+func TestNewFactory_AWSSecret(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "aws_secret"), []byte("resolved"), 0o600))
+
+	cnf := config.Default()
+	cnf.Secret.Provider = config.FileSecretProvider
+	cnf.Secret.Dir = dir
+	cnf.Cloud.AWS.Region = "eu-west-1"
+	cnf.Cloud.AWS.AKID = "akid"
+	cnf.Cloud.AWS.SecretKey = "@@aws_secret"
+
+	creds, err := internal.AWSCredentials(cnf)
+
+	require.NoError(t, err)
+	assert.Equal(t, "akid", creds.AccessKeyID)
+	assert.Equal(t, "resolved", creds.SecretAccessKey, "Secret applied")
 }
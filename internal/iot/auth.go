@@ -23,12 +23,16 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
 	"github.com/swpoolcontroller/internal/config"
 	"go.uber.org/zap"
 )
 
 const ClientIDName = "client_id"
 
+// TokenExpiration is the expiration of the device tokens
+const TokenExpiration = 5 * time.Minute
+
 const (
 	errBadID = "OAuth.Token.The secretID is bad"
 	errSign  = "OAuth.Token.Error signing token"
@@ -61,21 +65,26 @@ func (o *Auth) Token(ctx echo.Context) error {
 		return ctx.NoContent(http.StatusUnauthorized)
 	}
 
+	token, err := o.Sign(TokenExpiration)
+	if err != nil {
+		o.log.With(zap.Error(err)).Error(errSign, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	return ctx.String(http.StatusOK, token)
+}
+
+// Sign creates a device token that expires after the expiration
+func (o *Auth) Sign(expiration time.Duration) (string, error) {
 	claims := jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(
-			time.Now().Add(time.Duration(5) * time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
 	}
 
 	// Create token with claims
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	// Generate encoded token and send it as response.
 	token, err := t.SignedString([]byte(o.ac.TokenSecretKey))
-	if err != nil {
-		o.log.With(zap.Error(err)).Error(errSign, zap.Error(err))
 
-		return ctx.NoContent(http.StatusInternalServerError)
-	}
-
-	return ctx.String(http.StatusOK, token)
+	return token, errors.Wrap(err, errSign)
 }
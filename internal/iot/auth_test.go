@@ -21,9 +21,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/swpoolcontroller/internal/config"
 	"github.com/swpoolcontroller/internal/iot"
 	"go.uber.org/zap"
@@ -81,3 +84,23 @@ func TestAuth_Token(t *testing.T) {
 		})
 	}
 }
+
+func TestAuth_Sign(t *testing.T) {
+	t.Parallel()
+
+	o := iot.NewAuth(zap.NewExample(), config.API{TokenSecretKey: "key"})
+
+	token, err := o.Sign(time.Hour)
+	require.NoError(t, err)
+
+	var claims jwt.RegisteredClaims
+
+	_, err = jwt.ParseWithClaims(
+		token,
+		&claims,
+		func(_ *jwt.Token) (interface{}, error) { return []byte("key"), nil })
+	require.NoError(t, err)
+
+	assert.WithinDuration(t,
+		time.Now().Add(time.Hour), claims.ExpiresAt.Time, time.Minute)
+}
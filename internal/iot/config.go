@@ -21,6 +21,9 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -52,12 +55,27 @@ const (
 	infFile              = "file"
 )
 
+// AWS dynamodb fields. The current configuration is the item "1"
+// and the revisions are the items "rev#<rev>"
 const (
-	dynamoDBTableKeyValue   = "1"
-	dynamoDBTableKeyName    = "id"
-	dynamoDBTableConfigName = "config"
+	dynamoDBTableKeyValue = "1"
+	dynamoDBTableKeyName  = "id"
+	dynamoDBRevName       = "rev"
+	dynamoDBRevPrefix     = "rev#"
 )
 
+// dynamoRevision is the item of the current configuration
+// and of the revisions. The config is json
+type dynamoRevision struct {
+	ID     string    `dynamodbav:"id"`
+	Rev    int       `dynamodbav:"rev"`
+	Author string    `dynamodbav:"author"`
+	Saved  time.Time `dynamodbav:"saved"`
+	Config string    `dynamodbav:"config"`
+}
+
+// Config is the configuration of the micro controller.
+// The rules of its fields are declared in ConfigRules
 type Config struct {
 	// IniSendTime is the range for initiating metric sends
 	IniSendTime string `json:"iniSendTime"`
@@ -123,10 +141,13 @@ type ConfigRead interface {
 	Read() (Config, error)
 }
 
-// FileConfigWrite writes the micro controller configuration
+// ConfigWrite writes the micro controller configuration
 type ConfigWrite interface {
-	// Save saves the configuration
-	Save(data Config) error
+	// Save saves the configuration as the next revision of the author
+	Save(data Config, author string) (Revision, error)
+	// SaveIfMatch saves the configuration only if rev is the latest
+	// revision, otherwise ErrRevisionConflict. AnyRevision always saves
+	SaveIfMatch(data Config, author string, rev int) (Revision, error)
 }
 
 // DefaultConfigRead reads the default micro controller configuration
@@ -148,10 +169,37 @@ type DefaultConfigSave struct {
 }
 
 // Save not implement any action
-func (f *DefaultConfigSave) Save(data Config) error {
+func (f *DefaultConfigSave) Save(
+	data Config,
+	author string) (Revision, error) {
+	//
 	f.Log.Info(infdefaultSaveConfig, zap.String(infConfig, data.String()))
 
-	return nil
+	return newRevision(0, author, data), nil
+}
+
+// SaveIfMatch not implement any action
+func (f *DefaultConfigSave) SaveIfMatch(
+	data Config,
+	author string,
+	_ int) (Revision, error) {
+	//
+	return f.Save(data, author)
+}
+
+// Current returns no revision
+func (f *DefaultConfigSave) Current() (int, error) {
+	return 0, nil
+}
+
+// History returns no revisions
+func (f *DefaultConfigSave) History() ([]Revision, error) {
+	return []Revision{}, nil
+}
+
+// Revision returns ErrRevisionNotFound
+func (f *DefaultConfigSave) Revision(_ int) (Revision, error) {
+	return Revision{}, ErrRevisionNotFound
 }
 
 // FileConfigRead reads the micro controller configuration from file
@@ -192,32 +240,105 @@ func (c *FileConfigRead) Read() (Config, error) {
 	return mc, nil
 }
 
-// FileConfigWrite writes the micro controller configuration to file
+// FileConfigWrite writes the micro controller configuration to file.
+// The revisions are appended to the history file, the data file
+// with the .history suffix. Operations are protected against concurrency
 type FileConfigWrite struct {
 	Log      *zap.Logger
 	Hub      Hub
 	Config   config.Config
 	DataFile string
+
+	lock sync.Mutex
+}
+
+// Save saves the configuration to disk and appends the revision
+func (c *FileConfigWrite) Save(data Config, author string) (Revision, error) {
+	return c.SaveIfMatch(data, author, AnyRevision)
 }
 
-// Save saves the configuration to disk
-func (c FileConfigWrite) Save(data Config) error {
+// SaveIfMatch compares the latest revision of the history file and
+// saves the configuration if it matches, while holding the lock
+func (c *FileConfigWrite) SaveIfMatch(
+	data Config,
+	author string,
+	rev int) (Revision, error) {
+	//
 	c.Log.Info(
 		infSavingConfig,
 		zap.String(infConfig, data.String()), zap.String(infFile, c.DataFile))
 
 	conf, err := json.Marshal(data)
 	if err != nil {
-		return errors.Wrap(err, strings.Concat(errMarshallConfig, c.DataFile))
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errMarshallConfig, c.DataFile))
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	revs, err := readHistoryFile(c.historyFile())
+	if err != nil {
+		return Revision{}, err
+	}
+
+	last := 0
+	if len(revs) > 0 {
+		last = revs[0].Rev
+	}
+
+	if rev != AnyRevision && rev != last {
+		return Revision{}, ErrRevisionConflict
 	}
 
 	if err := os.WriteFile(c.DataFile, conf, os.FileMode(0664)); err != nil {
-		return errors.Wrap(err, strings.Concat(errSaveConfig, c.DataFile))
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errSaveConfig, c.DataFile))
+	}
+
+	saved := newRevision(last, author, data)
+
+	if err := appendHistoryFile(c.historyFile(), saved); err != nil {
+		return Revision{}, err
 	}
 
 	notifyHub(c.Config, data, c.Hub)
 
-	return nil
+	return saved, nil
+}
+
+// Current reads the latest revision of the history file
+func (c *FileConfigWrite) Current() (int, error) {
+	revs, err := c.History()
+	if err != nil || len(revs) == 0 {
+		return 0, err
+	}
+
+	return revs[0].Rev, nil
+}
+
+// History reads the revisions of the history file
+func (c *FileConfigWrite) History() ([]Revision, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return readHistoryFile(c.historyFile())
+}
+
+// Revision reads the revision of the history file
+func (c *FileConfigWrite) Revision(rev int) (Revision, error) {
+	revs, err := c.History()
+	if err != nil {
+		return Revision{}, err
+	}
+
+	return findRevision(revs, rev)
+}
+
+func (c *FileConfigWrite) historyFile() string {
+	return c.DataFile + historySuffix
 }
 
 // ConfigRead reads the micro controller configuration from AWS dynamodb
@@ -248,7 +369,8 @@ func (c *AWSConfigRead) Read() (Config, error) {
 	res, err := c.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
 		TableName: aws.String(c.tableName),
 		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: dynamoDBTableKeyValue},
+			dynamoDBTableKeyName: &types.AttributeValueMemberS{
+				Value: dynamoDBTableKeyValue},
 		},
 	})
 	if err != nil {
@@ -257,8 +379,8 @@ func (c *AWSConfigRead) Read() (Config, error) {
 		return Config{}, errors.Wrap(err, errReadConfig)
 	}
 
-	resMap := make(map[string]string)
-	if err := attributevalue.UnmarshalMap(res.Item, &resMap); err != nil {
+	var item dynamoRevision
+	if err := attributevalue.UnmarshalMap(res.Item, &item); err != nil {
 		c.log.Error(
 			errUnmarsConfig,
 			zap.String(infFile, c.tableName),
@@ -267,14 +389,13 @@ func (c *AWSConfigRead) Read() (Config, error) {
 		return Config{}, errors.Wrap(err, errUnmarsConfig)
 	}
 
-	if len(resMap) == 0 {
+	if item.Config == "" {
 		return DefaultConfig(), nil
 	}
 
 	var mc Config
 
-	if err := json.Unmarshal(
-		[]byte(resMap[dynamoDBTableConfigName]), &mc); err != nil {
+	if err := json.Unmarshal([]byte(item.Config), &mc); err != nil {
 		c.log.Error(
 			errUnmarsConfig,
 			zap.String(infFile, c.tableName),
@@ -316,7 +437,22 @@ func NewAWSConfigWrite(
 	}
 }
 
-func (c AWSDynamoConfigWrite) Save(data Config) error {
+// Save saves the configuration and the revision in a transaction
+func (c *AWSDynamoConfigWrite) Save(
+	data Config,
+	author string) (Revision, error) {
+	//
+	return c.SaveIfMatch(data, author, AnyRevision)
+}
+
+// SaveIfMatch saves the configuration and the revision in a transaction.
+// The put of the current item is conditioned to the revision that has
+// been read, so a concurrent save is a conflict
+func (c *AWSDynamoConfigWrite) SaveIfMatch(
+	data Config,
+	author string,
+	rev int) (Revision, error) {
+	//
 	c.log.Info(
 		infSavingConfig,
 		zap.String(infConfig, data.String()),
@@ -324,28 +460,229 @@ func (c AWSDynamoConfigWrite) Save(data Config) error {
 
 	conf, err := json.Marshal(data)
 	if err != nil {
-		return errors.Wrap(err, strings.Concat(errMarshallConfig, c.tableName))
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errMarshallConfig, c.tableName))
+	}
+
+	last, err := c.item(dynamoDBTableKeyValue)
+	if err != nil && !errors.Is(err, ErrRevisionNotFound) {
+		return Revision{}, err
+	}
+
+	if rev != AnyRevision && rev != last.Rev {
+		return Revision{}, ErrRevisionConflict
+	}
+
+	saved := newRevision(last.Rev, author, data)
+
+	current := dynamoRevision{
+		ID:     dynamoDBTableKeyValue,
+		Rev:    saved.Rev,
+		Author: saved.Author,
+		Saved:  saved.Saved,
+		Config: string(conf),
+	}
+
+	history := current
+	history.ID = dynamoDBRevPrefix + strconv.Itoa(saved.Rev)
+
+	citem, err := attributevalue.MarshalMap(current)
+	if err != nil {
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errMarshallConfig, c.tableName))
+	}
+
+	hitem, err := attributevalue.MarshalMap(history)
+	if err != nil {
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errMarshallConfig, c.tableName))
+	}
+
+	put := &types.Put{
+		TableName:           aws.String(c.tableName),
+		Item:                citem,
+		ConditionExpression: aws.String("attribute_not_exists(#rev)"),
+		ExpressionAttributeNames: map[string]string{
+			"#rev": dynamoDBRevName,
+		},
 	}
 
-	_, err = c.client.PutItem(
+	if last.Rev > 0 {
+		put.ConditionExpression = aws.String("#rev = :rev")
+		put.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":rev": &types.AttributeValueMemberN{
+				Value: strconv.Itoa(last.Rev)},
+		}
+	}
+
+	_, err = c.client.TransactWriteItems(
 		context.TODO(),
-		&dynamodb.PutItemInput{
-			TableName: aws.String(c.tableName),
-			Item: map[string]types.AttributeValue{
-				dynamoDBTableKeyName: &types.AttributeValueMemberS{
-					Value: dynamoDBTableKeyValue},
-				dynamoDBTableConfigName: &types.AttributeValueMemberS{
-					Value: string(conf)},
+		&dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{Put: put},
+				{Put: &types.Put{
+					TableName:           aws.String(c.tableName),
+					Item:                hitem,
+					ConditionExpression: aws.String("attribute_not_exists(#id)"),
+					ExpressionAttributeNames: map[string]string{
+						"#id": dynamoDBTableKeyName,
+					},
+				}},
 			},
 		})
-
 	if err != nil {
-		return errors.Wrap(err, strings.Concat(errSaveConfig, c.tableName))
+		if conditionFailed(err) {
+			return Revision{}, ErrRevisionConflict
+		}
+
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errSaveConfig, c.tableName))
 	}
 
 	notifyHub(c.config, data, c.hub)
 
-	return nil
+	return saved, nil
+}
+
+// Current gets the latest revision of the current item
+func (c *AWSDynamoConfigWrite) Current() (int, error) {
+	it, err := c.item(dynamoDBTableKeyValue)
+	if errors.Is(err, ErrRevisionNotFound) {
+		return 0, nil
+	}
+
+	return it.Rev, err
+}
+
+// History scans the revisions of the table
+func (c *AWSDynamoConfigWrite) History() ([]Revision, error) {
+	revs := []Revision{}
+
+	p := dynamodb.NewScanPaginator(c.client, &dynamodb.ScanInput{
+		TableName:        aws.String(c.tableName),
+		FilterExpression: aws.String("begins_with(#id, :prefix)"),
+		ExpressionAttributeNames: map[string]string{
+			"#id": dynamoDBTableKeyName,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: dynamoDBRevPrefix},
+		},
+	})
+
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errReadHistory, c.tableName))
+		}
+
+		var items []dynamoRevision
+
+		if err := attributevalue.UnmarshalListOfMaps(
+			page.Items, &items); err != nil {
+			//
+			return nil, errors.Wrap(
+				err,
+				strings.Concat(errUnmarsHistory, c.tableName))
+		}
+
+		for _, it := range items {
+			rev, err := it.revision()
+			if err != nil {
+				return nil, errors.Wrap(
+					err,
+					strings.Concat(errUnmarsHistory, c.tableName))
+			}
+
+			revs = append(revs, rev)
+		}
+	}
+
+	sortRevisions(revs)
+
+	return revs, nil
+}
+
+// Revision gets the revision of the table
+func (c *AWSDynamoConfigWrite) Revision(rev int) (Revision, error) {
+	it, err := c.item(dynamoDBRevPrefix + strconv.Itoa(rev))
+	if err != nil {
+		return Revision{}, err
+	}
+
+	r, err := it.revision()
+	if err != nil {
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errUnmarsHistory, c.tableName))
+	}
+
+	return r, nil
+}
+
+// item gets the item. ErrRevisionNotFound if it does not exist
+func (c *AWSDynamoConfigWrite) item(id string) (dynamoRevision, error) {
+	res, err := c.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(c.tableName),
+		Key: map[string]types.AttributeValue{
+			dynamoDBTableKeyName: &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return dynamoRevision{}, errors.Wrap(
+			err,
+			strings.Concat(errReadHistory, c.tableName))
+	}
+
+	if len(res.Item) == 0 {
+		return dynamoRevision{}, ErrRevisionNotFound
+	}
+
+	var it dynamoRevision
+
+	if err := attributevalue.UnmarshalMap(res.Item, &it); err != nil {
+		return dynamoRevision{}, errors.Wrap(
+			err,
+			strings.Concat(errUnmarsHistory, c.tableName))
+	}
+
+	return it, nil
+}
+
+// conditionFailed checks whether the transaction has been canceled
+// by a condition of its items
+func conditionFailed(err error) bool {
+	var tce *types.TransactionCanceledException
+	if !errors.As(err, &tce) {
+		return false
+	}
+
+	for _, r := range tce.CancellationReasons {
+		if aws.ToString(r.Code) == "ConditionalCheckFailed" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// revision converts the item to the revision
+func (it dynamoRevision) revision() (Revision, error) {
+	var data Config
+
+	err := json.Unmarshal([]byte(it.Config), &data)
+
+	return Revision{
+		Rev:    it.Rev,
+		Author: it.Author,
+		Saved:  it.Saved,
+		Config: data,
+	}, err
 }
 
 func notifyHub(c config.Config, data Config, h Hub) {
@@ -18,7 +18,7 @@
 package iot_test
 
 import (
-	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -43,9 +43,20 @@ func TestConfigSave_Default(t *testing.T) {
 	t.Parallel()
 
 	r := iotc.DefaultConfigSave{zap.NewExample()}
-	err := r.Save(iotc.DefaultConfig())
+	rev, err := r.Save(iotc.DefaultConfig(), "admin")
 
 	require.NoError(t, err)
+	assert.Equal(t, 1, rev.Rev)
+	assert.Equal(t, "admin", rev.Author)
+
+	revs, err := r.History()
+
+	require.NoError(t, err)
+	assert.Empty(t, revs)
+
+	_, err = r.Revision(1)
+
+	require.ErrorIs(t, err, iotc.ErrRevisionNotFound)
 }
 
 func TestConfigRead_Read(t *testing.T) {
@@ -150,7 +161,7 @@ func TestConfigWrite_Save(t *testing.T) {
 		{
 			name: "Write micro config successfully",
 			fields: fields{
-				DataFile: "./testr/micro-config-write-sucess.dat",
+				DataFile: "micro-config-write-sucess.dat",
 			},
 			args: args{
 				data: iotc.DefaultConfig(),
@@ -178,7 +189,7 @@ func TestConfigWrite_Save(t *testing.T) {
 		{
 			name: "Write micro config. Error writing file",
 			fields: fields{
-				DataFile: "./no_exist/micro-config-write.dat",
+				DataFile: "no_exist/micro-config-write.dat",
 			},
 			args: args{
 				data: iotc.DefaultConfig(),
@@ -206,18 +217,18 @@ func TestConfigWrite_Save(t *testing.T) {
 				Log:      zap.NewExample(),
 				Hub:      h,
 				Config:   cnf,
-				DataFile: tt.fields.DataFile,
+				DataFile: filepath.Join(t.TempDir(), tt.fields.DataFile),
 			}
 
-			if err := c.Save(tt.args.data); err != nil && tt.err.want {
+			rev, err := c.Save(tt.args.data, "admin")
+			if tt.err.want {
 				require.ErrorContains(t, err, tt.err.msg, "Error")
 
 				return
 			}
 
-			if err := os.Remove(tt.fields.DataFile); err != nil {
-				require.Error(t, err, "Removing micro config file created")
-			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, rev.Rev, "Revision")
 
 			h.AssertExpectations(t)
 		})
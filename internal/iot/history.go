@@ -0,0 +1,162 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package iot
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/pkg/strings"
+)
+
+var (
+	// ErrRevisionNotFound is returned when the revision does not exist
+	ErrRevisionNotFound = errors.New("The configuration revision " +
+		"does not exist")
+	// ErrRevisionConflict is returned when the configuration has been
+	// saved since the expected revision
+	ErrRevisionConflict = errors.New("The configuration has been " +
+		"changed by another save")
+)
+
+const (
+	errReadHistory    = "Reading the configuration history: "
+	errUnmarsHistory  = "Unmarshalling the configuration history: "
+	errMarshalHistory = "Marshalling the configuration revision: "
+	errWriteHistory   = "Writing the configuration history: "
+)
+
+// AnyRevision saves the configuration whatever the latest revision is
+const AnyRevision = -1
+
+// historySuffix is added to the config file name to name the history file
+const historySuffix = ".history"
+
+// maxRevisionSize is the maximum size of a revision of the history file
+const maxRevisionSize = 64 * 1024
+
+// Revision is a saved configuration. The revisions are numbered from 1
+type Revision struct {
+	Rev    int       `json:"rev"`
+	Author string    `json:"author"`
+	Saved  time.Time `json:"saved"`
+	Config Config    `json:"config"`
+}
+
+// ConfigHistory reads the saved revisions of the configuration
+type ConfigHistory interface {
+	// Current gets the latest revision, 0 if there are no revisions
+	Current() (int, error)
+	// History lists the revisions, the latest first
+	History() ([]Revision, error)
+	// Revision gets the revision. ErrRevisionNotFound if it does not exist
+	Revision(rev int) (Revision, error)
+}
+
+// newRevision creates the revision that follows the last one
+func newRevision(last int, author string, data Config) Revision {
+	return Revision{
+		Rev:    last + 1,
+		Author: author,
+		Saved:  time.Now().UTC(),
+		Config: data,
+	}
+}
+
+// sortRevisions sorts the revisions, the latest first
+func sortRevisions(revs []Revision) {
+	sort.Slice(revs, func(i, j int) bool {
+		return revs[i].Rev > revs[j].Rev
+	})
+}
+
+// findRevision finds the revision in the list
+func findRevision(revs []Revision, rev int) (Revision, error) {
+	for _, r := range revs {
+		if r.Rev == rev {
+			return r, nil
+		}
+	}
+
+	return Revision{}, ErrRevisionNotFound
+}
+
+// readHistoryFile reads the revisions of the file, one json per line.
+// If the file not exists there are no revisions
+func readHistoryFile(name string) ([]Revision, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Revision{}, nil
+		}
+
+		return nil, errors.Wrap(err, strings.Concat(errReadHistory, name))
+	}
+
+	defer file.Close()
+
+	revs := []Revision{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRevisionSize)
+
+	for scanner.Scan() {
+		var r Revision
+
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, errors.Wrap(err, strings.Concat(errUnmarsHistory, name))
+		}
+
+		revs = append(revs, r)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, strings.Concat(errReadHistory, name))
+	}
+
+	sortRevisions(revs)
+
+	return revs, nil
+}
+
+// appendHistoryFile appends the revision at the end of the file
+func appendHistoryFile(name string, rev Revision) error {
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errMarshalHistory, name))
+	}
+
+	file, err := os.OpenFile(
+		name,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0664)
+	if err != nil {
+		return errors.Wrap(err, strings.Concat(errWriteHistory, name))
+	}
+
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, strings.Concat(errWriteHistory, name))
+	}
+
+	return nil
+}
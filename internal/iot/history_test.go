@@ -0,0 +1,140 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package iot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/config"
+	iotc "github.com/swpoolcontroller/internal/iot"
+	"github.com/swpoolcontroller/internal/iot/mocks"
+	"go.uber.org/zap"
+)
+
+func TestFileConfigWrite_History(t *testing.T) {
+	t.Parallel()
+
+	h := mocks.NewHub(t)
+	h.On("Config", mock.Anything)
+
+	c := &iotc.FileConfigWrite{
+		Log:      zap.NewExample(),
+		Hub:      h,
+		Config:   config.Default(),
+		DataFile: filepath.Join(t.TempDir(), "micro-config.dat"),
+	}
+
+	revs, err := c.History()
+
+	require.NoError(t, err)
+	assert.Empty(t, revs, "No revisions before saving")
+
+	for i, author := range []string{"admin", "operator"} {
+		cnf := iotc.DefaultConfig()
+		cnf.Buffer = uint8(i + 1)
+
+		rev, err := c.Save(cnf, author)
+
+		require.NoError(t, err)
+		assert.Equal(t, i+1, rev.Rev, "Revision")
+	}
+
+	revs, err = c.History()
+
+	require.NoError(t, err)
+	require.Len(t, revs, 2)
+	assert.Equal(t, 2, revs[0].Rev, "Latest first")
+	assert.Equal(t, "operator", revs[0].Author)
+	assert.Equal(t, uint8(2), revs[0].Config.Buffer)
+
+	rev, err := c.Revision(1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "admin", rev.Author)
+	assert.Equal(t, uint8(1), rev.Config.Buffer)
+
+	_, err = c.Revision(3)
+
+	require.ErrorIs(t, err, iotc.ErrRevisionNotFound)
+}
+
+func TestFileConfigWrite_History_Error(t *testing.T) {
+	t.Parallel()
+
+	dataFile := filepath.Join(t.TempDir(), "micro-config.dat")
+
+	require.NoError(t, os.WriteFile(dataFile+".history", []byte("{"), 0600))
+
+	c := &iotc.FileConfigWrite{
+		Log:      zap.NewExample(),
+		Hub:      mocks.NewHub(t),
+		Config:   config.Default(),
+		DataFile: dataFile,
+	}
+
+	_, err := c.History()
+
+	require.ErrorContains(t, err, "Unmarshalling the configuration history")
+
+	_, err = c.Save(iotc.DefaultConfig(), "admin")
+
+	require.Error(t, err, "Save")
+}
+
+func TestFileConfigWrite_SaveIfMatch(t *testing.T) {
+	t.Parallel()
+
+	h := mocks.NewHub(t)
+	h.On("Config", mock.Anything)
+
+	c := &iotc.FileConfigWrite{
+		Log:      zap.NewExample(),
+		Hub:      h,
+		Config:   config.Default(),
+		DataFile: filepath.Join(t.TempDir(), "micro-config.dat"),
+	}
+
+	rev, err := c.SaveIfMatch(iotc.DefaultConfig(), "admin", 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, rev.Rev)
+
+	_, err = c.SaveIfMatch(iotc.DefaultConfig(), "operator", 0)
+
+	require.ErrorIs(t, err, iotc.ErrRevisionConflict, "Stale revision")
+
+	rev, err = c.SaveIfMatch(iotc.DefaultConfig(), "operator", 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, rev.Rev)
+
+	rev, err = c.SaveIfMatch(iotc.DefaultConfig(), "admin", iotc.AnyRevision)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, rev.Rev)
+
+	cur, err := c.Current()
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, cur)
+}
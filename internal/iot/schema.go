@@ -0,0 +1,122 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package iot
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	// SchemaDialect is the JSON schema version of ConfigSchema
+	SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	schemaTitle   = "Micro controller configuration"
+)
+
+// JSON schema types
+const (
+	schemaString  = "string"
+	schemaInteger = "integer"
+	schemaNumber  = "number"
+	schemaBoolean = "boolean"
+	schemaObject  = "object"
+)
+
+// Schema is the JSON schema of the configuration
+type Schema struct {
+	Schema     string                    `json:"$schema"`
+	Title      string                    `json:"title"`
+	Type       string                    `json:"type"`
+	Properties map[string]SchemaProperty `json:"properties"`
+	Required   []string                  `json:"required"`
+	// AdditionalProperties is false, the unknown fields are not saved
+	AdditionalProperties bool `json:"additionalProperties"`
+}
+
+// SchemaProperty is the JSON schema of a field. The unit and the order
+// of the field in the form are extensions of the schema
+type SchemaProperty struct {
+	Type    string      `json:"type"`
+	Title   string      `json:"title,omitempty"`
+	Minimum *float64    `json:"minimum,omitempty"`
+	Maximum *float64    `json:"maximum,omitempty"`
+	Pattern string      `json:"pattern,omitempty"`
+	Default interface{} `json:"default"`
+	Unit    string      `json:"x-unit,omitempty"`
+	Order   int         `json:"x-order"`
+}
+
+// ConfigSchema generates the JSON schema of Config with the types of its
+// fields, the rules of ConfigRules and the values of DefaultConfig
+func ConfigSchema() Schema {
+	s := Schema{
+		Schema:     SchemaDialect,
+		Title:      schemaTitle,
+		Type:       schemaObject,
+		Properties: make(map[string]SchemaProperty),
+		Required:   []string{},
+	}
+
+	v := reflect.ValueOf(DefaultConfig())
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+
+		p := SchemaProperty{
+			Type:    schemaType(t.Field(i).Type.Kind()),
+			Default: v.Field(i).Interface(),
+			Order:   i,
+		}
+
+		if r, ok := rule(ConfigRules, name); ok {
+			p.Title = r.Label
+			p.Unit = r.Unit
+
+			if p.Type == schemaInteger || p.Type == schemaNumber {
+				p.Minimum = &r.Min
+				p.Maximum = &r.Max
+			}
+
+			if f, ok := formats[r.Format]; ok {
+				p.Pattern = f.pattern.String()
+			}
+		}
+
+		s.Properties[name] = p
+		s.Required = append(s.Required, name)
+	}
+
+	return s
+}
+
+// schemaType is the JSON schema type of the kind
+func schemaType(k reflect.Kind) string {
+	switch k { //nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64:
+		return schemaInteger
+	case reflect.Float32, reflect.Float64:
+		return schemaNumber
+	case reflect.Bool:
+		return schemaBoolean
+	default:
+		return schemaString
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package iot_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	iotc "github.com/swpoolcontroller/internal/iot"
+)
+
+func TestConfigSchema(t *testing.T) {
+	t.Parallel()
+
+	s := iotc.ConfigSchema()
+
+	assert.Equal(t, "object", s.Type)
+	assert.False(t, s.AdditionalProperties)
+	assert.Len(t, s.Required, len(iotc.ConfigRules))
+
+	data, err := json.Marshal(iotc.DefaultConfig())
+	require.NoError(t, err)
+
+	var def map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(data, &def))
+
+	for _, r := range iotc.ConfigRules {
+		p, ok := s.Properties[r.Field]
+
+		require.True(t, ok, r.Field)
+		assert.Equal(t, r.Label, p.Title, r.Field)
+		assert.Equal(t, r.Unit, p.Unit, r.Field)
+		assert.EqualValues(t, def[r.Field], p.Default, r.Field)
+	}
+
+	wakeup := s.Properties["wakeup"]
+
+	assert.Equal(t, "integer", wakeup.Type)
+	assert.InDelta(t, 15, *wakeup.Minimum, 0)
+	assert.InDelta(t, 120, *wakeup.Maximum, 0)
+	assert.Equal(t, 2, wakeup.Order)
+
+	assert.Equal(t, "number", s.Properties["targetPh"].Type)
+	assert.Equal(t, "boolean", s.Properties["calibratingPh"].Type)
+	assert.Nil(t, s.Properties["calibratingPh"].Minimum)
+
+	ini := s.Properties["iniSendTime"]
+
+	assert.Equal(t, "string", ini.Type)
+	assert.Regexp(t, ini.Pattern, "09:00")
+	assert.NotRegexp(t, ini.Pattern, "25:99")
+}
@@ -0,0 +1,239 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package iot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/pkg/strings"
+	"go.uber.org/zap"
+)
+
+const (
+	errSQLReadConfig = "Reading the configuration of the micro controller " +
+		"from the sql database"
+	errSQLSaveConfig = "Saving the configuration of the micro controller " +
+		"in the sql database"
+)
+
+const (
+	infSQLConfigLoaded = "Configuration loaded from the sql database"
+	infSQLSavingConfig = "Saving configuration in the sql database"
+)
+
+// sqlConfigTable is the table of the configuration revisions
+const sqlConfigTable = "config_revisions"
+
+// sqlRevisionColumns are the columns of the config_revisions table
+const sqlRevisionColumns = "SELECT rev, author, saved, config " +
+	"FROM config_revisions"
+
+// rowScanner is implemented by sql.Row and sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// SQLConfigRead reads the micro controller configuration
+// from the latest revision of the sql database
+type SQLConfigRead struct {
+	Log *zap.Logger
+	DB  *sql.DB
+}
+
+// Read reads the latest revision.
+// If there are no revisions returns config default
+func (c *SQLConfigRead) Read() (Config, error) {
+	rev, err := scanRevision(c.DB.QueryRow(
+		sqlRevisionColumns + " ORDER BY rev DESC LIMIT 1"))
+	if err != nil {
+		if errors.Is(err, ErrRevisionNotFound) {
+			return DefaultConfig(), nil
+		}
+
+		c.Log.Error(errSQLReadConfig, zap.Error(err))
+
+		return Config{}, err
+	}
+
+	c.Log.Info(infSQLConfigLoaded, zap.String(infConfig, rev.Config.String()))
+
+	return rev.Config, nil
+}
+
+// SQLConfigWrite saves the micro controller configuration as a new
+// revision of the sql database. The latest revision is the current one
+type SQLConfigWrite struct {
+	Log    *zap.Logger
+	Hub    Hub
+	Config config.Config
+	DB     *sql.DB
+}
+
+// Save saves the configuration as the next revision
+func (c *SQLConfigWrite) Save(data Config, author string) (Revision, error) {
+	return c.SaveIfMatch(data, author, AnyRevision)
+}
+
+// SaveIfMatch compares the latest revision and inserts the next one
+// in the same transaction
+func (c *SQLConfigWrite) SaveIfMatch(
+	data Config,
+	author string,
+	rev int) (Revision, error) {
+	//
+	c.Log.Info(infSQLSavingConfig, zap.String(infConfig, data.String()))
+
+	conf, err := json.Marshal(data)
+	if err != nil {
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errMarshallConfig, sqlConfigTable))
+	}
+
+	tx, err := c.DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return Revision{}, errors.Wrap(err, errSQLSaveConfig)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	var last int
+
+	if err := tx.QueryRow(
+		"SELECT COALESCE(MAX(rev), 0) FROM config_revisions").
+		Scan(&last); err != nil {
+		//
+		return Revision{}, errors.Wrap(err, errSQLSaveConfig)
+	}
+
+	if rev != AnyRevision && rev != last {
+		return Revision{}, ErrRevisionConflict
+	}
+
+	saved := newRevision(last, author, data)
+
+	if _, err := tx.Exec(
+		"INSERT INTO config_revisions (rev, author, saved, config) "+
+			"VALUES (?, ?, ?, ?)",
+		saved.Rev,
+		saved.Author,
+		saved.Saved.Format(time.RFC3339Nano),
+		string(conf)); err != nil {
+		//
+		return Revision{}, errors.Wrap(err, errSQLSaveConfig)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Revision{}, errors.Wrap(err, errSQLSaveConfig)
+	}
+
+	notifyHub(c.Config, data, c.Hub)
+
+	return saved, nil
+}
+
+// Current reads the latest revision number
+func (c *SQLConfigWrite) Current() (int, error) {
+	var last int
+
+	if err := c.DB.QueryRow(
+		"SELECT COALESCE(MAX(rev), 0) FROM config_revisions").
+		Scan(&last); err != nil {
+		//
+		return 0, errors.Wrap(
+			err,
+			strings.Concat(errReadHistory, sqlConfigTable))
+	}
+
+	return last, nil
+}
+
+// History reads the revisions, the latest first
+func (c *SQLConfigWrite) History() ([]Revision, error) {
+	rows, err := c.DB.Query(sqlRevisionColumns + " ORDER BY rev DESC")
+	if err != nil {
+		return nil, errors.Wrap(
+			err,
+			strings.Concat(errReadHistory, sqlConfigTable))
+	}
+
+	defer rows.Close()
+
+	revs := []Revision{}
+
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		revs = append(revs, rev)
+	}
+
+	return revs, errors.Wrap(
+		rows.Err(),
+		strings.Concat(errReadHistory, sqlConfigTable))
+}
+
+// Revision reads the revision
+func (c *SQLConfigWrite) Revision(rev int) (Revision, error) {
+	return scanRevision(c.DB.QueryRow(
+		sqlRevisionColumns+" WHERE rev = ?", rev))
+}
+
+// scanRevision scans a row of sqlRevisionColumns.
+// ErrRevisionNotFound if there is no row
+func scanRevision(row rowScanner) (Revision, error) {
+	var (
+		rev   Revision
+		saved string
+		conf  string
+	)
+
+	if err := row.Scan(&rev.Rev, &rev.Author, &saved, &conf); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Revision{}, ErrRevisionNotFound
+		}
+
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errReadHistory, sqlConfigTable))
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, saved)
+	if err != nil {
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errUnmarsHistory, sqlConfigTable))
+	}
+
+	rev.Saved = t
+
+	if err := json.Unmarshal([]byte(conf), &rev.Config); err != nil {
+		return Revision{}, errors.Wrap(
+			err,
+			strings.Concat(errUnmarsHistory, sqlConfigTable))
+	}
+
+	return rev, nil
+}
@@ -0,0 +1,104 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package iot_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/config"
+	iotc "github.com/swpoolcontroller/internal/iot"
+	"github.com/swpoolcontroller/internal/iot/mocks"
+	"github.com/swpoolcontroller/internal/sqldb"
+	"go.uber.org/zap"
+)
+
+func TestSQLConfig(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqldb.Open(filepath.Join(t.TempDir(), "swpc.db"))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	h := mocks.NewHub(t)
+	h.On("Config", mock.Anything)
+
+	r := &iotc.SQLConfigRead{Log: zap.NewExample(), DB: db}
+	w := &iotc.SQLConfigWrite{
+		Log:    zap.NewExample(),
+		Hub:    h,
+		Config: config.Default(),
+		DB:     db,
+	}
+
+	cnf, err := r.Read()
+
+	require.NoError(t, err)
+	assert.Equal(t, iotc.DefaultConfig(), cnf, "Default without revisions")
+
+	cur, err := w.Current()
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, cur)
+
+	for i, author := range []string{"admin", "operator"} {
+		cnf := iotc.DefaultConfig()
+		cnf.Buffer = uint8(i + 4)
+
+		rev, err := w.SaveIfMatch(cnf, author, i)
+
+		require.NoError(t, err)
+		assert.Equal(t, i+1, rev.Rev, "Revision")
+	}
+
+	_, err = w.SaveIfMatch(iotc.DefaultConfig(), "admin", 1)
+
+	require.ErrorIs(t, err, iotc.ErrRevisionConflict, "Stale revision")
+
+	cnf, err = r.Read()
+
+	require.NoError(t, err)
+	assert.Equal(t, uint8(5), cnf.Buffer, "Latest revision")
+
+	revs, err := w.History()
+
+	require.NoError(t, err)
+	require.Len(t, revs, 2)
+	assert.Equal(t, 2, revs[0].Rev, "Latest first")
+	assert.Equal(t, "operator", revs[0].Author)
+	assert.False(t, revs[0].Saved.IsZero(), "Saved")
+
+	rev, err := w.Revision(1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "admin", rev.Author)
+	assert.Equal(t, uint8(4), rev.Config.Buffer)
+
+	_, err = w.Revision(3)
+
+	require.ErrorIs(t, err, iotc.ErrRevisionNotFound)
+
+	rev, err = w.Save(iotc.DefaultConfig(), "admin")
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, rev.Rev, "Any revision")
+}
@@ -0,0 +1,209 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package iot
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	errRuleMin    = "The value must be greater than or equal to "
+	errRuleMax    = "The value must be less than or equal to "
+	errRuleTime   = "The value must be an hour of the day as HH:MM"
+	errRuleWindow = "The end of the sending window must be after its start"
+	errFieldsBase = "The values are not valid"
+)
+
+// FormatTime is the format of the hours of the day, HH:MM
+const FormatTime = "time"
+
+// format is the pattern of a string format and its error
+type format struct {
+	pattern *regexp.Regexp
+	message string
+}
+
+// formats are the string formats of the rules
+var formats = map[string]format{
+	FormatTime: {
+		pattern: regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`),
+		message: errRuleTime,
+	},
+}
+
+// Rule is the rule of a field of a struct. The numbers must be
+// between Min and Max and the strings must have the Format
+type Rule struct {
+	// Field is the json name of the field
+	Field  string
+	Label  string
+	Unit   string
+	Min    float64
+	Max    float64
+	Format string
+}
+
+// ConfigRules are the rules of the fields of Config, in the order
+// of the struct. The limits are the ones of the config form of the UI.
+// The buffer is at most 20 s because the micro controller keeps the
+// metrics of the buffer in a fixed json document of 384 bytes
+var ConfigRules = []Rule{
+	{Field: "iniSendTime", Label: "Start sending", Format: FormatTime},
+	{Field: "endSendTime", Label: "End sending", Format: FormatTime},
+	{Field: "wakeup", Label: "Wake up", Unit: "min", Min: 15, Max: 120},
+	{Field: "buffer", Label: "Buffer", Unit: "s", Min: 3, Max: 20},
+	{
+		Field: "calibrationOrp", Label: "ORP calibration", Unit: "mV",
+		Min: -5000, Max: 5000,
+	},
+	{
+		Field: "calibrationPh", Label: "pH calibration", Unit: "pH",
+		Min: -14, Max: 14,
+	},
+	{Field: "calibratingOrp", Label: "Calibrating ORP"},
+	{
+		Field: "targetOrp", Label: "ORP target", Unit: "mV",
+		Min: -2000, Max: 2000,
+	},
+	{Field: "calibratingPh", Label: "Calibrating pH"},
+	{Field: "targetPh", Label: "pH target", Unit: "pH", Min: 0, Max: 14},
+	{
+		Field: "stabilizationTime", Label: "Stabilization time", Unit: "s",
+		Min: 5, Max: 60,
+	},
+}
+
+// FieldError is an invalid value of a field
+type FieldError struct {
+	// Field is the json name of the field
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every invalid field
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+
+	b.WriteString(errFieldsBase)
+
+	for _, f := range e {
+		b.WriteString("\n  ")
+		b.WriteString(f.Error())
+	}
+
+	return b.String()
+}
+
+// Validate checks the configuration with ConfigRules. It returns
+// a ValidationError with every invalid field or nil if it is valid
+func (c Config) Validate() error {
+	errs := ValidateFields(c, ConfigRules)
+
+	// The hub transmits between both hours of the same day
+	if len(errs) == 0 && c.EndSendTime <= c.IniSendTime {
+		errs = append(errs, FieldError{
+			Field:   "endSendTime",
+			Message: errRuleWindow,
+		})
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// ValidateFields checks the fields of the struct, named by their
+// json tag, with the rules. It returns every invalid field
+func ValidateFields(data interface{}, rules []Rule) ValidationError {
+	var errs ValidationError
+
+	v := reflect.ValueOf(data)
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+
+		r, ok := rule(rules, name)
+		if !ok {
+			continue
+		}
+
+		if msg := r.check(v.Field(i)); msg != "" {
+			errs = append(errs, FieldError{Field: name, Message: msg})
+		}
+	}
+
+	return errs
+}
+
+// rule finds the rule of the field
+func rule(rules []Rule, field string) (Rule, bool) {
+	for _, r := range rules {
+		if r.Field == field {
+			return r, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// check checks the value. It returns the message of the error
+// or empty if it is valid
+func (r Rule) check(v reflect.Value) string {
+	var n float64
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		f, ok := formats[r.Format]
+		if ok && !f.pattern.MatchString(v.String()) {
+			return f.message
+		}
+
+		return ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return ""
+	}
+
+	switch {
+	case n < r.Min:
+		return errRuleMin + strconv.FormatFloat(r.Min, 'f', -1, 64)
+	case n > r.Max:
+		return errRuleMax + strconv.FormatFloat(r.Max, 'f', -1, 64)
+	}
+
+	return ""
+}
@@ -0,0 +1,161 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package iot_test
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	iotc "github.com/swpoolcontroller/internal/iot"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		conf   func(c *iotc.Config)
+		fields []string
+	}{
+		{
+			name: "Validate. It should accept the default configuration",
+			conf: func(_ *iotc.Config) {},
+		},
+		{
+			name: "Validate. It should reject the invalid hours",
+			conf: func(c *iotc.Config) {
+				c.IniSendTime = "25:99"
+				c.EndSendTime = "9:00"
+			},
+			fields: []string{"iniSendTime", "endSendTime"},
+		},
+		{
+			name: "Validate. It should reject the values out of range",
+			conf: func(c *iotc.Config) {
+				c.Wakeup = 0
+				c.Buffer = 21
+				c.CalibrationORP = 5000.5
+				c.CalibrationPH = -15
+				c.TargetORP = 2001
+				c.TargetPH = -0.1
+				c.StabilizationTime = -1
+			},
+			fields: []string{
+				"wakeup", "buffer", "calibrationOrp", "calibrationPh",
+				"targetOrp", "targetPh", "stabilizationTime",
+			},
+		},
+		{
+			name: "Validate. It should accept the limits of the ranges",
+			conf: func(c *iotc.Config) {
+				c.Wakeup = 15
+				c.Buffer = 20
+				c.TargetPH = 0
+				c.StabilizationTime = 60
+			},
+		},
+		{
+			name: "Validate. It should reject an end before the start",
+			conf: func(c *iotc.Config) {
+				c.IniSendTime = "22:00"
+				c.EndSendTime = "09:00"
+			},
+			fields: []string{"endSendTime"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := iotc.DefaultConfig()
+			tt.conf(&c)
+
+			err := c.Validate()
+			if len(tt.fields) == 0 {
+				require.NoError(t, err)
+
+				return
+			}
+
+			var verr iotc.ValidationError
+
+			require.ErrorAs(t, err, &verr)
+
+			fields := make([]string, 0, len(verr))
+			for _, f := range verr {
+				fields = append(fields, f.Field)
+				assert.NotEmpty(t, f.Message, f.Field)
+			}
+
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
+
+func TestConfigRules_UI(t *testing.T) {
+	t.Parallel()
+
+	form, err := os.ReadFile("../../ui/src/config/config.tsx")
+	require.NoError(t, err)
+
+	limits := func(field string) (float64, float64) {
+		for _, r := range iotc.ConfigRules {
+			if strings.EqualFold(r.Field, field) {
+				return r.Min, r.Max
+			}
+		}
+
+		require.Failf(t, "The rule of the field does not exist", field)
+
+		return 0, 0
+	}
+
+	// The checks of the form, as "bufferValue >= 3 && ... <= 20"
+	checks := regexp.MustCompile(`(\w+)Value >= (-?[\d.]+) &&\s*`+
+		`(?:this\.state\.)?\w+Value <= (-?[\d.]+)`).
+		FindAllStringSubmatch(string(form), -1)
+
+	require.Len(t, checks, 7, "Checks of the numeric fields of the form")
+
+	for _, c := range checks {
+		lo, hi := limits(c[1])
+
+		assert.Equal(t, c[2], strconv.FormatFloat(lo, 'f', -1, 64), c[1])
+		assert.Equal(t, c[3], strconv.FormatFloat(hi, 'f', -1, 64), c[1])
+	}
+
+	// The limits of the inputs, as `id="buffer" ... min: 3, max: 20`
+	inputs := regexp.MustCompile(`id="(\w+)"[^<]*?`+
+		`inputProps: \{ min: (-?[\d.]+), max: (-?[\d.]+) \}`).
+		FindAllStringSubmatch(string(form), -1)
+
+	require.Len(t, inputs, 3, "Inputs with limits of the form")
+
+	for _, in := range inputs {
+		lo, hi := limits(strings.TrimSuffix(in[1], "ORP"))
+
+		assert.Equal(t, in[2], strconv.FormatFloat(lo, 'f', -1, 64), in[1])
+		assert.Equal(t, in[3], strconv.FormatFloat(hi, 'f', -1, 64), in[1])
+	}
+}
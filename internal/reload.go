@@ -0,0 +1,133 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package internal
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	errReloadDisabled = "The configuration cannot be reloaded " +
+		"because it has not a loader"
+	errReloadConfig = "The configuration cannot be reloaded. " +
+		"The current configuration is kept"
+	errReconfigureHub = "The hub has not received the new settings"
+)
+
+// reconfigureTimeout is the maximum time to wait for the hub,
+// which does not receive the settings once it is stopping
+const reconfigureTimeout = 2 * time.Second
+
+const (
+	infReloading      = "Reloading the configuration ..."
+	infReloaded       = "The configuration has been reloaded"
+	infReloadRestart  = "Some changes of the configuration need a restart"
+	infReloadChanges  = "Changes"
+	infReloadPending  = "Pending"
+	infReloadNoChange = "The configuration has not changed"
+)
+
+// Reload applies the changes of the configuration that are safe
+// while running: the hub timings, the heartbeat, the log level
+// and the IOT flags of the UI. It returns the json path of the
+// changes that are not applied until the server is restarted.
+// If the hub does not receive the settings, nothing is applied
+func (s *Server) Reload(cnf config.Config) ([]string, error) {
+	f := s.factory
+
+	running := f.loaded
+	applyLive(&running, cnf)
+
+	applied := config.Changes(f.loaded, running)
+	pending := config.Changes(running, cnf)
+
+	if len(applied) == 0 {
+		f.Log.Info(infReloadNoChange)
+
+		return pending, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reconfigureTimeout)
+	defer cancel()
+
+	if err := f.Hub.Reconfigure(ctx, hubSettings(cnf)); err != nil {
+		return nil, errors.Wrap(err, errReconfigureHub)
+	}
+
+	f.Level.SetLevel(zapcore.Level(cnf.Level))
+	f.WebHandler.Authz.SetIOT(cnf.IOT)
+
+	f.loaded = running
+	applyLive(&f.Config, cnf)
+
+	f.Log.Info(
+		infReloaded,
+		zap.String(infReloadChanges, strings.Join(applied, ", ")))
+
+	return pending, nil
+}
+
+// reload loads the configuration again and applies it.
+// If it cannot be loaded or it is not valid, nothing is changed
+func (s *Server) reload() {
+	if s.Load == nil {
+		s.factory.Log.Warn(errReloadDisabled)
+
+		return
+	}
+
+	s.factory.Log.Info(infReloading)
+
+	cnf, err := s.Load()
+	if err != nil {
+		s.factory.Log.Error(errReloadConfig, zap.Error(err))
+
+		return
+	}
+
+	pending, err := s.Reload(cnf)
+	if err != nil {
+		s.factory.Log.Error(errReloadConfig, zap.Error(err))
+
+		return
+	}
+
+	if len(pending) > 0 {
+		s.factory.Log.Warn(
+			infReloadRestart,
+			zap.String(infReloadPending, strings.Join(pending, ", ")))
+	}
+}
+
+// applyLive copies the keys that can be changed while running
+func applyLive(dst *config.Config, src config.Config) {
+	dst.Level = src.Level
+	dst.Hub = src.Hub
+	dst.CommLatencyTime = src.CommLatencyTime
+	dst.HeartbeatInterval = src.HeartbeatInterval
+	dst.HeartbeatPingTime = src.HeartbeatPingTime
+	dst.HeartbeatTimeoutCount = src.HeartbeatTimeoutCount
+	dst.IOT = src.IOT
+}
@@ -22,9 +22,11 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/pkg/errors"
 	"github.com/swpoolcontroller/internal/config"
@@ -36,6 +38,7 @@ import (
 
 const (
 	errShutdownServer = "Shutting down web server"
+	errCloseDB        = "Closing the sql database"
 )
 
 const (
@@ -50,12 +53,18 @@ const (
 type Server struct {
 	factory *Factory
 	quit    chan os.Signal
+	hup     chan os.Signal
+
+	// Load loads the configuration again on SIGHUP.
+	// If it is nil, the configuration is not reloaded
+	Load func() (config.Config, error)
 }
 
 func NewServer(factory *Factory) *Server {
 	return &Server{
 		factory: factory,
 		quit:    make(chan os.Signal, 1),
+		hup:     make(chan os.Signal, 1),
 	}
 }
 
@@ -85,7 +94,9 @@ func (s *Server) Start() error {
 	}()
 
 	signal.Notify(s.quit, os.Interrupt)
-	<-s.quit
+	signal.Notify(s.hup, syscall.SIGHUP)
+
+	s.wait()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -102,11 +113,29 @@ func (s *Server) Start() error {
 	s.factory.Log.Info(infStoppingHub)
 	s.factory.Hub.Stop()
 
+	if err := s.factory.Close(); err != nil {
+		s.factory.Log.Error(errCloseDB, zap.Error(err))
+	}
+
 	s.factory.Log.Info(infStoppedServer)
 
 	return nil
 }
 
+// wait reloads the configuration on SIGHUP until the server is stopped
+func (s *Server) wait() {
+	for {
+		select {
+		case <-s.hup:
+			s.reload()
+		case <-s.quit:
+			signal.Stop(s.hup)
+
+			return
+		}
+	}
+}
+
 // Middleware configure security and behaviour of http
 func (s *Server) Middleware() {
 	s.factory.Webs.Use(middleware.Recover())
@@ -126,9 +155,39 @@ func (s *Server) Route() {
 	wapp := s.factory.Webs.Group("/app")
 	wapp.GET("/config", s.factory.WebHandler.AppConfig.Load)
 
+	auditor := s.factory.WebHandler.Audit
+	sessions := s.factory.WebHandler.Sessions
+
 	wa := s.factory.Webs.Group("/auth")
-	wa.GET("/login", s.factory.WebHandler.Auth.Login)
-	wa.GET("/logout", s.factory.WebHandler.Auth.Logout)
+	if signin, ok := s.factory.WebHandler.Auth.(web.SignIner); ok {
+		wa.GET("/signin", signin.SignIn)
+	}
+
+	if s.factory.Config.Auth.Provider == config.AuthProviderLocal {
+		// The credentials are posted by the login form of the UI
+		wa.POST(
+			"/login",
+			s.factory.WebHandler.Auth.Login,
+			auditor.Record(web.ActionLogin),
+			sessions.Start())
+	} else {
+		wa.GET(
+			"/login",
+			s.factory.WebHandler.Auth.Login,
+			auditor.Record(web.ActionLogin),
+			sessions.Start())
+	}
+
+	wa.GET(
+		"/logout",
+		s.factory.WebHandler.Auth.Logout,
+		auditor.Record(web.ActionLogout),
+		sessions.End())
+	wa.POST(
+		"/refresh",
+		s.factory.WebHandler.Auth.Refresh,
+		sessions.Renewal(),
+		sessions.Start())
 	wa.GET(strings.Concat(
 		"/token/:",
 		iot.ClientIDName),
@@ -139,21 +198,129 @@ func (s *Server) Route() {
 	// Web
 	wapi := s.factory.Webs.Group("/api/web")
 
-	if s.factory.Config.Auth.Provider == config.AuthProviderOauth2 {
+	apiKey := s.factory.WebHandler.APIKey
+	if apiKey != nil {
+		// The integrations send an api key as bearer instead of the cookie
+		wapi.Use(apiKey.Middleware())
+	}
+
+	if s.factory.Config.Auth.Provider != config.AuthProviderDev {
+		// ParseJWT validates the signature and, for oidc and local,
+		// the issuer
 		config := echojwt.Config{
-			KeyFunc:     s.factory.JWT.GetKey,
+			ParseTokenFunc: func(_ echo.Context, auth string) (interface{}, error) {
+				return s.factory.JWT.ParseJWT(auth)
+			},
 			TokenLookup: strings.Concat("cookie:", web.AuthHeaderName),
 		}
+
+		if apiKey != nil {
+			config.Skipper = apiKey.Authenticated
+		}
+
 		wapi.Use(echojwt.WithConfig(config))
 	}
 
-	wapi.GET("/config", s.factory.WebHandler.Config.Load)
-	wapi.POST("/config", s.factory.WebHandler.Config.Save)
+	// The terminated sessions are rejected even if the token is valid
+	wapi.Use(sessions.Check())
+
+	authz := s.factory.WebHandler.Authz
+
+	wapi.GET(
+		"/config",
+		s.factory.WebHandler.Config.Load,
+		authz.Require(web.PermConfigRead))
+	wapi.POST(
+		"/config",
+		s.factory.WebHandler.Config.Save,
+		auditor.Record(web.ActionConfigSave),
+		authz.Require(web.PermConfigWrite))
+	wapi.GET(
+		"/config/schema",
+		s.factory.WebHandler.Config.Schema,
+		authz.Require(web.PermConfigRead))
+	wapi.GET(
+		"/config/status",
+		s.factory.WebHandler.Config.DeliveryStatus,
+		authz.Require(web.PermConfigRead))
+	wapi.GET(
+		"/config/history",
+		s.factory.WebHandler.Config.Revisions,
+		authz.Require(web.PermConfigRead))
+	wapi.GET(
+		"/config/diff",
+		s.factory.WebHandler.Config.Diff,
+		authz.Require(web.PermConfigRead))
+	wapi.POST(
+		"/config/rollback/:rev",
+		s.factory.WebHandler.Config.Rollback,
+		auditor.Record(web.ActionConfigRollback),
+		authz.Require(web.PermConfigWrite))
+
+	wapi.POST(
+		"/sample",
+		s.factory.WebHandler.Sample.Save,
+		auditor.Record(web.ActionSampleSave),
+		authz.Require(web.PermSampleWrite))
+	wapi.GET(
+		"/samples",
+		s.factory.WebHandler.Sample.List,
+		authz.Require(web.PermSampleRead))
+	wapi.GET(
+		"/samples/export",
+		s.factory.WebHandler.Sample.Export,
+		authz.Require(web.PermSampleRead))
+	wapi.GET(
+		"/samples/:id",
+		s.factory.WebHandler.Sample.Get,
+		authz.Require(web.PermSampleRead))
+	wapi.DELETE(
+		"/samples/:id",
+		s.factory.WebHandler.Sample.Delete,
+		auditor.Record(web.ActionSampleDelete),
+		authz.Require(web.PermSampleWrite))
+	wapi.POST(
+		"/predict",
+		s.factory.WebHandler.Prediction.Predict,
+		authz.Require(web.PermMetricsRead))
+
+	if pwd, ok := s.factory.WebHandler.Auth.(web.PasswordChanger); ok {
+		// Any authenticated user can change its password
+		wapi.POST(
+			"/password",
+			pwd.ChangePassword,
+			auditor.Record(web.ActionPasswordChange),
+			authz.Require(web.PermMetricsRead))
+	}
+
+	if apiKey != nil {
+		wapi.GET("/apikeys", apiKey.List, authz.Require(web.PermAdmin))
+		wapi.POST(
+			"/apikeys",
+			apiKey.Create,
+			auditor.Record(web.ActionAPIKeyCreate),
+			authz.Require(web.PermAdmin))
+		wapi.DELETE(
+			strings.Concat("/apikeys/:", web.APIKeyIDName),
+			apiKey.Revoke,
+			auditor.Record(web.ActionAPIKeyRevoke),
+			authz.Require(web.PermAdmin))
+	}
+
+	wapi.GET("/sessions", sessions.List, authz.Require(web.PermAdmin))
+	wapi.DELETE(
+		strings.Concat("/sessions/:", web.SessionIDName),
+		sessions.Terminate,
+		auditor.Record(web.ActionSessionTerminate),
+		authz.Require(web.PermAdmin))
 
-	wapi.POST("/sample", s.factory.WebHandler.Sample.Save)
-	wapi.POST("/predict", s.factory.WebHandler.Prediction.Predict)
+	wapi.GET("/audit", auditor.Query, authz.Require(web.PermAdmin))
+	wapi.GET("/audit/export", auditor.Export, authz.Require(web.PermAdmin))
 
-	wapi.GET("/ws", s.factory.WebHandler.WS.Register)
+	wapi.GET(
+		"/ws",
+		s.factory.WebHandler.WS.Register,
+		authz.Require(web.PermMetricsRead))
 
 	// Device API
 	mapi := s.factory.Webs.Group("/api/device")
@@ -24,12 +24,15 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/swpoolcontroller/internal"
 	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/internal/web"
+	"github.com/swpoolcontroller/pkg/iot"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestServer_Start(t *testing.T) {
 	t.Parallel()
 
-	f := internal.NewFactory()
+	f := internal.NewFactory(config.Default())
 	s := internal.NewServer(f)
 
 	go func() {
@@ -42,7 +45,7 @@ func TestServer_Start(t *testing.T) {
 func TestServer_Middleware(t *testing.T) {
 	t.Parallel()
 
-	f := internal.NewFactory()
+	f := internal.NewFactory(config.Default())
 	s := internal.NewServer(f)
 
 	s.Middleware()
@@ -53,22 +56,93 @@ func TestServer_Middleware(t *testing.T) {
 func TestServer_Route_Auth_Provider_Oauth2(t *testing.T) {
 	t.Parallel()
 
-	f := internal.NewFactory()
+	f := internal.NewFactory(config.Default())
 	f.Config.Auth.Provider = config.AuthProviderOauth2
 	s := internal.NewServer(f)
 
 	s.Route()
 
-	assert.Len(t, f.Webs.Router().Routes(), 14)
+	assert.Len(t, f.Webs.Router().Routes(), 28)
 }
 
 func TestServer_Route_Auth_Provider_Dev(t *testing.T) {
 	t.Parallel()
 
-	f := internal.NewFactory()
+	f := internal.NewFactory(config.Default())
 	s := internal.NewServer(f)
 
 	s.Route()
 
-	assert.Len(t, f.Webs.Router().Routes(), 12)
+	assert.Len(t, f.Webs.Router().Routes(), 28)
+}
+
+func TestServer_Route_APIKeys(t *testing.T) {
+	t.Parallel()
+
+	f := internal.NewFactory(config.Default())
+	f.WebHandler.APIKey = &web.APIKeyWeb{Log: f.Log}
+	s := internal.NewServer(f)
+
+	s.Route()
+
+	// The middleware of /api/web adds the not found routes of the group
+	assert.Len(t, f.Webs.Router().Routes(), 31)
+}
+
+func TestServer_Reload(t *testing.T) {
+	t.Parallel()
+
+	f := internal.NewFactory(config.Default())
+	s := internal.NewServer(f)
+
+	f.Hubt.Register()
+	f.Hub.Run()
+
+	defer f.Hub.Stop()
+
+	cnf := config.Default()
+	cnf.Level = 1
+	cnf.HeartbeatInterval = 60
+	cnf.TaskTime = 20
+	cnf.IOT.SampleUI = true
+	cnf.Internal.Port = 6000
+
+	pending, err := s.Reload(cnf)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"server.internal.port"}, pending)
+	assert.Equal(t, zapcore.WarnLevel, f.Level.Level())
+	assert.Equal(t, uint8(60), f.Config.HeartbeatInterval)
+	assert.Equal(t, 5000, f.Config.Internal.Port, "Not applied")
+	assert.True(t, f.WebHandler.Authz.Allowed(
+		web.Principal{Role: web.RoleAdmin},
+		web.PermSampleWrite))
+
+	// The pending changes are reported until the restart
+	again, err := s.Reload(cnf)
+
+	require.NoError(t, err)
+	assert.Equal(t, pending, again)
+}
+
+func TestServer_ReloadStopped(t *testing.T) {
+	t.Parallel()
+
+	f := internal.NewFactory(config.Default())
+	s := internal.NewServer(f)
+
+	f.Hubt.Register()
+	f.Hub.Run()
+	f.Hub.Stop()
+
+	cnf := config.Default()
+	cnf.Level = 1
+	cnf.HeartbeatInterval = 60
+
+	_, err := s.Reload(cnf)
+
+	require.ErrorIs(t, err, iot.ErrClosed)
+	assert.NotEqual(t, zapcore.WarnLevel, f.Level.Level(), "Not applied")
+	assert.Equal(t,
+		config.Default().HeartbeatInterval, f.Config.HeartbeatInterval)
 }
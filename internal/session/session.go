@@ -0,0 +1,238 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+// Package session keeps the web sessions on the server,
+// so they can be listed and terminated
+package session
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Session is a web session. Its id is the websocket client id
+type Session struct {
+	ID        string    `json:"id"`
+	User      string    `json:"user"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	Created   time.Time `json:"created"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// Expires is when the session ends unless it is renewed
+	Expires time.Time `json:"expires"`
+	// TokenID identifies the current session token to deny it
+	// when the session is terminated
+	TokenID string `json:"-"`
+	// RefreshID identifies the refresh token of the provider, so the
+	// session is only renewed with it. Without it, the session token
+	// renews the session
+	RefreshID string `json:"-"`
+}
+
+// renewal is the credential that renews the session
+func (s Session) renewal() string {
+	if s.RefreshID != "" {
+		return s.RefreshID
+	}
+
+	return s.TokenID
+}
+
+// Registry keeps the active sessions and the terminated ones.
+// The sessions are kept during the retention after they expire,
+// while they can be renewed. The terminated sessions are remembered
+// as long to reject their cookies, because they cannot be removed
+// from the browser. It is safe for concurrent use
+type Registry struct {
+	lock     sync.Mutex
+	sessions map[string]Session
+	// renewals are the session ids by their renewal credential
+	renewals   map[string]string
+	terminated map[string]time.Time
+	retention  time.Duration
+}
+
+// NewRegistry builds an empty registry. retention is how long
+// a session can be renewed and a terminated session is rejected
+// after it would have expired
+func NewRegistry(retention time.Duration) *Registry {
+	return &Registry{
+		sessions:   make(map[string]Session),
+		renewals:   make(map[string]string),
+		terminated: make(map[string]time.Time),
+		retention:  retention,
+	}
+}
+
+// Open registers the session or renews it. The creation time and,
+// if the new ones are unknown, the user and the refresh token
+// of a renewed session are kept
+func (r *Registry) Open(s Session) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.purge(time.Now())
+
+	if prev, ok := r.sessions[s.ID]; ok {
+		s.Created = prev.Created
+
+		if s.User == "" {
+			s.User = prev.User
+		}
+
+		// Not every provider rotates the refresh token
+		if s.RefreshID == "" {
+			s.RefreshID = prev.RefreshID
+		}
+
+		r.remove(prev)
+	}
+
+	r.sessions[s.ID] = s
+
+	if key := s.renewal(); key != "" {
+		r.renewals[key] = s.ID
+	}
+}
+
+// Touch updates the last time the session was seen
+func (r *Registry) Touch(id string, seen time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if s, ok := r.sessions[id]; ok {
+		s.LastSeen = seen
+		r.sessions[id] = s
+	}
+}
+
+// Close removes the session
+func (r *Registry) Close(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if s, ok := r.sessions[id]; ok {
+		r.remove(s)
+	}
+}
+
+// Get returns the session if it is active
+func (r *Registry) Get(id string) (Session, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s, ok := r.sessions[id]
+
+	return s, ok && s.Expires.After(time.Now())
+}
+
+// Renewal returns the session renewed with the credential while
+// it can be renewed. The terminated sessions are not found
+func (r *Registry) Renewal(key string) (Session, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	id, ok := r.renewals[key]
+	if !ok {
+		return Session{}, false
+	}
+
+	s, ok := r.sessions[id]
+
+	return s, ok && r.renewable(s, time.Now())
+}
+
+// List returns the sessions that are active or can be renewed,
+// the oldest first
+func (r *Registry) List() []Session {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.purge(time.Now())
+
+	list := make([]Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		list = append(list, s)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.Before(list[j].Created)
+	})
+
+	return list
+}
+
+// Terminate removes the active session and rejects it from now on.
+// It returns false if the session does not exist
+func (r *Registry) Terminate(id string) (Session, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	r.purge(now)
+
+	s, ok := r.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+
+	r.remove(s)
+	r.terminated[id] = s.Expires.Add(r.retention)
+
+	return s, true
+}
+
+// Terminated checks whether the session has been terminated
+func (r *Registry) Terminated(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	exp, ok := r.terminated[id]
+
+	return ok && exp.After(time.Now())
+}
+
+// purge removes the sessions that cannot be renewed
+// and the expired terminations
+func (r *Registry) purge(now time.Time) {
+	for _, s := range r.sessions {
+		if !r.renewable(s, now) {
+			r.remove(s)
+		}
+	}
+
+	for id, exp := range r.terminated {
+		if !exp.After(now) {
+			delete(r.terminated, id)
+		}
+	}
+}
+
+// renewable checks whether the session can still be renewed
+func (r *Registry) renewable(s Session, now time.Time) bool {
+	return s.Expires.Add(r.retention).After(now)
+}
+
+// remove deletes the session and its renewal credential
+func (r *Registry) remove(s Session) {
+	delete(r.sessions, s.ID)
+
+	if key := s.renewal(); r.renewals[key] == s.ID {
+		delete(r.renewals, key)
+	}
+}
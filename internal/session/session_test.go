@@ -0,0 +1,170 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package session_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/session"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := session.NewRegistry(time.Minute)
+	now := time.Now()
+
+	r.Open(session.Session{
+		ID:      "1",
+		User:    "alice",
+		Created: now,
+		Expires: now.Add(time.Minute),
+	})
+	r.Open(session.Session{
+		ID:      "2",
+		User:    "bob",
+		Created: now.Add(time.Second),
+		Expires: now.Add(time.Minute),
+	})
+	r.Open(session.Session{
+		ID:      "3",
+		Created: now,
+		Expires: now.Add(-time.Minute),
+	})
+
+	list := r.List()
+	require.Len(t, list, 2, "The session that cannot be renewed is purged")
+	assert.Equal(t, "1", list[0].ID)
+	assert.Equal(t, "2", list[1].ID)
+
+	// Renewal keeps the creation time and the user
+	r.Open(session.Session{
+		ID:      "1",
+		Created: now.Add(time.Hour),
+		Expires: now.Add(time.Hour),
+	})
+	r.Touch("1", now.Add(time.Second))
+
+	s, ok := r.Get("1")
+	require.True(t, ok)
+	assert.Equal(t, "alice", s.User)
+	assert.True(t, s.Created.Equal(now))
+	assert.True(t, s.LastSeen.Equal(now.Add(time.Second)))
+
+	r.Close("2")
+
+	_, ok = r.Get("2")
+	assert.False(t, ok)
+	assert.False(t, r.Terminated("2"), "Closed is not terminated")
+}
+
+func TestRegistry_Terminate(t *testing.T) {
+	t.Parallel()
+
+	r := session.NewRegistry(time.Minute)
+	now := time.Now()
+
+	r.Open(session.Session{
+		ID:      "1",
+		Created: now,
+		Expires: now.Add(time.Minute),
+	})
+
+	_, ok := r.Terminate("2")
+	assert.False(t, ok)
+
+	s, ok := r.Terminate("1")
+	require.True(t, ok)
+	assert.Equal(t, "1", s.ID)
+
+	assert.True(t, r.Terminated("1"))
+	assert.Empty(t, r.List())
+}
+
+func TestRegistry_Renewal(t *testing.T) {
+	t.Parallel()
+
+	r := session.NewRegistry(time.Minute)
+	now := time.Now()
+
+	r.Open(session.Session{
+		ID:        "1",
+		Expires:   now.Add(-30 * time.Second),
+		TokenID:   "token1",
+		RefreshID: "refresh1",
+	})
+	r.Open(session.Session{
+		ID:      "2",
+		Expires: now.Add(time.Minute),
+		TokenID: "token2",
+	})
+
+	s, ok := r.Renewal("refresh1")
+	require.True(t, ok, "Expired but renewable")
+	assert.Equal(t, "1", s.ID)
+
+	_, ok = r.Renewal("token1")
+	assert.False(t, ok, "The refresh token renews the session")
+
+	s, ok = r.Renewal("token2")
+	require.True(t, ok, "The session token renews the session")
+	assert.Equal(t, "2", s.ID)
+
+	// Renewal without rotation keeps the refresh token
+	r.Open(session.Session{
+		ID:      "1",
+		Expires: now.Add(time.Minute),
+		TokenID: "token3",
+	})
+
+	_, ok = r.Renewal("refresh1")
+	assert.True(t, ok)
+
+	// Rotation replaces the session token
+	r.Open(session.Session{
+		ID:      "2",
+		Expires: now.Add(time.Minute),
+		TokenID: "token4",
+	})
+
+	_, ok = r.Renewal("token2")
+	assert.False(t, ok, "The previous session token")
+
+	_, ok = r.Terminate("1")
+	require.True(t, ok)
+
+	_, ok = r.Renewal("refresh1")
+	assert.False(t, ok, "The terminated session")
+
+	r.Close("2")
+
+	_, ok = r.Renewal("token4")
+	assert.False(t, ok, "The closed session")
+
+	r.Open(session.Session{
+		ID:        "3",
+		Expires:   now.Add(-2 * time.Minute),
+		RefreshID: "refresh3",
+	})
+
+	_, ok = r.Renewal("refresh3")
+	assert.False(t, ok, "Beyond the retention")
+}
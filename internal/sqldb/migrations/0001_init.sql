@@ -0,0 +1,30 @@
+-- Revisions of the micro controller configuration.
+-- The current configuration is the latest revision
+CREATE TABLE config_revisions (
+	rev INTEGER PRIMARY KEY,
+	author TEXT NOT NULL,
+	saved TEXT NOT NULL,
+	config TEXT NOT NULL
+);
+
+-- Samples of the state of the water judged by the expert
+CREATE TABLE samples (
+	id TEXT PRIMARY KEY,
+	temp TEXT NOT NULL,
+	ph TEXT NOT NULL,
+	orp TEXT NOT NULL,
+	chlorine TEXT NOT NULL,
+	quality TEXT NOT NULL
+);
+
+-- Audit log. The entry is the json of the audit entry and the columns
+-- are kept to filter it
+CREATE TABLE audit (
+	id TEXT PRIMARY KEY,
+	time TEXT NOT NULL,
+	action TEXT NOT NULL,
+	user TEXT NOT NULL,
+	entry TEXT NOT NULL
+);
+
+CREATE INDEX audit_time ON audit (time);
@@ -0,0 +1,35 @@
+-- The samples store the readings and the chlorine as numbers and the
+-- quality as its number (0 bad, 1 regular, 2 good). Taken is when the
+-- hub received the readings, empty for the samples saved before.
+-- The table is strict, so a value that is not a number or a quality
+-- fails the migration instead of being stored as 0 in the training data
+CREATE TABLE samples_typed (
+	id TEXT PRIMARY KEY,
+	temp REAL NOT NULL,
+	ph REAL NOT NULL,
+	orp REAL NOT NULL,
+	chlorine REAL NOT NULL,
+	quality INTEGER NOT NULL CHECK (quality BETWEEN 0 AND 2),
+	taken TEXT NOT NULL DEFAULT ''
+) STRICT;
+
+-- The order of the rowids keeps the order the samples were saved
+INSERT INTO samples_typed (id, temp, ph, orp, chlorine, quality)
+SELECT
+	id,
+	temp,
+	ph,
+	orp,
+	chlorine,
+	CASE quality
+		WHEN 'bad' THEN 0
+		WHEN 'regular' THEN 1
+		WHEN 'good' THEN 2
+		ELSE quality
+	END
+FROM samples
+ORDER BY rowid;
+
+DROP TABLE samples;
+
+ALTER TABLE samples_typed RENAME TO samples;
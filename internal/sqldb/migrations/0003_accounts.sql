@@ -0,0 +1,17 @@
+-- Local users. The hash includes the algorithm and the salt
+CREATE TABLE users (
+	username TEXT PRIMARY KEY,
+	hash TEXT NOT NULL,
+	role TEXT NOT NULL,
+	totp_secret TEXT NOT NULL DEFAULT ''
+);
+
+-- Api keys of the third-party integrations. Only the hash of the key
+-- is stored and the scopes are a json array
+CREATE TABLE api_keys (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	hash TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	created TEXT NOT NULL
+);
@@ -0,0 +1,210 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+// Package sqldb opens the embedded SQLite database of the sql data provider
+// and keeps its schema up to date with the numbered migrations
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	strs "github.com/swpoolcontroller/pkg/strings"
+
+	// registers the pure go sqlite driver
+	_ "modernc.org/sqlite"
+)
+
+const (
+	errOpen          = "Opening the sql database: "
+	errMigrationName = "The migration name must start with its version: "
+	errReadMigration = "Reading the migration: "
+	errMigrate       = "Applying the migration: "
+	errVersion       = "Reading the schema version"
+)
+
+// driver is the name of the sqlite driver
+const driver = "sqlite"
+
+// pragmas are applied on every connection. The busy timeout waits
+// for the lock instead of failing while another writer holds it
+const pragmas = "?_pragma=foreign_keys(1)" +
+	"&_pragma=busy_timeout(5000)" +
+	"&_pragma=journal_mode(WAL)"
+
+// migrationsTable keeps the applied versions
+const migrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied TEXT NOT NULL
+)`
+
+// Migrations are the sql scripts that create the schema. Their names
+// are <version>_<description>.sql and they are applied in version order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// migration is a sql script of the schema
+type migration struct {
+	version int
+	name    string
+	script  string
+}
+
+// Open opens the database file, creating it if it does not exist,
+// and applies the pending migrations
+func Open(file string) (*sql.DB, error) {
+	db, err := sql.Open(driver, strs.Concat("file:", file, pragmas))
+	if err != nil {
+		return nil, errors.Wrap(err, strs.Concat(errOpen, file))
+	}
+
+	// sqlite allows a single writer. One connection serializes the
+	// transactions of the process instead of failing with busy errors
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+
+		return nil, errors.Wrap(err, strs.Concat(errOpen, file))
+	}
+
+	if err := Migrate(db, Migrations); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Migrate applies the migrations of the folder migrations of fsys
+// with a version greater than the current one, each in a transaction
+func Migrate(db *sql.DB, fsys fs.FS) error {
+	if _, err := db.Exec(migrationsTable); err != nil {
+		return errors.Wrap(err, errMigrate)
+	}
+
+	migrations, err := readMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	current, err := Version(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := apply(db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Version reads the version of the latest applied migration,
+// 0 if there is none
+func Version(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+
+	if err := db.QueryRow(
+		"SELECT MAX(version) FROM schema_migrations").
+		Scan(&version); err != nil {
+		//
+		return 0, errors.Wrap(err, errVersion)
+	}
+
+	return int(version.Int64), nil
+}
+
+func apply(db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return errors.Wrap(err, strs.Concat(errMigrate, m.name))
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(m.script); err != nil {
+		return errors.Wrap(err, strs.Concat(errMigrate, m.name))
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (version, name, applied) "+
+			"VALUES (?, ?, ?)",
+		m.version,
+		m.name,
+		time.Now().UTC().Format(time.RFC3339Nano)); err != nil {
+		//
+		return errors.Wrap(err, strs.Concat(errMigrate, m.name))
+	}
+
+	return errors.Wrap(tx.Commit(), strs.Concat(errMigrate, m.name))
+}
+
+// readMigrations reads the scripts sorted by version
+func readMigrations(fsys fs.FS) ([]migration, error) {
+	names, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, errors.Wrap(err, errReadMigration)
+	}
+
+	migrations := make([]migration, 0, len(names))
+
+	for _, name := range names {
+		base := path.Base(name)
+
+		prefix, _, _ := strings.Cut(base, "_")
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version < 1 {
+			return nil, errors.New(strs.Concat(errMigrationName, base))
+		}
+
+		script, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, errors.Wrap(err, strs.Concat(errReadMigration, base))
+		}
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    base,
+			script:  string(script),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
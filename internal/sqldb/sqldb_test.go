@@ -0,0 +1,244 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package sqldb_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/sqldb"
+)
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "swpc.db")
+
+	db, err := sqldb.Open(file)
+	require.NoError(t, err)
+
+	version, err := sqldb.Version(db)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version, "Latest schema")
+
+	_, err = db.Exec("INSERT INTO samples " +
+		"(id, temp, ph, orp, chlorine, quality) " +
+		"VALUES ('1', 24, 7.2, 650, 1, 2)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = sqldb.Open(file)
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	var count int
+
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM samples").
+		Scan(&count))
+	assert.Equal(t, 1, count, "The data is kept when it is opened again")
+}
+
+func TestMigrate_TypedSamples(t *testing.T) {
+	t.Parallel()
+
+	initSQL, err := sqldb.Migrations.ReadFile("migrations/0001_init.sql")
+	require.NoError(t, err)
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "swpc.db"))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	require.NoError(t, sqldb.Migrate(db, fstest.MapFS{
+		"migrations/0001_init.sql": {Data: initSQL},
+	}))
+
+	_, err = db.Exec("INSERT INTO samples " +
+		"(id, temp, ph, orp, chlorine, quality) VALUES " +
+		"('b', '24.5', '7.2', '650', '1.5', '2'), " +
+		"('a', '26', '7', '700', '0', 'regular')")
+	require.NoError(t, err)
+
+	require.NoError(t, sqldb.Migrate(db, sqldb.Migrations))
+
+	rows, err := db.Query("SELECT id, temp, chlorine, quality, taken " +
+		"FROM samples ORDER BY rowid")
+	require.NoError(t, err)
+
+	defer rows.Close()
+
+	type sample struct {
+		id       string
+		temp     float64
+		chlorine float64
+		quality  int
+		taken    string
+	}
+
+	var samples []sample
+
+	for rows.Next() {
+		var s sample
+
+		require.NoError(t, rows.Scan(
+			&s.id, &s.temp, &s.chlorine, &s.quality, &s.taken))
+
+		samples = append(samples, s)
+	}
+
+	require.NoError(t, rows.Err())
+	assert.Equal(
+		t,
+		[]sample{{"b", 24.5, 1.5, 2, ""}, {"a", 26, 0, 1, ""}},
+		samples,
+		"The values are numbers in the order they were saved")
+}
+
+func TestMigrate_TypedSamplesInvalid(t *testing.T) {
+	t.Parallel()
+
+	initSQL, err := sqldb.Migrations.ReadFile("migrations/0001_init.sql")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		sample string
+		err    string
+	}{
+		{
+			name:   "Reading. It should fail if it is not a number",
+			sample: "('a', 'warm', '7.2', '650', '1.5', 'good')",
+			err:    "samples_typed.temp",
+		},
+		{
+			name:   "Chlorine. It should fail if it is empty",
+			sample: "('a', '24', '7.2', '650', '', 'good')",
+			err:    "samples_typed.chlorine",
+		},
+		{
+			name:   "Quality. It should fail if it is unknown",
+			sample: "('a', '24', '7.2', '650', '1.5', 'great')",
+			err:    "samples_typed.quality",
+		},
+		{
+			name:   "Quality. It should fail if it is out of range",
+			sample: "('a', '24', '7.2', '650', '1.5', '5')",
+			err:    "CHECK constraint failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "swpc.db"))
+			require.NoError(t, err)
+
+			defer db.Close()
+
+			require.NoError(t, sqldb.Migrate(db, fstest.MapFS{
+				"migrations/0001_init.sql": {Data: initSQL},
+			}))
+
+			_, err = db.Exec("INSERT INTO samples " +
+				"(id, temp, ph, orp, chlorine, quality) VALUES " + tt.sample)
+			require.NoError(t, err)
+
+			err = sqldb.Migrate(db, sqldb.Migrations)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+
+			version, err := sqldb.Version(db)
+
+			require.NoError(t, err)
+			assert.Equal(t, 1, version, "The migration is rolled back")
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		version int
+		err     string
+	}{
+		{
+			name: "Pending migrations. It should apply them in version order",
+			fsys: fstest.MapFS{
+				"migrations/0002_b.sql": {Data: []byte(
+					"ALTER TABLE a ADD COLUMN b TEXT")},
+				"migrations/0001_a.sql": {Data: []byte(
+					"CREATE TABLE a (id INTEGER PRIMARY KEY)")},
+			},
+			version: 2,
+		},
+		{
+			name: "Without version. It should fail",
+			fsys: fstest.MapFS{
+				"migrations/init.sql": {Data: []byte("SELECT 1")},
+			},
+			err: "The migration name must start with its version",
+		},
+		{
+			name: "Wrong script. It should fail and keep the version",
+			fsys: fstest.MapFS{
+				"migrations/0001_a.sql": {Data: []byte("CREATE TABLE")},
+			},
+			err: "Applying the migration: 0001_a.sql",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, err := sqldb.Open(filepath.Join(t.TempDir(), "swpc.db"))
+			require.NoError(t, err)
+
+			defer db.Close()
+
+			_, err = db.Exec("DELETE FROM schema_migrations")
+			require.NoError(t, err)
+
+			err = sqldb.Migrate(db, tt.fsys)
+
+			version, verr := sqldb.Version(db)
+			require.NoError(t, verr)
+
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				assert.Equal(t, 0, version, "Version")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, version, "Version")
+
+			require.NoError(t, sqldb.Migrate(db, tt.fsys), "Applied once")
+		})
+	}
+}
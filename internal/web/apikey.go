@@ -0,0 +1,224 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/account"
+	"go.uber.org/zap"
+)
+
+const (
+	errAPIKeyRequest = "APIKey. Getting the api key of the request body"
+	errAPIKeyCreate  = "APIKey. Creating the api key"
+	errAPIKeyList    = "APIKey. Listing the api keys"
+	errAPIKeyRevoke  = "APIKey. Revoking the api key"
+	errAPIKeyAuth    = "APIKey. Authenticating the api key"
+)
+
+const (
+	infAPIKeyCreated = "APIKey. The api key has been created"
+	infAPIKeyRevoked = "APIKey. The api key has been revoked"
+)
+
+const (
+	// APIKeyIDName is the param name of the api key id
+	APIKeyIDName = "id"
+	// bearerPrefix is the scheme of the Authorization header
+	bearerPrefix = "Bearer "
+)
+
+// APIKeyManager manages the api keys of the third-party integrations
+type APIKeyManager interface {
+	Create(name string, scopes []string) (string, account.APIKey, error)
+	List() ([]account.APIKey, error)
+	Revoke(id string) error
+	Authenticate(raw string) (account.APIKey, error)
+}
+
+// apiKeyRequestDTO is the request to create an api key
+type apiKeyRequestDTO struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// apiKeyDTO is an api key without its hash.
+// Key is only sent once, when the api key is created
+type apiKeyDTO struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Scopes  []string  `json:"scopes"`
+	Created time.Time `json:"created"`
+	Key     string    `json:"key,omitempty"`
+}
+
+// APIKeyWeb authenticates the api keys sent as bearer
+// and manages them through the admin endpoints
+type APIKeyWeb struct {
+	Log  *zap.Logger
+	Keys APIKeyManager
+}
+
+// Middleware authenticates the requests with an api key in the
+// Authorization header. Requests without api key continue to the
+// session authentication
+func (a *APIKeyWeb) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			raw, ok := bearerAPIKey(ctx.Request().Header.Get(AuthHeaderName))
+			if !ok {
+				return next(ctx)
+			}
+
+			k, err := a.Keys.Authenticate(raw)
+			if err != nil {
+				if errors.Is(err, account.ErrInvalidAPIKey) {
+					a.Log.Warn(errAPIKeyAuth, zap.String("Path", ctx.Path()))
+				} else {
+					a.Log.Error(errAPIKeyAuth, zap.Error(err))
+				}
+
+				return ctx.NoContent(http.StatusUnauthorized)
+			}
+
+			scopes := make([]Permission, 0, len(k.Scopes))
+			for _, s := range k.Scopes {
+				scopes = append(scopes, Permission(s))
+			}
+
+			ctx.Set(PrincipalKey, Principal{
+				Subject: "apikey:" + k.ID,
+				Name:    k.Name,
+				Role:    RoleNone,
+				Scopes:  scopes,
+			})
+
+			return next(ctx)
+		}
+	}
+}
+
+// Authenticated checks whether the request has been authenticated
+// by an api key, so the session authentication can be skipped
+func (a *APIKeyWeb) Authenticated(ctx echo.Context) bool {
+	p, ok := ctx.Get(PrincipalKey).(Principal)
+
+	return ok && p.Scopes != nil
+}
+
+// Create creates an api key. The key is only returned in this response
+func (a *APIKeyWeb) Create(ctx echo.Context) error {
+	var req apiKeyRequestDTO
+
+	if err := ctx.Bind(&req); err != nil {
+		a.Log.Error(errAPIKeyRequest, zap.Error(err))
+
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	raw, k, err := a.Keys.Create(req.Name, req.Scopes)
+	if err != nil {
+		if errors.Is(err, account.ErrAPIKeyName) ||
+			errors.Is(err, account.ErrScope) {
+			//
+			a.Log.Warn(errAPIKeyCreate, zap.Error(err))
+
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+
+		a.Log.Error(errAPIKeyCreate, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	a.Log.Info(
+		infAPIKeyCreated,
+		zap.String("ID", k.ID),
+		zap.String("Name", k.Name),
+		zap.Strings("Scopes", k.Scopes))
+
+	res := toAPIKeyDTO(k)
+	res.Key = raw
+
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+// List lists the api keys
+func (a *APIKeyWeb) List(ctx echo.Context) error {
+	keys, err := a.Keys.List()
+	if err != nil {
+		a.Log.Error(errAPIKeyList, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	res := make([]apiKeyDTO, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, toAPIKeyDTO(k))
+	}
+
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// Revoke revokes the api key of the path
+func (a *APIKeyWeb) Revoke(ctx echo.Context) error {
+	id := ctx.Param(APIKeyIDName)
+
+	if err := a.Keys.Revoke(id); err != nil {
+		if errors.Is(err, account.ErrAPIKeyNotFound) {
+			return ctx.NoContent(http.StatusNotFound)
+		}
+
+		a.Log.Error(errAPIKeyRevoke, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	a.Log.Info(infAPIKeyRevoked, zap.String("ID", id))
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func toAPIKeyDTO(k account.APIKey) apiKeyDTO {
+	return apiKeyDTO{
+		ID:      k.ID,
+		Name:    k.Name,
+		Scopes:  k.Scopes,
+		Created: k.Created,
+	}
+}
+
+// bearerAPIKey gets the api key of the Authorization header.
+// Bearer tokens that are not api keys are ignored
+func bearerAPIKey(header string) (string, bool) {
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return "", false
+	}
+
+	raw := strings.TrimSpace(header[len(bearerPrefix):])
+	if !strings.HasPrefix(raw, account.APIKeyPrefix) {
+		return "", false
+	}
+
+	return raw, true
+}
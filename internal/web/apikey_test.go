@@ -0,0 +1,226 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/account"
+	"github.com/swpoolcontroller/internal/web"
+	"github.com/swpoolcontroller/internal/web/mocks"
+	"go.uber.org/zap"
+)
+
+func TestAPIKeyWeb_Middleware(t *testing.T) {
+	t.Parallel()
+
+	key := account.APIKey{
+		ID:     "1",
+		Name:   "facility",
+		Scopes: []string{"metrics:read"},
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		perm   web.Permission
+		status int
+	}{
+		{
+			name:   "Without header. It should continue to the session auth",
+			header: "",
+			perm:   web.PermMetricsRead,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Bearer that is not an api key. It should be ignored",
+			header: "Bearer token",
+			perm:   web.PermMetricsRead,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Valid api key in scope. It should return StatusOK",
+			header: "Bearer swpc_1.valid",
+			perm:   web.PermMetricsRead,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Valid api key out of scope. It should return StatusForbidden",
+			header: "Bearer swpc_1.valid",
+			perm:   web.PermConfigRead,
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "Invalid api key. It should return StatusUnauthorized",
+			header: "Bearer swpc_1.invalid",
+			perm:   web.PermMetricsRead,
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			keys := &mocks.APIKeyManager{}
+			keys.On("Authenticate", "swpc_1.valid").Return(key, nil)
+			keys.On("Authenticate", "swpc_1.invalid").
+				Return(account.APIKey{}, account.ErrInvalidAPIKey)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/web/predict", nil)
+			req.Header.Set(echo.HeaderAuthorization, tt.header)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+
+			a := &web.APIKeyWeb{Log: zap.NewExample(), Keys: keys}
+			authz := &web.Authorizer{
+				Log:    zap.NewExample(),
+				Config: oauth2Config(),
+			}
+
+			h := a.Middleware()(authz.Require(tt.perm)(
+				func(c echo.Context) error {
+					return c.NoContent(http.StatusOK)
+				}))
+
+			_ = h(ctx)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(
+				t,
+				tt.header == "Bearer swpc_1.valid",
+				a.Authenticated(ctx))
+		})
+	}
+}
+
+func TestAPIKeyWeb_Create(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{
+			name:   "Valid request. It should return StatusCreated",
+			body:   `{"name":"facility","scopes":["metrics:read"]}`,
+			err:    nil,
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Unknown scope. It should return StatusBadRequest",
+			body:   `{"name":"facility","scopes":["admin"]}`,
+			err:    account.ErrScope,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Repository error. It should return StatusInternalServerError",
+			body:   `{"name":"facility","scopes":["metrics:read"]}`,
+			err:    errors.New("error"),
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			keys := &mocks.APIKeyManager{}
+			keys.On("Create", "facility", mock.Anything).
+				Return("swpc_1.secret", account.APIKey{ID: "1"}, tt.err)
+
+			e := echo.New()
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/api/web/apikeys",
+				strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+
+			a := &web.APIKeyWeb{Log: zap.NewExample(), Keys: keys}
+
+			_ = a.Create(ctx)
+
+			assert.Equal(t, tt.status, rec.Code)
+
+			if tt.status == http.StatusCreated {
+				var res map[string]interface{}
+
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				assert.Equal(t, "swpc_1.secret", res["key"])
+				assert.NotContains(t, res, "hash")
+			}
+		})
+	}
+}
+
+func TestAPIKeyWeb_Revoke(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{
+			name:   "Existing key. It should return StatusNoContent",
+			err:    nil,
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "Unknown key. It should return StatusNotFound",
+			err:    account.ErrAPIKeyNotFound,
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			keys := &mocks.APIKeyManager{}
+			keys.On("Revoke", "1").Return(tt.err)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/api/web/apikeys/1", nil)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames(web.APIKeyIDName)
+			ctx.SetParamValues("1")
+
+			a := &web.APIKeyWeb{Log: zap.NewExample(), Keys: keys}
+
+			_ = a.Revoke(ctx)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
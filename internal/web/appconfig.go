@@ -22,7 +22,7 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
-	"github.com/rs/xid"
+	"github.com/pkg/errors"
 	"github.com/swpoolcontroller/internal/config"
 	"github.com/swpoolcontroller/pkg/auth"
 	"go.uber.org/zap"
@@ -35,6 +35,7 @@ const (
 
 const (
 	infLoadConfig = "Load app config"
+	warnStates    = "Too many pending login states"
 )
 
 type configDTO struct {
@@ -43,6 +44,14 @@ type configDTO struct {
 	CheckAuthName string `json:"checkAuthName"`
 	IOTConfig     bool   `json:"iotConfig"`
 	AISample      bool   `json:"aiSample"`
+	// Permissions are the effective permissions of the user
+	Permissions []Permission `json:"permissions"`
+	// RefreshInterval defines in seconds how often the UI renews
+	// the session. 0 disables the renewal
+	RefreshInterval int `json:"refreshInterval"`
+	// ChangePassword enables the change of password in the UI.
+	// Only for local accounts
+	ChangePassword bool `json:"changePassword,omitempty"`
 }
 
 type AppConfigurator interface {
@@ -54,19 +63,28 @@ type AppConfigurator interface {
 type AppConfig struct {
 	Log    *zap.Logger
 	Config config.Config
+	Authz  *Authorizer
+	// States issues the state of the login URL
+	States StateStore
 }
 
 // Load loads the app configuration
 func (c *AppConfig) Load(ctx echo.Context) error {
-	state := xid.New().String()
+	statec, err := c.States.Issue()
+	if errors.Is(err, auth.ErrTooManyStates) {
+		c.Log.Warn(warnStates)
+
+		return ctx.NoContent(http.StatusServiceUnavailable)
+	}
 
-	statec, err := auth.EncodeState([]byte(c.Config.Web.SecretKey), []byte(state))
 	if err != nil {
 		c.Log.Error(errEncodeState, zap.Error(err))
 
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
+	perms := permissions(ctx, c.Authz)
+
 	config := configDTO{
 		AuthLoginURL: auth.Oauth2URL(
 			c.Config.Auth.LoginURL,
@@ -78,9 +96,83 @@ func (c *AppConfig) Load(ctx echo.Context) error {
 			c.Config.Auth.ClientID,
 			c.Config.AuthRedirectURI(RedirectLogout),
 			""),
-		CheckAuthName: AuthCheckName,
-		IOTConfig:     c.Config.IOT.ConfigUI,
-		AISample:      c.Config.IOT.SampleUI,
+		CheckAuthName:   AuthCheckName,
+		IOTConfig:       hasPermission(perms, PermConfigWrite),
+		AISample:        hasPermission(perms, PermSampleWrite),
+		Permissions:     perms,
+		RefreshInterval: refreshInterval(c.Config),
+	}
+
+	sconfig, err := json.Marshal(config)
+	if err != nil {
+		c.Log.Error(errMarshalConfig, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	c.Log.Info(infLoadConfig, zap.String("AppConfig", string(sconfig)))
+
+	return ctx.JSON(http.StatusOK, config)
+}
+
+// AppConfigOIDC loads the app config to the UI for OpenID Connect
+// providers. The login starts in the server to generate
+// the PKCE verifier and the nonce
+type AppConfigOIDC struct {
+	Log     *zap.Logger
+	Config  config.Config
+	Authz   *Authorizer
+	Service OIDCService
+}
+
+// Load loads the app configuration
+func (c *AppConfigOIDC) Load(ctx echo.Context) error {
+	perms := permissions(ctx, c.Authz)
+
+	config := configDTO{
+		AuthLoginURL: RedirectSignIn,
+		AuthLogoutURL: c.Service.LogoutURL(
+			c.Config.AuthRedirectURI(RedirectLogout)),
+		CheckAuthName:   AuthCheckName,
+		IOTConfig:       hasPermission(perms, PermConfigWrite),
+		AISample:        hasPermission(perms, PermSampleWrite),
+		Permissions:     perms,
+		RefreshInterval: refreshInterval(c.Config),
+	}
+
+	sconfig, err := json.Marshal(config)
+	if err != nil {
+		c.Log.Error(errMarshalConfig, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	c.Log.Info(infLoadConfig, zap.String("AppConfig", string(sconfig)))
+
+	return ctx.JSON(http.StatusOK, config)
+}
+
+// AppConfigLocal loads the app config to the UI for local accounts.
+// The login is a form of the UI
+type AppConfigLocal struct {
+	Log    *zap.Logger
+	Config config.Config
+	Authz  *Authorizer
+}
+
+// Load loads the app configuration
+func (c *AppConfigLocal) Load(ctx echo.Context) error {
+	perms := permissions(ctx, c.Authz)
+
+	config := configDTO{
+		AuthLoginURL:    RedirectLocalLogin,
+		AuthLogoutURL:   RedirectLogout,
+		CheckAuthName:   AuthCheckName,
+		IOTConfig:       hasPermission(perms, PermConfigWrite),
+		AISample:        hasPermission(perms, PermSampleWrite),
+		Permissions:     perms,
+		RefreshInterval: refreshInterval(c.Config),
+		ChangePassword:  true,
 	}
 
 	sconfig, err := json.Marshal(config)
@@ -99,17 +191,22 @@ func (c *AppConfig) Load(ctx echo.Context) error {
 type AppConfigDev struct {
 	Log    *zap.Logger
 	Config config.Config
+	Authz  *Authorizer
 }
 
 // Load loads the app configuration setting up authentication
 // in development mode
 func (c *AppConfigDev) Load(ctx echo.Context) error {
+	perms := permissions(ctx, c.Authz)
+
 	config := configDTO{
-		AuthLoginURL:  RedirectLogin,
-		AuthLogoutURL: RedirectLogout,
-		CheckAuthName: AuthCheckName,
-		IOTConfig:     c.Config.IOT.ConfigUI,
-		AISample:      c.Config.IOT.SampleUI,
+		AuthLoginURL:    RedirectLogin,
+		AuthLogoutURL:   RedirectLogout,
+		CheckAuthName:   AuthCheckName,
+		IOTConfig:       hasPermission(perms, PermConfigWrite),
+		AISample:        hasPermission(perms, PermSampleWrite),
+		Permissions:     perms,
+		RefreshInterval: refreshInterval(c.Config),
 	}
 
 	sconfig, err := json.Marshal(config)
@@ -123,3 +220,33 @@ func (c *AppConfigDev) Load(ctx echo.Context) error {
 
 	return ctx.JSON(http.StatusOK, config)
 }
+
+// refreshInterval renews the session at half of its expiration
+func refreshInterval(cnf config.Config) int {
+	if cnf.Web.RefreshExpiration <= 0 {
+		return 0
+	}
+
+	return cnf.Web.SessionExpiration * 60 / 2 //nolint:gomnd
+}
+
+// permissions returns the effective permissions of the user of the request.
+// If the user is not authenticated, it has no permissions
+func permissions(ctx echo.Context, authz *Authorizer) []Permission {
+	p, ok := authz.Principal(ctx)
+	if !ok {
+		return []Permission{}
+	}
+
+	return authz.Permissions(p)
+}
+
+func hasPermission(perms []Permission, perm Permission) bool {
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+
+	return false
+}
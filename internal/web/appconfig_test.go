@@ -20,12 +20,16 @@ package web_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/swpoolcontroller/internal/config"
 	"github.com/swpoolcontroller/internal/web"
+	"github.com/swpoolcontroller/pkg/auth"
 	"go.uber.org/zap"
 )
 
@@ -59,7 +63,8 @@ func TestAppConfig_Load(t *testing.T) {
 				status: http.StatusOK,
 				body: "{\"authLoginUrl\":\"\",\"authLogoutUrl\":\"\"," +
 					"\"checkAuthName\":\"IsAuth\"," +
-					"\"iotConfig\":true,\"aiSample\":false}\n",
+					"\"iotConfig\":false,\"aiSample\":false," +
+					"\"permissions\":[],\"refreshInterval\":300}\n",
 			},
 		},
 	}
@@ -73,9 +78,18 @@ func TestAppConfig_Load(t *testing.T) {
 			rec := httptest.NewRecorder()
 			ctx := e.NewContext(req, rec)
 
+			cnf := tt.argConfig
+			cnf.Auth.Provider = config.AuthProviderOauth2
+
 			ac := web.AppConfig{
 				Log:    zap.NewExample(),
 				Config: tt.argConfig,
+				Authz:  &web.Authorizer{Log: zap.NewExample(), Config: cnf},
+				States: &auth.StateIssuer{
+					Key:    []byte(tt.argConfig.Web.SecretKey),
+					TTL:    time.Minute,
+					Nonces: auth.NewNonceStore(),
+				},
 			}
 
 			_ = ac.Load(ctx)
@@ -86,6 +100,40 @@ func TestAppConfig_Load(t *testing.T) {
 	}
 }
 
+func TestAppConfig_LoadTooManyStates(t *testing.T) {
+	t.Parallel()
+
+	cnf := config.Default()
+	nonces := auth.NewNonceStore()
+
+	// The pending states of the unauthenticated clients are limited
+	for i := 0; ; i++ {
+		err := nonces.Add(strconv.Itoa(i), time.Now().Add(time.Minute))
+		if err != nil {
+			require.ErrorIs(t, err, auth.ErrTooManyStates)
+
+			break
+		}
+	}
+
+	ac := web.AppConfig{
+		Log:    zap.NewExample(),
+		Config: cnf,
+		Authz:  &web.Authorizer{Log: zap.NewExample(), Config: cnf},
+		States: &auth.StateIssuer{
+			Key:    []byte(cnf.Web.SecretKey),
+			TTL:    time.Minute,
+			Nonces: nonces,
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/appconfig", nil)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, ac.Load(echo.New().NewContext(req, rec)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
 func TestAppConfigDev_Load(t *testing.T) {
 	t.Parallel()
 
@@ -106,7 +154,10 @@ func TestAppConfigDev_Load(t *testing.T) {
 				status: http.StatusOK,
 				body: "{\"authLoginUrl\":\"/auth/login\"," +
 					"\"authLogoutUrl\":\"/auth/logout\",\"checkAuthName\":\"IsAuth\"," +
-					"\"iotConfig\":true,\"aiSample\":false}\n",
+					"\"iotConfig\":true,\"aiSample\":false," +
+					"\"permissions\":[\"metrics:read\",\"config:read\"," +
+					"\"config:write\",\"sample:read\",\"admin\"]," +
+					"\"refreshInterval\":300}\n",
 			},
 		},
 	}
@@ -123,6 +174,10 @@ func TestAppConfigDev_Load(t *testing.T) {
 			ac := web.AppConfigDev{
 				Log:    zap.NewExample(),
 				Config: tt.argConfig,
+				Authz: &web.Authorizer{
+					Log:    zap.NewExample(),
+					Config: tt.argConfig,
+				},
 			}
 
 			_ = ac.Load(ctx)
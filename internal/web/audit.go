@@ -0,0 +1,242 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+	"github.com/swpoolcontroller/internal/audit"
+	"go.uber.org/zap"
+)
+
+const (
+	errAuditAppend = "Audit. Appending the entry"
+	errAuditQuery  = "Audit. Querying the entries"
+	errAuditFilter = "Audit. Parsing the filter of the query"
+	errAuditExport = "Audit. Exporting the entries"
+)
+
+const (
+	// AuditUserKey is the key of the echo context where the login
+	// handlers store the user, because there is no session yet
+	AuditUserKey = "audit.user"
+	// AuditChangesKey is the key of the echo context where the handlers
+	// store the changes of the audited action
+	AuditChangesKey = "audit.changes"
+)
+
+// Audited actions
+const (
+	ActionLogin            = "auth.login"
+	ActionLogout           = "auth.logout"
+	ActionConfigSave       = "config.save"
+	ActionConfigRollback   = "config.rollback"
+	ActionSampleSave       = "sample.save"
+	ActionSampleDelete     = "sample.delete"
+	ActionPasswordChange   = "password.change"
+	ActionAPIKeyCreate     = "apikey.create"
+	ActionAPIKeyRevoke     = "apikey.revoke"
+	ActionSessionTerminate = "session.terminate"
+)
+
+// Query params of the audit API
+const (
+	auditFromName   = "from"
+	auditToName     = "to"
+	auditUserName   = "user"
+	auditActionName = "action"
+	auditLimitName  = "limit"
+	auditFormatName = "format"
+	auditFormatCSV  = "csv"
+)
+
+// Auditor records the mutating calls, the logins and the logouts
+// into the append-only audit log and queries it
+type Auditor struct {
+	Log   *zap.Logger
+	Repo  audit.Repo
+	Authz *Authorizer
+}
+
+// Record returns a middleware that appends an entry of the action
+// once the request has been handled, whether it succeeds or fails
+func (a *Auditor) Record(action string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			err := next(ctx)
+
+			status := ctx.Response().Status
+
+			var herr *echo.HTTPError
+			if errors.As(err, &herr) {
+				status = herr.Code
+			}
+
+			entry := audit.Entry{
+				ID:     xid.New().String(),
+				Time:   time.Now().UTC(),
+				Action: action,
+				Result: auditResult(ctx, status),
+				Status: status,
+				IP:     ctx.RealIP(),
+				Method: ctx.Request().Method,
+				Path:   ctx.Request().URL.Path,
+			}
+
+			a.identify(ctx, &entry)
+
+			if changes, ok := ctx.Get(AuditChangesKey).([]audit.Change); ok {
+				entry.Changes = changes
+			}
+
+			if errA := a.Repo.Append(entry); errA != nil {
+				a.Log.Error(
+					errAuditAppend,
+					zap.String("Action", action),
+					zap.Error(errA))
+			}
+
+			return err
+		}
+	}
+}
+
+// Query returns the entries of the filter as json
+func (a *Auditor) Query(ctx echo.Context) error {
+	entries, status := a.query(ctx)
+	if status != http.StatusOK {
+		return ctx.NoContent(status)
+	}
+
+	return ctx.JSON(http.StatusOK, entries)
+}
+
+// Export downloads the entries of the filter as json or csv
+func (a *Auditor) Export(ctx echo.Context) error {
+	entries, status := a.query(ctx)
+	if status != http.StatusOK {
+		return ctx.NoContent(status)
+	}
+
+	if ctx.QueryParam(auditFormatName) != auditFormatCSV {
+		ctx.Response().Header().Set(
+			echo.HeaderContentDisposition,
+			"attachment; filename=audit.json")
+
+		return ctx.JSON(http.StatusOK, entries)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	ctx.Response().Header().Set(
+		echo.HeaderContentDisposition,
+		"attachment; filename=audit.csv")
+	ctx.Response().WriteHeader(http.StatusOK)
+
+	if err := audit.WriteCSV(ctx.Response(), entries); err != nil {
+		a.Log.Error(errAuditExport, zap.Error(err))
+	}
+
+	return nil
+}
+
+func (a *Auditor) query(ctx echo.Context) ([]audit.Entry, int) {
+	filter, err := auditFilter(ctx)
+	if err != nil {
+		a.Log.Warn(errAuditFilter, zap.Error(err))
+
+		return nil, http.StatusBadRequest
+	}
+
+	entries, err := a.Repo.Query(filter)
+	if err != nil {
+		if errors.Is(err, audit.ErrNoStore) {
+			return nil, http.StatusNotImplemented
+		}
+
+		a.Log.Error(errAuditQuery, zap.Error(err))
+
+		return nil, http.StatusInternalServerError
+	}
+
+	return entries, http.StatusOK
+}
+
+// identify sets the user of the entry. The login handlers identify
+// the user explicitly, otherwise it is the user of the session
+func (a *Auditor) identify(ctx echo.Context, entry *audit.Entry) {
+	if name, ok := ctx.Get(AuditUserKey).(string); ok && name != "" {
+		entry.User = name
+
+		return
+	}
+
+	if a.Authz == nil {
+		return
+	}
+
+	if p, ok := a.Authz.Principal(ctx); ok {
+		entry.User = p.Name
+		entry.Subject = p.Subject
+		entry.Role = string(p.Role)
+	}
+}
+
+// auditResult checks whether the action succeeded. The oauth2
+// logins always redirect, the failures to the auth error page
+func auditResult(ctx echo.Context, status int) string {
+	if status >= http.StatusBadRequest ||
+		ctx.Response().Header().Get(echo.HeaderLocation) == RedirectErrorAuth {
+		return audit.ResultFailure
+	}
+
+	return audit.ResultSuccess
+}
+
+func auditFilter(ctx echo.Context) (audit.Filter, error) {
+	filter := audit.Filter{
+		User:   ctx.QueryParam(auditUserName),
+		Action: ctx.QueryParam(auditActionName),
+	}
+
+	var err error
+
+	if v := ctx.QueryParam(auditFromName); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.Wrap(err, auditFromName)
+		}
+	}
+
+	if v := ctx.QueryParam(auditToName); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.Wrap(err, auditToName)
+		}
+	}
+
+	if v := ctx.QueryParam(auditLimitName); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, errors.Wrap(err, auditLimitName)
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,228 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/audit"
+	"github.com/swpoolcontroller/internal/web"
+	"go.uber.org/zap"
+)
+
+func newAuditor(t *testing.T) *web.Auditor {
+	t.Helper()
+
+	return &web.Auditor{
+		Log: zap.NewExample(),
+		Repo: &audit.FileRepo{
+			Log:      zap.NewExample(),
+			FileName: filepath.Join(t.TempDir(), "audit.log"),
+		},
+		Authz: &web.Authorizer{
+			Log:    zap.NewExample(),
+			Config: oauth2Config(),
+		},
+	}
+}
+
+func TestAuditor_Record(t *testing.T) {
+	t.Parallel()
+
+	changes := []audit.Change{{Field: "wakeup", Before: 30, After: 10}}
+
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		claims  jwt.MapClaims
+		entry   audit.Entry
+	}{
+		{
+			name: "Config saved. It should record the user and the changes",
+			handler: func(c echo.Context) error {
+				c.Set(web.AuditChangesKey, changes)
+
+				return c.NoContent(http.StatusOK)
+			},
+			claims: jwt.MapClaims{"sub": "1", "username": "alice"},
+			entry: audit.Entry{
+				Action:  web.ActionConfigSave,
+				Result:  audit.ResultSuccess,
+				Status:  http.StatusOK,
+				User:    "alice",
+				Subject: "1",
+				Role:    "viewer",
+				Changes: changes,
+			},
+		},
+		{
+			name: "Login rejected. It should record the failure",
+			handler: func(c echo.Context) error {
+				c.Set(web.AuditUserKey, "bob")
+
+				return c.NoContent(http.StatusUnauthorized)
+			},
+			entry: audit.Entry{
+				Action: web.ActionLogin,
+				Result: audit.ResultFailure,
+				Status: http.StatusUnauthorized,
+				User:   "bob",
+			},
+		},
+		{
+			name: "OAuth2 login redirected to error. It should record the failure",
+			handler: func(c echo.Context) error {
+				return c.Redirect(http.StatusFound, web.RedirectErrorAuth)
+			},
+			entry: audit.Entry{
+				Action: web.ActionLogin,
+				Result: audit.ResultFailure,
+				Status: http.StatusFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := newAuditor(t)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/web/config", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+
+			if tt.claims != nil {
+				ctx.Set("user", &jwt.Token{Claims: tt.claims, Valid: true})
+			}
+
+			require.NoError(t, a.Record(tt.entry.Action)(tt.handler)(ctx))
+
+			entries, err := a.Repo.Query(audit.Filter{})
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+
+			got := entries[0]
+			assert.NotEmpty(t, got.ID)
+			assert.False(t, got.Time.IsZero())
+			assert.Equal(t, "10.0.0.1", got.IP)
+			assert.Equal(t, http.MethodPost, got.Method)
+			assert.Equal(t, "/api/web/config", got.Path)
+			assert.Equal(t, tt.entry.Action, got.Action)
+			assert.Equal(t, tt.entry.Result, got.Result)
+			assert.Equal(t, tt.entry.Status, got.Status)
+			assert.Equal(t, tt.entry.User, got.User)
+			assert.Equal(t, tt.entry.Subject, got.Subject)
+			assert.Equal(t, tt.entry.Role, got.Role)
+			assert.Len(t, got.Changes, len(tt.entry.Changes))
+		})
+	}
+}
+
+func TestAuditor_Query(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		url    string
+		repo   func(t *testing.T) audit.Repo
+		status int
+		body   string
+	}{
+		{
+			name: "Filtered query. It should return StatusOK",
+			url:  "/api/web/audit?user=alice&limit=1",
+			repo: func(t *testing.T) audit.Repo {
+				t.Helper()
+
+				return newAuditor(t).Repo
+			},
+			status: http.StatusOK,
+			body:   `"user":"alice"`,
+		},
+		{
+			name: "Invalid date. It should return StatusBadRequest",
+			url:  "/api/web/audit?from=yesterday",
+			repo: func(t *testing.T) audit.Repo {
+				t.Helper()
+
+				return newAuditor(t).Repo
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "Without store. It should return StatusNotImplemented",
+			url:  "/api/web/audit",
+			repo: func(_ *testing.T) audit.Repo {
+				return &audit.LogRepo{Log: zap.NewExample()}
+			},
+			status: http.StatusNotImplemented,
+		},
+		{
+			name: "CSV export. It should return StatusOK",
+			url:  "/api/web/audit/export?format=csv",
+			repo: func(t *testing.T) audit.Repo {
+				t.Helper()
+
+				return newAuditor(t).Repo
+			},
+			status: http.StatusOK,
+			body:   "time,action,result",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := newAuditor(t)
+			a.Repo = tt.repo(t)
+
+			_ = a.Repo.Append(audit.Entry{User: "alice", Action: "auth.login"})
+			_ = a.Repo.Append(audit.Entry{User: "bob", Action: "auth.login"})
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+
+			if strings.Contains(tt.url, "/export") {
+				_ = a.Export(ctx)
+			} else {
+				_ = a.Query(ctx)
+			}
+
+			assert.Equal(t, tt.status, rec.Code)
+
+			if tt.body != "" {
+				assert.Contains(t, rec.Body.String(), tt.body)
+				assert.NotContains(t, rec.Body.String(), `"user":"bob"`)
+			}
+		})
+	}
+}
@@ -18,31 +18,44 @@
 package web
 
 import (
+	"context"
+	"encoding/base64"
 	"net/http"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
 	"github.com/rs/xid"
 	"github.com/swpoolcontroller/internal/config"
 	"github.com/swpoolcontroller/pkg/auth"
+	"github.com/swpoolcontroller/pkg/crypto"
 	"go.uber.org/zap"
 )
 
+var errTokenNotValid = errors.New("The renewed token is not valid")
+
 const (
 	errTkInvalid     = "Token invalid"
 	errStateInvalid  = "Auth state invalid"
 	errGetWSClientID = "Getting web socket client id from web request"
+	errRefresh       = "Auth.Renewing the session"
+	errRefreshCookie = "Auth.Saving the refresh token"
 )
 
 const (
-	infLogin  = "Auth.Get token for login"
-	infLogoff = "Auth.Remove token for logoff"
+	infLogin   = "Auth.Get token for login"
+	infLogoff  = "Auth.Remove token for logoff"
+	infRefresh = "Auth.Renew token with the refresh token"
+
+	warnRefreshClient = "Auth.The hub client has not been extended"
 )
 
 const (
 	AuthHeaderName = "Authorization"
 	AuthCheckName  = "IsAuth"
+	// RefreshTokenName is the cookie that keeps the provider
+	// refresh token encrypted
+	RefreshTokenName = "RefreshToken"
 )
 
 const (
@@ -50,24 +63,43 @@ const (
 	RedirectLogin     = "/auth/login"
 	RedirectLogout    = "/auth/logout"
 	RedirectErrorAuth = "/auth/error"
+	// RefreshPath renews the session without leaving the page
+	RefreshPath = "/auth/refresh"
+	// refreshCookiePath limits the refresh token cookie to the auth
+	// routes, so it is sent to renew the session and to revoke it
+	refreshCookiePath = "/auth"
 )
 
 type OAuth2 interface {
-	Token(params auth.OA2TokenInput) (*jwt.Token, error)
+	Token(params auth.OA2TokenInput) (auth.OA2Token, error)
+	Refresh(params auth.OA2RefreshTokenInput) (auth.OA2Token, error)
 	RevokeToken(params auth.OA2RevokeTokenInput) error
 }
 
+// StateStore issues the single-use states of the oauth2 flow
+// and consumes them on login
+type StateStore interface {
+	Issue() (string, error)
+	Consume(state string) error
+}
+
 type Auth interface {
 	Login(ctx echo.Context) error
 	Logout(ctx echo.Context) error
+	// Refresh renews the session and extends the hub client.
+	// It returns 401 if the session cannot be renewed
+	Refresh(ctx echo.Context) error
 }
 
 // AuthFlow manages authentication of oauth2 supported providers
 type AuthFlow struct {
 	Log     *zap.Logger
 	Service OAuth2
+	States  StateStore
 	Hub     Hub
 	Config  config.Config
+	// Tokens denies the session tokens on logout. It can be nil
+	Tokens *DenylistParser
 }
 
 // Login gets a token from the provider
@@ -77,8 +109,7 @@ func (o *AuthFlow) Login(ctx echo.Context) error {
 
 	o.Log.Info(infLogin, zap.String("Code", code), zap.String("State", state))
 
-	if _, err := auth.DecodeState(
-		[]byte(o.Config.Web.SecretKey), state); err != nil {
+	if err := o.States.Consume(state); err != nil {
 		o.Log.Error(errStateInvalid, zap.Error(err))
 
 		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
@@ -91,53 +122,63 @@ func (o *AuthFlow) Login(ctx echo.Context) error {
 	}
 
 	token, err := o.Service.Token(param)
-	if err != nil || !token.Valid {
+	if err != nil || !token.AccessToken.Valid {
 		o.Log.Error(errTkInvalid, zap.Error(err))
 
 		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
 	}
 
-	// Save the security token in the cookies
-	// MaxAge is the same time than token expiration
-	// except expiration for jwt token.
-	// The expiration of the cookie with the token is
-	// kept 5 minutes longer than the internal expiration
-	// of the token, in case a new request is made from
-	// the browser.
-	// In this way, the server will return permission denied
-	// instead of bad request (this case would be because
-	// when the cookie expires the request would come without
-	// a token).
-	expiration := time.Now().Add(
-		time.Duration(o.Config.Web.SessionExpiration) * time.Minute)
-	cookie := cookies(AuthHeaderName, token.Raw, expiration.Add(5*time.Minute))
-	cookie.Secure = o.Config.External.TLS
-	ctx.SetCookie(cookie)
-
-	cookie = cookieAuthCheckName(expiration)
-	cookie.Secure = o.Config.External.TLS
-	ctx.SetCookie(cookie)
-
-	// ID for hub client
-	cookie = cookieWSClientIDName(expiration)
-	cookie.Secure = o.Config.External.TLS
-	ctx.SetCookie(cookie)
+	ctx.Set(AuditUserKey, tokenName(token.AccessToken))
+	setSessionCookies(ctx, o.Config, token.AccessToken.Raw, xid.New().String())
+	setRefreshCookie(ctx, o.Log, o.Config, token.RefreshToken)
 
 	return ctx.Redirect(http.StatusFound, RedirectLoginOk)
 }
 
+// Refresh renews the access token using the refresh token
+func (o *AuthFlow) Refresh(ctx echo.Context) error {
+	return refreshSession(
+		ctx,
+		o.Log,
+		o.Config,
+		o.Hub,
+		func(refreshToken string) (string, string, error) {
+			token, err := o.Service.Refresh(auth.OA2RefreshTokenInput{
+				URL:          o.Config.Auth.TokenURL,
+				RefreshToken: refreshToken,
+			})
+			if err != nil {
+				return "", "", err
+			}
+
+			if !token.AccessToken.Valid {
+				return "", "", errTokenNotValid
+			}
+
+			return token.AccessToken.Raw, token.RefreshToken, nil
+		})
+}
+
 // Logout revokes token, unregisters client into hub and delete cookies
 func (o *AuthFlow) Logout(ctx echo.Context) error {
 	o.Log.Info(infLogoff)
 
-	cookie := cookies(AuthHeaderName, "", time.Time{})
-	cookie.MaxAge = 0 // Remove cookie
-	cookie.Secure = o.Config.External.TLS
-	ctx.SetCookie(cookie)
+	if o.Config.Auth.RevokeURL != "" {
+		revoke := func(token string, hint string) error {
+			return o.Service.RevokeToken(auth.OA2RevokeTokenInput{
+				URL:      o.Config.Auth.RevokeURL,
+				Token:    token,
+				TypeHint: hint,
+			})
+		}
+
+		// Some providers, like AWS Cognito, only revoke refresh tokens,
+		// and the access tokens issued with them
+		revokeToken(o.Log, refreshToken(ctx, o.Config), auth.TokenHintRefresh, revoke)
+		revokeToken(o.Log, sessionToken(ctx), auth.TokenHintAccess, revoke)
+	}
 
-	cookie = removeCookieAuthCheckName()
-	cookie.Secure = o.Config.External.TLS
-	ctx.SetCookie(cookie)
+	closeSession(ctx, o.Log, o.Config, o.Hub, o.Tokens)
 
 	return ctx.Redirect(http.StatusFound, RedirectLoginOk)
 }
@@ -153,19 +194,27 @@ type AuthFlowDev struct {
 func (o *AuthFlowDev) Login(ctx echo.Context) error {
 	o.Log.Info(infLogin)
 
-	expiration := time.Now().Add(
-		time.Duration(o.Config.Web.SessionExpiration) * time.Minute)
+	setSessionCookies(ctx, o.Config, "", xid.New().String())
 
-	cookie := cookieAuthCheckName(expiration)
-	cookie.Secure = o.Config.External.TLS
-	ctx.SetCookie(cookie)
+	return ctx.Redirect(http.StatusFound, RedirectLoginOk)
+}
 
-	// ID for hub client
-	cookie = cookieWSClientIDName(expiration)
-	cookie.Secure = o.Config.External.TLS
-	ctx.SetCookie(cookie)
+// Refresh extends the session and the hub client.
+// There is no token to renew
+func (o *AuthFlowDev) Refresh(ctx echo.Context) error {
+	o.Log.Info(infRefresh)
 
-	return ctx.Redirect(http.StatusFound, RedirectLoginOk)
+	id, err := ctx.Cookie(WSClientIDName)
+	if err != nil {
+		o.Log.Error(errGetWSClientID, zap.Error(err))
+
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	setSessionCookies(ctx, o.Config, "", id.Value)
+	refreshClient(ctx, o.Log, o.Hub, id.Value, sessionExpiration(o.Config))
+
+	return ctx.NoContent(http.StatusOK)
 }
 
 // Logout unregisters client into hub and delete cookies
@@ -183,6 +232,191 @@ func (o *AuthFlowDev) Logout(ctx echo.Context) error {
 	return ctx.Redirect(http.StatusFound, RedirectLoginOk)
 }
 
+// refreshSession reads the refresh token, renews the tokens in the provider
+// and extends the session cookies and the hub client of the session found
+// by the renewal middleware.
+// renew returns the new session token and, if the provider rotates it,
+// the new refresh token
+func refreshSession(
+	ctx echo.Context,
+	log *zap.Logger,
+	cnf config.Config,
+	hub Hub,
+	renew func(refreshToken string) (string, string, error)) error {
+	//
+	log.Info(infRefresh)
+
+	id, ok := renewedSession(ctx, log)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	c, err := ctx.Cookie(RefreshTokenName)
+	if err != nil {
+		log.Error(errRefresh, zap.Error(err))
+
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	refreshToken, err := decryptCookie(cnf, c.Value)
+	if err != nil {
+		log.Error(errRefresh, zap.Error(err))
+
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	token, newRefreshToken, err := renew(refreshToken)
+	if err != nil {
+		log.Error(errRefresh, zap.Error(err))
+
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	setSessionCookies(ctx, cnf, token, id)
+	setRefreshCookie(ctx, log, cnf, newRefreshToken)
+	refreshClient(ctx, log, hub, id, sessionExpiration(cnf))
+
+	return ctx.NoContent(http.StatusOK)
+}
+
+// refreshClient extends the hub client of the renewed session.
+// If the hub does not receive it, the client expires as before
+func refreshClient(
+	ctx echo.Context,
+	log *zap.Logger,
+	hub Hub,
+	id string,
+	expiration time.Duration) {
+	//
+	c, cancel := context.WithTimeout(ctx.Request().Context(), statusTimeout)
+	defer cancel()
+
+	if err := hub.RefreshClient(c, id, expiration); err != nil {
+		log.Warn(warnRefreshClient, zap.Error(err))
+	}
+}
+
+// setRefreshCookie saves the refresh token encrypted in the cookies
+// and its id for the session. If the token is empty or the renewal
+// is disabled, nothing is saved
+func setRefreshCookie(
+	ctx echo.Context,
+	log *zap.Logger,
+	cnf config.Config,
+	refreshToken string) {
+	//
+	if refreshToken == "" || cnf.Web.RefreshExpiration <= 0 {
+		return
+	}
+
+	value, err := encryptCookie(cnf, refreshToken)
+	if err != nil {
+		// The session continues until it expires
+		log.Error(errRefreshCookie, zap.Error(err))
+
+		return
+	}
+
+	cookie := cookies(
+		RefreshTokenName,
+		value,
+		time.Now().Add(
+			time.Duration(cnf.Web.RefreshExpiration)*time.Minute))
+	cookie.Path = refreshCookiePath
+	cookie.Secure = cnf.External.TLS
+	ctx.SetCookie(cookie)
+
+	// The session is registered once the handler ends
+	ctx.Set(sessionRefreshKey, refreshID(refreshToken))
+}
+
+func encryptCookie(cnf config.Config, value string) (string, error) {
+	v, err := crypto.Encrypt([]byte(cnf.Web.SecretKey), []byte(value))
+	if err != nil {
+		return "", errors.Wrap(err, errRefreshCookie)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(v), nil
+}
+
+func decryptCookie(cnf config.Config, value string) (string, error) {
+	v, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", errors.Wrap(err, errRefresh)
+	}
+
+	d, err := crypto.Decrypt([]byte(cnf.Web.SecretKey), v)
+	if err != nil {
+		return "", errors.Wrap(err, errRefresh)
+	}
+
+	return string(d), nil
+}
+
+func sessionExpiration(cnf config.Config) time.Duration {
+	return time.Duration(cnf.Web.SessionExpiration) * time.Minute
+}
+
+// setSessionCookies saves the security token and the session
+// in the cookies. Without token (dev) only the session is saved.
+// id is the hub client id
+func setSessionCookies(
+	ctx echo.Context,
+	cnf config.Config,
+	token string,
+	id string) {
+	//
+	// Save the security token in the cookies
+	// MaxAge is the same time than token expiration
+	// except expiration for jwt token.
+	// The expiration of the cookie with the token is
+	// kept 5 minutes longer than the internal expiration
+	// of the token, in case a new request is made from
+	// the browser.
+	// In this way, the server will return permission denied
+	// instead of bad request (this case would be because
+	// when the cookie expires the request would come without
+	// a token).
+	expiration := time.Now().Add(sessionExpiration(cnf))
+
+	// The session is registered once the handler ends
+	ctx.Set(sessionIDKey, id)
+	ctx.Set(sessionTokenKey, token)
+
+	if token != "" {
+		cookie := cookies(AuthHeaderName, token, expiration.Add(5*time.Minute))
+		cookie.Secure = cnf.External.TLS
+		ctx.SetCookie(cookie)
+	}
+
+	cookie := cookieAuthCheckName(expiration)
+	cookie.Secure = cnf.External.TLS
+	ctx.SetCookie(cookie)
+
+	// ID for hub client
+	cookie = cookieWSClientIDName(id, expiration)
+	cookie.Secure = cnf.External.TLS
+	ctx.SetCookie(cookie)
+}
+
+// removeSessionCookies removes the security token and the session cookies
+func removeSessionCookies(ctx echo.Context, cnf config.Config) {
+	cookie := cookies(AuthHeaderName, "", time.Time{})
+	cookie.MaxAge = 0 // Remove cookie
+	cookie.Secure = cnf.External.TLS
+	ctx.SetCookie(cookie)
+
+	cookie = removeCookieAuthCheckName()
+	cookie.Secure = cnf.External.TLS
+	ctx.SetCookie(cookie)
+
+	cookie = cookies(RefreshTokenName, "", time.Time{})
+	cookie.Path = refreshCookiePath
+	cookie.MaxAge = -1 // Remove cookie
+	cookie.Secure = cnf.External.TLS
+	ctx.SetCookie(cookie)
+}
+
 func unregisterHub(ctx echo.Context, log *zap.Logger, hub Hub) bool {
 	id, err := ctx.Cookie(WSClientIDName)
 	if err != nil {
@@ -203,10 +437,10 @@ func cookieAuthCheckName(expiration time.Time) *http.Cookie {
 	return cookie
 }
 
-func cookieWSClientIDName(expiration time.Time) *http.Cookie {
+func cookieWSClientIDName(id string, expiration time.Time) *http.Cookie {
 	cookie := cookies(
 		WSClientIDName,
-		xid.New().String(),
+		id,
 		expiration.Add(5*time.Minute))
 
 	return cookie
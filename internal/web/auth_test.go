@@ -23,10 +23,13 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/swpoolcontroller/internal/config"
 	"github.com/swpoolcontroller/internal/web"
 	"github.com/swpoolcontroller/internal/web/mocks"
@@ -101,8 +104,9 @@ func TestAuthFlow_Login(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
-		state string
-		code  string
+		state  string
+		code   string
+		replay bool
 	}
 
 	type want struct {
@@ -113,18 +117,12 @@ func TestAuthFlow_Login(t *testing.T) {
 
 	type mock struct {
 		use   bool
-		token *jwt.Token
+		token auth.OA2Token
 		err   error
 	}
 
-	state, err := auth.EncodeState(
-		[]byte(config.Default().SecretKey),
-		[]byte("state"))
-	if err != nil {
-		t.Error("Encode state error")
-
-		return
-	}
+	// state is replaced by a state issued for each test
+	const state = "issued"
 
 	tests := []struct {
 		name string
@@ -157,7 +155,7 @@ func TestAuthFlow_Login(t *testing.T) {
 			},
 			mock: mock{
 				use:   true,
-				token: &jwt.Token{},
+				token: auth.OA2Token{AccessToken: &jwt.Token{}},
 				err:   errToken,
 			},
 			want: want{
@@ -174,7 +172,7 @@ func TestAuthFlow_Login(t *testing.T) {
 			},
 			mock: mock{
 				use:   true,
-				token: &jwt.Token{Valid: true},
+				token: auth.OA2Token{AccessToken: &jwt.Token{Raw: "token", Valid: true}},
 			},
 			want: want{
 				statusCode: http.StatusFound,
@@ -182,15 +180,63 @@ func TestAuthFlow_Login(t *testing.T) {
 				redirect:   web.RedirectLoginOk,
 			},
 		},
+		{
+			name: `Login with refresh token. 
+						 It should return StatusFound and save the refresh token`,
+			args: args{
+				state: state,
+				code:  "123",
+			},
+			mock: mock{
+				use: true,
+				token: auth.OA2Token{
+					AccessToken:  &jwt.Token{Raw: "token", Valid: true},
+					RefreshToken: "refresh",
+				},
+			},
+			want: want{
+				statusCode: http.StatusFound,
+				cookies:    4,
+				redirect:   web.RedirectLoginOk,
+			},
+		},
+		{
+			name: `Login with a state already used. 
+						 It should return StatusFound with RedirectErrorAuth`,
+			args: args{
+				state:  state,
+				code:   "123",
+				replay: true,
+			},
+			mock: mock{
+				use:   true,
+				token: auth.OA2Token{AccessToken: &jwt.Token{Raw: "token", Valid: true}},
+			},
+			want: want{
+				statusCode: http.StatusFound,
+				cookies:    0,
+				redirect:   web.RedirectErrorAuth,
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
+			states := newStateIssuer(config.Default())
+
+			argState := tt.args.state
+			if argState == state {
+				issued, err := states.Issue()
+				require.NoError(t, err)
+
+				argState = issued
+			}
+
 			e := echo.New()
 			queryParams := url.Values{}
-			queryParams.Set("state", tt.args.state)
+			queryParams.Set("state", argState)
 			queryParams.Set("code", tt.args.code)
 			req := httptest.NewRequest(
 				http.MethodGet,
@@ -205,6 +251,7 @@ func TestAuthFlow_Login(t *testing.T) {
 				Log:     zap.NewExample(),
 				Service: oa,
 				Config:  config.Default(),
+				States:  states,
 			}
 
 			param := auth.OA2TokenInput{
@@ -216,6 +263,11 @@ func TestAuthFlow_Login(t *testing.T) {
 				oa.On("Token", param).Return(tt.mock.token, tt.mock.err)
 			}
 
+			if tt.args.replay {
+				_ = o.Login(e.NewContext(req.Clone(req.Context()),
+					httptest.NewRecorder()))
+			}
+
 			_ = o.Login(c)
 
 			assert.Equal(t, tt.want.statusCode, rec.Code)
@@ -234,64 +286,330 @@ func TestAuthFlow_Login(t *testing.T) {
 func TestAuthFlow_Logout(t *testing.T) {
 	t.Parallel()
 
-	type args struct {
-		clientName string
-		clientID   string
-	}
-
 	type want struct {
 		statusCode int
 		cookies    int
 	}
 
 	tests := []struct {
-		name string
-		args args
-		want want
+		name      string
+		revokeURL string
+		revokeErr error
+		want      want
 	}{
 		{
-			name: "Logout. it should return StatusFound with RedirectLoginOk",
-			args: args{
-				clientName: web.WSClientIDName,
-				clientID:   "123",
+			name:      "Logout without revoke url. It should close the session",
+			revokeURL: "",
+			want: want{
+				statusCode: http.StatusFound,
+				cookies:    3,
 			},
+		},
+		{
+			name:      "Logout. It should revoke the tokens at the provider",
+			revokeURL: "http://provider/oauth2/revoke",
 			want: want{
 				statusCode: http.StatusFound,
-				cookies:    2,
+				cookies:    3,
 			},
-		}}
+		},
+		{
+			name:      "Logout with provider error. It should close the session anyway",
+			revokeURL: "http://provider/oauth2/revoke",
+			revokeErr: errToken,
+			want: want{
+				statusCode: http.StatusFound,
+				cookies:    3,
+			},
+		},
+	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
 			e := echo.New()
+			oa := mocks.NewOAuth2(t)
+			h := mocks.NewHub(t)
+			tokens, session := sessionToken(t)
+
+			cnf := config.Default()
+			cnf.Auth.RevokeURL = tt.revokeURL
+
+			o := &web.AuthFlow{
+				Log:     zap.NewExample(),
+				Service: oa,
+				Hub:     h,
+				Config:  cnf,
+				Tokens:  tokens,
+			}
+
 			req := httptest.NewRequest(http.MethodGet, "/logout", nil)
-			co := &http.Cookie{
-				Name:  tt.args.clientName,
-				Value: tt.args.clientID,
+			req.AddCookie(&http.Cookie{Name: web.WSClientIDName, Value: "123"})
+			req.AddCookie(&http.Cookie{Name: web.AuthHeaderName, Value: session})
+			req.AddCookie(loginRefreshCookie(t, o))
+
+			if tt.revokeURL != "" {
+				oa.On("RevokeToken", auth.OA2RevokeTokenInput{
+					URL:      tt.revokeURL,
+					Token:    "refresh",
+					TypeHint: auth.TokenHintRefresh,
+				}).Return(tt.revokeErr)
+				oa.On("RevokeToken", auth.OA2RevokeTokenInput{
+					URL:      tt.revokeURL,
+					Token:    session,
+					TypeHint: auth.TokenHintAccess,
+				}).Return(tt.revokeErr)
 			}
-			req.AddCookie(co)
+
+			h.On("UnregisterClient", "123")
 
 			rec := httptest.NewRecorder()
 
-			c := e.NewContext(req, rec)
+			_ = o.Logout(e.NewContext(req, rec))
+
+			assert.Equal(t, tt.want.statusCode, rec.Code)
+
+			r := rec.Result()
+			defer r.Body.Close()
+			assert.Len(t, r.Cookies(), tt.want.cookies, "Cookies")
+
+			_, err := tokens.ParseJWT(session)
+			require.Error(t, err, "The session token must be denied")
+		})
+	}
+}
+
+// sessionToken returns a denylist parser and a valid session token
+func sessionToken(t *testing.T) (*web.DenylistParser, string) {
+	t.Helper()
+
+	signer := &auth.HMACJWT{Key: []byte("key"), Issuer: "swpc"}
+
+	token, err := signer.Sign(jwt.MapClaims{
+		"jti":      "1",
+		"sub":      "1",
+		"username": "alice",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+
+	tokens := &web.DenylistParser{Parser: signer, Denylist: auth.NewDenylist()}
+
+	_, err = tokens.ParseJWT(token)
+	require.NoError(t, err)
+
+	return tokens, token
+}
+
+func TestAuthFlow_Refresh(t *testing.T) {
+	t.Parallel()
 
+	type want struct {
+		statusCode int
+		cookies    int
+	}
+
+	type renew struct {
+		use   bool
+		token auth.OA2Token
+		err   error
+	}
+
+	tests := []struct {
+		name      string
+		refresh   bool
+		terminate bool
+		mock      renew
+		want      want
+	}{
+		{
+			name:    "Refresh without refresh token. It should return StatusUnauthorized",
+			refresh: false,
+			want: want{
+				statusCode: http.StatusUnauthorized,
+				cookies:    0,
+			},
+		},
+		{
+			name:    "Refresh with provider error. It should return StatusUnauthorized",
+			refresh: true,
+			mock: renew{
+				use: true,
+				err: errToken,
+			},
+			want: want{
+				statusCode: http.StatusUnauthorized,
+				cookies:    0,
+			},
+		},
+		{
+			name:    "Refresh. It should return StatusOK and rotate the refresh token",
+			refresh: true,
+			mock: renew{
+				use: true,
+				token: auth.OA2Token{
+					AccessToken:  &jwt.Token{Raw: "new", Valid: true},
+					RefreshToken: "refresh2",
+				},
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				cookies:    4,
+			},
+		},
+		{
+			name:      "Refresh of a terminated session. It should return StatusUnauthorized",
+			refresh:   true,
+			terminate: true,
+			want: want{
+				statusCode: http.StatusUnauthorized,
+				cookies:    4,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := echo.New()
+			oa := mocks.NewOAuth2(t)
 			h := mocks.NewHub(t)
+			s, _ := newSessions(t)
 
 			o := &web.AuthFlow{
-				Log:    zap.NewExample(),
-				Hub:    h,
-				Config: config.Default(),
+				Log:     zap.NewExample(),
+				Service: oa,
+				Hub:     h,
+				Config:  config.Default(),
 			}
 
-			_ = o.Logout(c)
+			// The session is found by the refresh token, not by the cookie
+			req := httptest.NewRequest(http.MethodPost, web.RefreshPath, nil)
+			req.AddCookie(&http.Cookie{Name: web.WSClientIDName, Value: "123"})
+
+			id := "123"
+
+			if tt.refresh {
+				var c *http.Cookie
+
+				c, id = loginSession(t, o, s)
+				req.AddCookie(c)
+			}
+
+			if tt.terminate {
+				_, ok := s.Registry.Terminate(id)
+				require.True(t, ok)
+			}
+
+			if tt.mock.use {
+				oa.On("Refresh", auth.OA2RefreshTokenInput{
+					URL:          o.Config.Auth.TokenURL,
+					RefreshToken: "refresh",
+				}).Return(tt.mock.token, tt.mock.err)
+			}
+
+			if tt.want.statusCode == http.StatusOK {
+				h.On(
+					"RefreshClient",
+					mock.Anything,
+					id,
+					time.Duration(o.Config.Web.SessionExpiration)*time.Minute).
+					Return(nil)
+			}
+
+			rec := httptest.NewRecorder()
+
+			_ = s.Renewal()(o.Refresh)(e.NewContext(req, rec))
 
 			assert.Equal(t, tt.want.statusCode, rec.Code)
 
 			r := rec.Result()
 			defer r.Body.Close()
+
 			assert.Len(t, r.Cookies(), tt.want.cookies, "Cookies")
 		})
 	}
 }
+
+// loginRefreshCookie logs in and returns the encrypted refresh cookie
+func loginRefreshCookie(t *testing.T, o *web.AuthFlow) *http.Cookie {
+	t.Helper()
+
+	c, _ := loginSession(t, o, nil)
+
+	return c
+}
+
+// loginSession logs in registering the session in s, if it is not nil,
+// and returns the encrypted refresh cookie and the session id
+func loginSession(
+	t *testing.T,
+	o *web.AuthFlow,
+	s *web.Sessions) (*http.Cookie, string) {
+	//
+	t.Helper()
+
+	states := newStateIssuer(o.Config)
+
+	state, err := states.Issue()
+	require.NoError(t, err)
+
+	oa := mocks.NewOAuth2(t)
+	oa.On("Token", mock.Anything).Return(
+		auth.OA2Token{
+			AccessToken:  &jwt.Token{Raw: "token", Valid: true},
+			RefreshToken: "refresh",
+		},
+		nil)
+
+	login := &web.AuthFlow{
+		Log:     o.Log,
+		Service: oa,
+		Config:  o.Config,
+		States:  states,
+	}
+
+	req := httptest.NewRequest(
+		http.MethodGet,
+		"/login?"+url.Values{"state": {state}, "code": {"1"}}.Encode(),
+		nil)
+	rec := httptest.NewRecorder()
+	handler := login.Login
+
+	if s != nil {
+		handler = s.Start()(handler)
+	}
+
+	_ = handler(echo.New().NewContext(req, rec))
+
+	r := rec.Result()
+	defer r.Body.Close()
+
+	var refresh *http.Cookie
+
+	id := ""
+
+	for _, c := range r.Cookies() {
+		switch c.Name {
+		case web.RefreshTokenName:
+			refresh = c
+		case web.WSClientIDName:
+			id = c.Value
+		}
+	}
+
+	require.NotNil(t, refresh, "Refresh cookie not found")
+
+	return refresh, id
+}
+
+// newStateIssuer returns a state issuer keyed with the config secret key
+func newStateIssuer(conf config.Config) *auth.StateIssuer {
+	return &auth.StateIssuer{
+		Key:    []byte(conf.Web.SecretKey),
+		TTL:    time.Minute,
+		Nonces: auth.NewNonceStore(),
+	}
+}
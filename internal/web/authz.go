@@ -0,0 +1,338 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/swpoolcontroller/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	errNoPrincipal = "Authz. The user cannot be identified"
+	errForbidden   = "Authz. The user does not have permission"
+)
+
+const (
+	// PrincipalKey is the key of the echo context
+	// where the authenticated user is stored
+	PrincipalKey = "principal"
+	// tokenKey is the key of the echo context where echojwt
+	// stores the JWT token
+	tokenKey = "user"
+)
+
+// Role is the role of an user
+type Role string
+
+const (
+	// RoleNone does not grant any permission
+	RoleNone Role = ""
+	// RoleViewer can see metrics and the configuration
+	RoleViewer Role = "viewer"
+	// RoleOperator can change the configuration and add samples
+	RoleOperator Role = "operator"
+	// RoleAdmin can do everything
+	RoleAdmin Role = "admin"
+)
+
+// rank returns the precedence of the role
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	case RoleNone:
+		return 0
+	}
+
+	return 0
+}
+
+// Permission is an action that can be performed on the API
+type Permission string
+
+const (
+	// PermMetricsRead allows to receive metrics and predictions
+	PermMetricsRead Permission = "metrics:read"
+	// PermConfigRead allows to read the micro-controller configuration
+	PermConfigRead Permission = "config:read"
+	// PermConfigWrite allows to change the micro-controller configuration
+	PermConfigWrite Permission = "config:write"
+	// PermSampleRead allows to list and export the samples
+	PermSampleRead Permission = "sample:read"
+	// PermSampleWrite allows to add samples for the ai model
+	PermSampleWrite Permission = "sample:write"
+	// PermAdmin allows to manage the app
+	PermAdmin Permission = "admin"
+)
+
+// rolePermissions are the permissions granted by each role.
+// The order is kept when the permissions are sent to the UI
+//
+//nolint:gochecknoglobals
+var rolePermissions = map[Role][]Permission{
+	RoleNone:   {},
+	RoleViewer: {PermMetricsRead, PermConfigRead},
+	RoleOperator: {
+		PermMetricsRead,
+		PermConfigRead,
+		PermConfigWrite,
+		PermSampleRead,
+		PermSampleWrite,
+	},
+	RoleAdmin: {
+		PermMetricsRead,
+		PermConfigRead,
+		PermConfigWrite,
+		PermSampleRead,
+		PermSampleWrite,
+		PermAdmin,
+	},
+}
+
+// Principal is the authenticated user
+type Principal struct {
+	// Subject is the unique identifier of the user
+	Subject string `json:"sub"`
+	// Name is the readable name of the user
+	Name string `json:"name"`
+	// Role is the effective role of the user
+	Role Role `json:"role"`
+	// Scopes limit the permissions of the api keys.
+	// If it is nil the permissions are those of the role
+	Scopes []Permission `json:"scopes,omitempty"`
+}
+
+// TokenParser parses and validates a raw JWT token
+type TokenParser interface {
+	ParseJWT(tokenString string) (*jwt.Token, error)
+}
+
+// Authorizer derives the roles of the users from the JWT claims
+// and checks the permissions of each route
+type Authorizer struct {
+	Log    *zap.Logger
+	Config config.Config
+	// Parser is used to identify the user outside of the routes
+	// protected by JWT, through the auth cookie.
+	Parser TokenParser
+
+	// iot replaces the IOT flags of Config once they are reloaded
+	iot atomic.Pointer[config.IOT]
+}
+
+// SetIOT changes the IOT flags while the server is running
+func (a *Authorizer) SetIOT(flags config.IOT) {
+	a.iot.Store(&flags)
+}
+
+// iotFlags returns the reloaded IOT flags or those of Config
+func (a *Authorizer) iotFlags() config.IOT {
+	if flags := a.iot.Load(); flags != nil {
+		return *flags
+	}
+
+	return a.Config.IOT
+}
+
+// Require returns a middleware that only allows the request
+// if the user has the permission
+func (a *Authorizer) Require(perm Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			p, ok := a.Principal(ctx)
+			if !ok {
+				a.Log.Error(errNoPrincipal, zap.String("Path", ctx.Path()))
+
+				return ctx.NoContent(http.StatusUnauthorized)
+			}
+
+			if !a.Allowed(p, perm) {
+				a.Log.Warn(
+					errForbidden,
+					zap.String("User", p.Name),
+					zap.String("Role", string(p.Role)),
+					zap.String("Permission", string(perm)),
+					zap.String("Path", ctx.Path()))
+
+				return ctx.NoContent(http.StatusForbidden)
+			}
+
+			ctx.Set(PrincipalKey, p)
+
+			return next(ctx)
+		}
+	}
+}
+
+// Principal gets the authenticated user of the request.
+// In development mode the user is always an administrator
+func (a *Authorizer) Principal(ctx echo.Context) (Principal, bool) {
+	if p, ok := ctx.Get(PrincipalKey).(Principal); ok {
+		return p, true
+	}
+
+	if a.Config.Auth.Provider == config.AuthProviderDev {
+		return Principal{Subject: "dev", Name: "dev", Role: RoleAdmin}, true
+	}
+
+	token, ok := ctx.Get(tokenKey).(*jwt.Token)
+	if !ok {
+		token, ok = a.cookieToken(ctx)
+		if !ok {
+			return Principal{}, false
+		}
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Principal{}, false
+	}
+
+	return a.principal(claims), true
+}
+
+// Permissions returns the effective permissions of the user.
+// The global IOT flags remove the permissions for everybody
+func (a *Authorizer) Permissions(p Principal) []Permission {
+	granted := rolePermissions[p.Role]
+	if p.Scopes != nil {
+		granted = p.Scopes
+	}
+
+	perms := make([]Permission, 0, len(granted))
+	flags := a.iotFlags()
+
+	for _, perm := range granted {
+		if perm == PermConfigWrite && !flags.ConfigUI {
+			continue
+		}
+
+		if perm == PermSampleWrite && !flags.SampleUI {
+			continue
+		}
+
+		perms = append(perms, perm)
+	}
+
+	return perms
+}
+
+// Allowed checks whether the user has the permission
+func (a *Authorizer) Allowed(p Principal, perm Permission) bool {
+	return hasPermission(a.Permissions(p), perm)
+}
+
+func (a *Authorizer) cookieToken(ctx echo.Context) (*jwt.Token, bool) {
+	if a.Parser == nil {
+		return nil, false
+	}
+
+	cookie, err := ctx.Cookie(AuthHeaderName)
+	if err != nil || cookie.Value == "" {
+		return nil, false
+	}
+
+	token, err := a.Parser.ParseJWT(cookie.Value)
+	if err != nil || !token.Valid {
+		return nil, false
+	}
+
+	return token, true
+}
+
+func (a *Authorizer) principal(claims jwt.MapClaims) Principal {
+	sub, _ := claims.GetSubject()
+
+	return Principal{
+		Subject: sub,
+		Name:    claimsName(claims),
+		Role:    a.role(claims[a.Config.Auth.RolesClaim]),
+	}
+}
+
+// claimsName gets the readable name of the user.
+// If there is no name it is the subject
+func claimsName(claims jwt.MapClaims) string {
+	for _, k := range []string{
+		"username", "cognito:username", "preferred_username", "email"} {
+		if v, ok := claims[k].(string); ok && v != "" {
+			return v
+		}
+	}
+
+	sub, _ := claims.GetSubject()
+
+	return sub
+}
+
+// tokenName gets the readable name of the user of the token
+func tokenName(token *jwt.Token) string {
+	if token == nil {
+		return ""
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+
+	return claimsName(claims)
+}
+
+// role maps the groups of the claim to the highest app role
+func (a *Authorizer) role(claim interface{}) Role {
+	var groups []string
+
+	switch v := claim.(type) {
+	case string:
+		groups = strings.FieldsFunc(v, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	case []interface{}:
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	role := Role(a.Config.Auth.DefaultRole)
+
+	for _, g := range groups {
+		r := Role(g)
+		if m, ok := a.Config.Auth.Roles[g]; ok {
+			r = Role(m)
+		}
+
+		if r.rank() > role.rank() {
+			role = r
+		}
+	}
+
+	return role
+}
@@ -0,0 +1,187 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/internal/web"
+	"go.uber.org/zap"
+)
+
+func oauth2Config() config.Config {
+	cnf := config.Default()
+	cnf.Auth.Provider = config.AuthProviderOauth2
+	cnf.Auth.Roles = map[string]string{"pool-admins": "admin"}
+
+	return cnf
+}
+
+func TestAuthorizer_Require(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		perm   web.Permission
+		status int
+	}{
+		{
+			name:   "Without token. StatusUnauthorized",
+			claims: nil,
+			perm:   web.PermConfigRead,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Default role viewer reads config. StatusOK",
+			claims: jwt.MapClaims{"sub": "1"},
+			perm:   web.PermConfigRead,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Viewer writes config. StatusForbidden",
+			claims: jwt.MapClaims{"sub": "1", "cognito:groups": []interface{}{}},
+			perm:   web.PermConfigWrite,
+			status: http.StatusForbidden,
+		},
+		{
+			name: "Operator writes config. StatusOK",
+			claims: jwt.MapClaims{
+				"sub":            "1",
+				"cognito:groups": []interface{}{"viewer", "operator"},
+			},
+			perm:   web.PermConfigWrite,
+			status: http.StatusOK,
+		},
+		{
+			name: "Operator adds sample disabled by SampleUI. StatusForbidden",
+			claims: jwt.MapClaims{
+				"sub":            "1",
+				"cognito:groups": []interface{}{"operator"},
+			},
+			perm:   web.PermSampleWrite,
+			status: http.StatusForbidden,
+		},
+		{
+			name: "Mapped group admin. StatusOK",
+			claims: jwt.MapClaims{
+				"sub":            "1",
+				"cognito:groups": "pool-admins",
+			},
+			perm:   web.PermAdmin,
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/web/config", nil)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+
+			if tt.claims != nil {
+				ctx.Set("user", &jwt.Token{Claims: tt.claims, Valid: true})
+			}
+
+			a := &web.Authorizer{
+				Log:    zap.NewExample(),
+				Config: oauth2Config(),
+			}
+
+			h := a.Require(tt.perm)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			_ = h(ctx)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestAuthorizer_Principal(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	ctx.Set("user", &jwt.Token{Claims: jwt.MapClaims{
+		"sub":              "1",
+		"cognito:username": "john",
+		"cognito:groups":   []interface{}{"pool-admins"},
+	}})
+
+	a := &web.Authorizer{
+		Log:    zap.NewExample(),
+		Config: oauth2Config(),
+	}
+
+	p, ok := a.Principal(ctx)
+
+	assert.True(t, ok)
+	assert.Equal(
+		t,
+		web.Principal{Subject: "1", Name: "john", Role: web.RoleAdmin},
+		p)
+}
+
+func TestAuthorizer_Principal_Dev(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	a := &web.Authorizer{
+		Log:    zap.NewExample(),
+		Config: config.Default(),
+	}
+
+	p, ok := a.Principal(ctx)
+
+	assert.True(t, ok)
+	assert.Equal(t, web.RoleAdmin, p.Role)
+}
+
+func TestAuthorizer_SetIOT(t *testing.T) {
+	t.Parallel()
+
+	a := &web.Authorizer{
+		Log:    zap.NewExample(),
+		Config: oauth2Config(),
+	}
+	admin := web.Principal{Role: web.RoleAdmin}
+
+	assert.True(t, a.Allowed(admin, web.PermConfigWrite))
+	assert.False(t, a.Allowed(admin, web.PermSampleWrite))
+
+	a.SetIOT(config.IOT{ConfigUI: false, SampleUI: true})
+
+	assert.False(t, a.Allowed(admin, web.PermConfigWrite))
+	assert.True(t, a.Allowed(admin, web.PermSampleWrite))
+}
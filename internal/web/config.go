@@ -18,9 +18,15 @@
 package web
 
 import (
+	"context"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/audit"
 	"github.com/swpoolcontroller/internal/iot"
 	"go.uber.org/zap"
 )
@@ -29,29 +35,104 @@ const (
 	errloadConfig    = "Loading configuration"
 	errGettingConfig = "Getting the configuration of the request body"
 	errSavingConfig  = "Saving config request"
+	errPrevConfig    = "Reading the previous configuration for the audit"
+	errHistory       = "Reading the configuration history"
+	errRevision      = "Reading the configuration revision"
+	errRevisionParam = "Parsing the configuration revision"
+	errRollback      = "Rolling back the configuration"
+	errIfMatch       = "Parsing the If-Match header of the config request"
+	errConflict      = "The configuration has been changed by another save"
+	errInvalidConfig = "The configuration is not valid"
+	errConfigStatus  = "Getting the delivery status of the configuration"
+)
+
+// Optimistic concurrency headers. ifMatchAny matches any revision
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+	ifMatchAny    = "*"
+)
+
+// statusTimeout is the maximum time to wait for the hub
+const statusTimeout = 2 * time.Second
+
+// Params of the history API
+const (
+	revisionName = "rev"
+	diffFromName = "from"
+	diffToName   = "to"
 )
 
 // ConfigWeb manages the web configuration
 type ConfigWeb struct {
-	Log    *zap.Logger
-	MicroR iot.ConfigRead
-	MicroW iot.ConfigWrite
+	Log     *zap.Logger
+	MicroR  iot.ConfigRead
+	MicroW  iot.ConfigWrite
+	History iot.ConfigHistory
+	Status  ConfigStatuser
+}
+
+// configDiffDTO is the changes between two revisions
+type configDiffDTO struct {
+	From    int            `json:"from"`
+	To      int            `json:"to"`
+	Changes []audit.Change `json:"changes"`
 }
 
-// Load loads the configuration from disk file
+// Load loads the latest revision of the configuration.
+// The ETag is its number, required by Save in If-Match
 func (cf *ConfigWeb) Load(ctx echo.Context) error {
-	data, err := cf.MicroR.Read()
+	rev, data, err := cf.latest()
 	if err != nil {
 		cf.Log.Error(errloadConfig, zap.Error(err))
 
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
+	ctx.Response().Header().Set(headerETag, etag(rev))
+
 	return ctx.JSON(http.StatusOK, data)
 }
 
-// Save saves the configuration to disk
+// latest reads the latest revision and its configuration. The configuration
+// is read from the revision, so a save in between does not change it.
+// Without revisions it is the stored or the default configuration
+func (cf *ConfigWeb) latest() (int, iot.Config, error) {
+	rev, err := cf.History.Current()
+	if err != nil {
+		return 0, iot.Config{}, err
+	}
+
+	if rev == 0 {
+		data, err := cf.MicroR.Read()
+
+		return 0, data, err
+	}
+
+	saved, err := cf.History.Revision(rev)
+	if err != nil {
+		return 0, iot.Config{}, err
+	}
+
+	return rev, saved.Config, nil
+}
+
+// Save saves the configuration to disk if the If-Match header
+// is the latest revision, otherwise another user has saved it
+// and the request fails with the precondition failed status
 func (cf *ConfigWeb) Save(ctx echo.Context) error {
+	match := ctx.Request().Header.Get(headerIfMatch)
+	if match == "" {
+		return ctx.NoContent(http.StatusPreconditionRequired)
+	}
+
+	rev, err := parseETag(match)
+	if err != nil {
+		cf.Log.Error(errIfMatch, zap.Error(err))
+
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
 	var conf iot.Config
 
 	if err := ctx.Bind(&conf); err != nil {
@@ -60,11 +141,218 @@ func (cf *ConfigWeb) Save(ctx echo.Context) error {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
 
-	if err := cf.MicroW.Save(conf); err != nil {
+	if errs, ok := cf.invalid(conf); ok {
+		return validationProblem(ctx, errInvalidConfig, errs)
+	}
+
+	// The audit log records the changes, not only the new value
+	var prev interface{}
+
+	if p, err := cf.MicroR.Read(); err != nil {
+		cf.Log.Warn(errPrevConfig, zap.Error(err))
+	} else {
+		prev = p
+	}
+
+	saved, err := cf.MicroW.SaveIfMatch(conf, author(ctx), rev)
+	if err != nil {
+		if errors.Is(err, iot.ErrRevisionConflict) {
+			cf.Log.Warn(errConflict, zap.String("IfMatch", match))
+
+			return ctx.NoContent(http.StatusPreconditionFailed)
+		}
+
 		cf.Log.Error(errSavingConfig, zap.Error(err))
 
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
+	ctx.Set(AuditChangesKey, audit.Diff(prev, conf))
+	ctx.Response().Header().Set(headerETag, etag(saved.Rev))
+
 	return ctx.NoContent(http.StatusOK)
 }
+
+// Schema returns the JSON schema of the configuration, so the forms
+// can be rendered and validated with the rules of Save
+func (cf *ConfigWeb) Schema(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, iot.ConfigSchema())
+}
+
+// DeliveryStatus returns whether the latest configuration is pending
+// until the micro controller wakes up or when it was applied
+func (cf *ConfigWeb) DeliveryStatus(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(ctx.Request().Context(), statusTimeout)
+	defer cancel()
+
+	status, err := cf.Status.ConfigStatus(c)
+	if err != nil {
+		cf.Log.Error(errConfigStatus, zap.Error(err))
+
+		return ctx.NoContent(http.StatusServiceUnavailable)
+	}
+
+	return ctx.JSON(http.StatusOK, status)
+}
+
+// Revisions lists the saved revisions of the configuration, the latest first
+func (cf *ConfigWeb) Revisions(ctx echo.Context) error {
+	revs, err := cf.History.History()
+	if err != nil {
+		cf.Log.Error(errHistory, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	return ctx.JSON(http.StatusOK, revs)
+}
+
+// Diff returns the changes between the revisions from and to.
+// If to is not set the changes are against the latest revision
+func (cf *ConfigWeb) Diff(ctx echo.Context) error {
+	from, err := strconv.Atoi(ctx.QueryParam(diffFromName))
+	if err != nil {
+		cf.Log.Error(errRevisionParam, zap.Error(err))
+
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	revs, err := cf.History.History()
+	if err != nil {
+		cf.Log.Error(errHistory, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	if len(revs) == 0 {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	to := revs[0].Rev
+
+	if p := ctx.QueryParam(diffToName); p != "" {
+		if to, err = strconv.Atoi(p); err != nil {
+			cf.Log.Error(errRevisionParam, zap.Error(err))
+
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+	}
+
+	var fromRev, toRev iot.Revision
+
+	for _, r := range revs {
+		switch r.Rev {
+		case from:
+			fromRev = r
+		case to:
+			toRev = r
+		}
+	}
+
+	if fromRev.Rev != from || toRev.Rev != to {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	return ctx.JSON(http.StatusOK, configDiffDTO{
+		From:    from,
+		To:      to,
+		Changes: audit.Diff(fromRev.Config, toRev.Config),
+	})
+}
+
+// Rollback saves the configuration of the revision as a new revision,
+// so the micro controller receives it again
+func (cf *ConfigWeb) Rollback(ctx echo.Context) error {
+	rev, err := strconv.Atoi(ctx.Param(revisionName))
+	if err != nil {
+		cf.Log.Error(errRevisionParam, zap.Error(err))
+
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	old, err := cf.History.Revision(rev)
+	if err != nil {
+		if errors.Is(err, iot.ErrRevisionNotFound) {
+			return ctx.NoContent(http.StatusNotFound)
+		}
+
+		cf.Log.Error(errRevision, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	// The revisions saved before the rules could be invalid
+	if errs, ok := cf.invalid(old.Config); ok {
+		return validationProblem(ctx, errInvalidConfig, errs)
+	}
+
+	var prev interface{}
+
+	if p, err := cf.MicroR.Read(); err != nil {
+		cf.Log.Warn(errPrevConfig, zap.Error(err))
+	} else {
+		prev = p
+	}
+
+	saved, err := cf.MicroW.Save(old.Config, author(ctx))
+	if err != nil {
+		cf.Log.Error(errRollback, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	ctx.Set(AuditChangesKey, audit.Diff(prev, old.Config))
+	ctx.Response().Header().Set(headerETag, etag(saved.Rev))
+
+	return ctx.JSON(http.StatusOK, saved)
+}
+
+// invalid checks the rules of the configuration.
+// It returns the invalid fields and true if it is not valid
+func (cf *ConfigWeb) invalid(conf iot.Config) (iot.ValidationError, bool) {
+	var errs iot.ValidationError
+
+	if !errors.As(conf.Validate(), &errs) {
+		return nil, false
+	}
+
+	cf.Log.Warn(errInvalidConfig, zap.Error(errs))
+
+	return errs, true
+}
+
+// etag is the strong entity tag of the revision
+func etag(rev int) string {
+	return strconv.Quote(strconv.Itoa(rev))
+}
+
+// parseETag parses the revision of the entity tag.
+// The weak tags are accepted and * is any revision
+func parseETag(tag string) (int, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+
+	if tag == ifMatchAny {
+		return iot.AnyRevision, nil
+	}
+
+	rev, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, errors.Wrap(err, errIfMatch)
+	}
+
+	n, err := strconv.Atoi(rev)
+	if err != nil {
+		return 0, errors.Wrap(err, errIfMatch)
+	}
+
+	return n, nil
+}
+
+// author is the name of the authenticated user of the request
+func author(ctx echo.Context) string {
+	if p, ok := ctx.Get(PrincipalKey).(Principal); ok {
+		return p.Name
+	}
+
+	return ""
+}
@@ -18,23 +18,33 @@
 package web_test
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/audit"
 	"github.com/swpoolcontroller/internal/config"
 	iotc "github.com/swpoolcontroller/internal/iot"
 	"github.com/swpoolcontroller/internal/iot/mocks"
 	"github.com/swpoolcontroller/internal/web"
+	wmocks "github.com/swpoolcontroller/internal/web/mocks"
 	"github.com/swpoolcontroller/pkg/iot"
 	"go.uber.org/zap"
 )
 
+// validConfig is a request body that satisfies the rules
+const validConfig = `{"iniSendTime": "09:00", "endSendTime": "21:00",
+	"wakeup": 20, "buffer": 10, "stabilizationTime": 20}`
+
 func TestConfigWeb_Load(t *testing.T) {
 	t.Parallel()
 
@@ -42,6 +52,7 @@ func TestConfigWeb_Load(t *testing.T) {
 
 	type res struct {
 		status int
+		etag   string
 		body   string
 	}
 
@@ -55,6 +66,7 @@ func TestConfigWeb_Load(t *testing.T) {
 			dataFile: "./testr/micro-config.dat",
 			res: res{
 				status: http.StatusOK,
+				etag:   `"0"`,
 				body: "{\"iniSendTime\":\"10:00\",\"endSendTime\":\"21:01\"," +
 					"\"wakeup\":16,\"buffer\":3," +
 					"\"calibrationOrp\":0,\"calibrationPh\":0," +
@@ -89,11 +101,15 @@ func TestConfigWeb_Load(t *testing.T) {
 					DataFile: tt.dataFile,
 				},
 				MicroW: &iotc.FileConfigWrite{},
+				History: &iotc.FileConfigWrite{
+					DataFile: tt.dataFile,
+				},
 			}
 
 			_ = cf.Load(c)
 
 			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.etag, rec.Header().Get("ETag"))
 			assert.Equal(t, tt.body, rec.Body.String())
 		})
 	}
@@ -112,6 +128,7 @@ func TestConfigWeb_Save(t *testing.T) {
 	tests := []struct {
 		name      string
 		field     fields
+		ifMatch   string
 		argBody   string
 		resStatus int
 	}{
@@ -121,11 +138,11 @@ func TestConfigWeb_Save(t *testing.T) {
 				hubf: func() iotc.Hub {
 					h := mocks.NewHub(t)
 					h.On("Config", iot.DeviceConfig{
-						WakeUpTime:         10,
+						WakeUpTime:         20,
 						CollectMetricsTime: 1000,
 						Buffer:             10,
-						IniSendTime:        "12:00",
-						EndSendTime:        "12:00",
+						IniSendTime:        "09:00",
+						EndSendTime:        "21:00",
 						CalibrationORP:     1132.12,
 						CalibrationPH:      1.12,
 						CalibratingORP:     true,
@@ -137,10 +154,11 @@ func TestConfigWeb_Save(t *testing.T) {
 
 					return h
 				},
-				dataFile: "./testr/micro-config-write-sucess.dat",
+				dataFile: "micro-config-write-sucess.dat",
 			},
-			argBody: `{"iniSendTime":"12:00","endSendTime":"12:00",
-			 "wakeup":10,"buffer":10,
+			ifMatch: `"0"`,
+			argBody: `{"iniSendTime":"09:00","endSendTime":"21:00",
+			 "wakeup":20,"buffer":10,
 			 "calibrationOrp":1132.12,"calibrationPh":1.12,
 			 "calibratingOrp":true,"targetOrp":450.10,
 			 "calibratingPh":true,"targetPh":7.2,
@@ -151,19 +169,64 @@ func TestConfigWeb_Save(t *testing.T) {
 			name: "Save. StatusBadRequest",
 			field: fields{
 				hubf:     func() iotc.Hub { return mocks.NewHub(t) },
-				dataFile: "./testr/micro-config-write-sucess.dat",
+				dataFile: "micro-config-write-sucess.dat",
 			},
+			ifMatch:   `"0"`,
 			argBody:   "{",
 			resStatus: http.StatusBadRequest,
 		},
+		{
+			name: "Save. StatusPreconditionRequired",
+			field: fields{
+				hubf:     func() iotc.Hub { return mocks.NewHub(t) },
+				dataFile: "micro-config-write-sucess.dat",
+			},
+			argBody:   validConfig,
+			resStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name: "Save. StatusPreconditionFailed",
+			field: fields{
+				hubf:     func() iotc.Hub { return mocks.NewHub(t) },
+				dataFile: "micro-config-write-sucess.dat",
+			},
+			ifMatch:   `"3"`,
+			argBody:   validConfig,
+			resStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name: "Save. StatusBadRequest if If-Match is not a revision",
+			field: fields{
+				hubf:     func() iotc.Hub { return mocks.NewHub(t) },
+				dataFile: "micro-config-write-sucess.dat",
+			},
+			ifMatch:   `"abc"`,
+			argBody:   validConfig,
+			resStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Save. StatusOk with any revision",
+			field: fields{
+				hubf: func() iotc.Hub {
+					h := mocks.NewHub(t)
+					h.On("Config", mock.Anything)
+
+					return h
+				},
+				dataFile: "micro-config-write-sucess.dat",
+			},
+			ifMatch:   "*",
+			argBody:   validConfig,
+			resStatus: http.StatusOK,
+		},
 		{
 			name: "Save. StatusInternalServerError",
 			field: fields{
 				hubf:     func() iotc.Hub { return mocks.NewHub(t) },
-				dataFile: "./not_exist/micro-config-write.dat",
+				dataFile: "not_exist/micro-config-write.dat",
 			},
-			argBody: `{"iniSendTime": "12:00", "endSendTime": "12:00",
-			 "wakeup": 10, "buffer": 10}`,
+			ifMatch:   `"0"`,
+			argBody:   validConfig,
 			resStatus: http.StatusInternalServerError,
 		},
 	}
@@ -178,30 +241,372 @@ func TestConfigWeb_Save(t *testing.T) {
 
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
 			rec := httptest.NewRecorder()
 
 			c := e.NewContext(req, rec)
 
+			dataFile := filepath.Join(t.TempDir(), tt.field.dataFile)
+
 			cf := &web.ConfigWeb{
-				Log:    zap,
-				MicroR: &iotc.FileConfigRead{},
+				Log: zap,
+				MicroR: &iotc.FileConfigRead{
+					Log:      zap,
+					DataFile: dataFile,
+				},
 				MicroW: &iotc.FileConfigWrite{
 					Log:      zap,
 					Hub:      tt.field.hubf(),
 					Config:   config.Default(),
-					DataFile: tt.field.dataFile,
+					DataFile: dataFile,
 				},
 			}
 
 			_ = cf.Save(c)
 
+			assert.Equal(t, tt.resStatus, rec.Code)
+
 			if rec.Code == http.StatusOK {
-				if err := os.Remove(tt.field.dataFile); err != nil {
-					require.Error(t, err, "Removing micro config file created")
-				}
+				assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
 			}
+		})
+	}
+}
 
-			assert.Equal(t, tt.resStatus, rec.Code)
+// historyConfigWeb creates the handler with two saved revisions,
+// the first one with wakeup 20 and the second one with wakeup 30
+func historyConfigWeb(t *testing.T) *web.ConfigWeb {
+	t.Helper()
+
+	dataFile := filepath.Join(t.TempDir(), "micro-config.dat")
+
+	h := mocks.NewHub(t)
+	h.On("Config", mock.Anything).Maybe()
+
+	w := &iotc.FileConfigWrite{
+		Log:      zap.NewExample(),
+		Hub:      h,
+		Config:   config.Default(),
+		DataFile: dataFile,
+	}
+
+	for _, wakeup := range []uint8{20, 30} {
+		cnf := iotc.DefaultConfig()
+		cnf.Wakeup = wakeup
+
+		_, err := w.Save(cnf, "admin")
+		require.NoError(t, err)
+	}
+
+	return &web.ConfigWeb{
+		Log: zap.NewExample(),
+		MicroR: &iotc.FileConfigRead{
+			Log:      zap.NewExample(),
+			DataFile: dataFile,
+		},
+		MicroW:  w,
+		History: w,
+	}
+}
+
+func TestConfigWeb_LoadRevision(t *testing.T) {
+	t.Parallel()
+
+	cf := historyConfigWeb(t)
+
+	// The configuration read before the latest save is not returned
+	cf.MicroR = &iotc.FileConfigRead{
+		Log:      zap.NewExample(),
+		DataFile: "./testr/micro-config.dat",
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/config", nil)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, cf.Load(echo.New().NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	var data iotc.Config
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &data))
+	assert.Equal(t, uint8(30), data.Wakeup, "Config of the revision")
+}
+
+func TestConfigWeb_Revisions(t *testing.T) {
+	t.Parallel()
+
+	cf := historyConfigWeb(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/config/history", nil)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, cf.Revisions(echo.New().NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var revs []iotc.Revision
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &revs))
+	require.Len(t, revs, 2)
+	assert.Equal(t, 2, revs[0].Rev)
+	assert.Equal(t, uint8(30), revs[0].Config.Wakeup)
+	assert.Equal(t, "admin", revs[1].Author)
+}
+
+func TestConfigWeb_Diff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		body   string
+	}{
+		{
+			name:   "Diff. It should return the changes against the latest",
+			query:  "from=1",
+			status: http.StatusOK,
+			body: `{"from":1,"to":2,"changes":[` +
+				`{"field":"wakeup","before":20,"after":30}]}`,
+		},
+		{
+			name:   "Diff. It should return the changes between revisions",
+			query:  "from=2&to=1",
+			status: http.StatusOK,
+			body: `{"from":2,"to":1,"changes":[` +
+				`{"field":"wakeup","before":30,"after":20}]}`,
+		},
+		{
+			name:   "Diff. It should fail if the revision is not a number",
+			query:  "from=a",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Diff. It should fail if the revision does not exist",
+			query:  "from=1&to=3",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cf := historyConfigWeb(t)
+
+			req := httptest.NewRequest(
+				http.MethodGet, "/config/diff?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			require.NoError(t, cf.Diff(echo.New().NewContext(req, rec)))
+
+			assert.Equal(t, tt.status, rec.Code)
+
+			if tt.body != "" {
+				assert.JSONEq(t, tt.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestConfigWeb_Rollback(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		rev    string
+		status int
+	}{
+		{
+			name:   "Rollback. It should save the revision as the latest",
+			rev:    "1",
+			status: http.StatusOK,
+		},
+		{
+			name:   "Rollback. It should fail if the revision is not a number",
+			rev:    "a",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Rollback. It should fail if the revision does not exist",
+			rev:    "5",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cf := historyConfigWeb(t)
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			rec := httptest.NewRecorder()
+
+			c := echo.New().NewContext(req, rec)
+			c.SetParamNames("rev")
+			c.SetParamValues(tt.rev)
+			c.Set(web.PrincipalKey, web.Principal{Name: "operator"})
+
+			require.NoError(t, cf.Rollback(c))
+
+			assert.Equal(t, tt.status, rec.Code)
+
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var rev iotc.Revision
+
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rev))
+			assert.Equal(t, 3, rev.Rev)
+			assert.Equal(t, "operator", rev.Author)
+			assert.Equal(t, uint8(20), rev.Config.Wakeup)
+
+			changes, ok := c.Get(web.AuditChangesKey).([]audit.Change)
+			require.True(t, ok, "Audit changes")
+			assert.Len(t, changes, 1)
+
+			cnf, err := cf.MicroR.Read()
+			require.NoError(t, err)
+			assert.Equal(t, uint8(20), cnf.Wakeup)
+		})
+	}
+}
+
+func TestConfigWeb_Save_Invalid(t *testing.T) {
+	t.Parallel()
+
+	body := strings.NewReader(`{"iniSendTime": "25:99",
+		"endSendTime": "21:00", "wakeup": 0, "buffer": 0,
+		"stabilizationTime": -1}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/config", body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("If-Match", "*")
+
+	rec := httptest.NewRecorder()
+
+	dataFile := filepath.Join(t.TempDir(), "micro-config.dat")
+
+	cf := &web.ConfigWeb{
+		Log: zap.NewExample(),
+		MicroR: &iotc.FileConfigRead{
+			Log:      zap.NewExample(),
+			DataFile: dataFile,
+		},
+		MicroW: &iotc.FileConfigWrite{
+			Log:      zap.NewExample(),
+			Hub:      mocks.NewHub(t),
+			Config:   config.Default(),
+			DataFile: dataFile,
+		},
+	}
+
+	require.NoError(t, cf.Save(echo.New().NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t,
+		"application/problem+json", rec.Header().Get("Content-Type"))
+
+	var p web.Problem
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	assert.Equal(t, http.StatusUnprocessableEntity, p.Status)
+
+	fields := make([]string, 0, len(p.Errors))
+	for _, e := range p.Errors {
+		fields = append(fields, e.Field)
+	}
+
+	assert.Equal(t,
+		[]string{"iniSendTime", "wakeup", "buffer", "stabilizationTime"},
+		fields)
+
+	assert.NoFileExists(t, dataFile, "Not saved")
+}
+
+func TestConfigWeb_Schema(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/config/schema", nil)
+	rec := httptest.NewRecorder()
+
+	cf := &web.ConfigWeb{Log: zap.NewExample()}
+
+	require.NoError(t, cf.Schema(echo.New().NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var s iotc.Schema
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &s))
+	assert.Equal(t, iotc.SchemaDialect, s.Schema)
+	assert.Len(t, s.Properties, len(iotc.ConfigRules))
+	assert.Equal(t, "integer", s.Properties["wakeup"].Type)
+	assert.InDelta(t, 30, s.Properties["wakeup"].Default, 0)
+}
+
+func TestConfigWeb_DeliveryStatus(t *testing.T) {
+	t.Parallel()
+
+	applied := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status iot.ConfigStatus
+		err    error
+		code   int
+		body   string
+	}{
+		{
+			name:   "DeliveryStatus. It should return the pending config",
+			status: iot.ConfigStatus{Version: 2, Pending: true},
+			code:   http.StatusOK,
+			body:   `{"version":2,"pending":true}`,
+		},
+		{
+			name: "DeliveryStatus. It should return when it was applied",
+			status: iot.ConfigStatus{
+				Version: 2, Pending: false, Applied: &applied,
+			},
+			code: http.StatusOK,
+			body: `{"version":2,"pending":false,` +
+				`"applied":"2024-05-01T10:00:00Z"}`,
+		},
+		{
+			name: "DeliveryStatus. It should fail if the hub does not respond",
+			err:  context.DeadlineExceeded,
+			code: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := wmocks.NewConfigStatuser(t)
+			s.On("ConfigStatus", mock.Anything).Return(tt.status, tt.err)
+
+			cf := &web.ConfigWeb{Log: zap.NewExample(), Status: s}
+
+			req := httptest.NewRequest(http.MethodGet, "/config/status", nil)
+			rec := httptest.NewRecorder()
+
+			c := echo.New().NewContext(req, rec)
+
+			require.NoError(t, cf.DeliveryStatus(c))
+
+			assert.Equal(t, tt.code, rec.Code)
+
+			if tt.body != "" {
+				assert.JSONEq(t, tt.body, rec.Body.String())
+			}
 		})
 	}
 }
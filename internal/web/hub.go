@@ -17,7 +17,12 @@
 
 package web
 
-import "github.com/swpoolcontroller/pkg/iot"
+import (
+	"context"
+	"time"
+
+	"github.com/swpoolcontroller/pkg/iot"
+)
 
 type Hub interface {
 	// RegisterClient registers client into the hub
@@ -25,4 +30,19 @@ type Hub interface {
 
 	// UnregisterClient unregisters client into the hub
 	UnregisterClient(id string)
+
+	// RefreshClient extends the expiration of the client
+	RefreshClient(ctx context.Context, id string, expiration time.Duration) error
+}
+
+// ConfigStatuser gets the delivery of the config to the micro controller
+type ConfigStatuser interface {
+	// ConfigStatus gets whether the config is pending or applied
+	ConfigStatus(ctx context.Context) (iot.ConfigStatus, error)
+}
+
+// MetricsReader reads the latest buffer of readings of the device
+type MetricsReader interface {
+	// Metrics gets the latest buffer and when it was received
+	Metrics(ctx context.Context) (iot.Metrics, error)
 }
@@ -0,0 +1,282 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+	"github.com/swpoolcontroller/internal/account"
+	"github.com/swpoolcontroller/internal/config"
+	"go.uber.org/zap"
+)
+
+var errSessionExpired = errors.New("The session cannot be renewed anymore")
+
+const (
+	errLocalRequest  = "Local. Getting the credentials of the request body"
+	errLocalLogin    = "Local. Login failed"
+	errLocalToken    = "Local. Signing the session token"
+	errLocalPassword = "Local. Changing the password"
+)
+
+const (
+	infLocalPassword = "Local. Changing the password"
+)
+
+const (
+	// RedirectLocalLogin is the UI page with the login form
+	RedirectLocalLogin = "/auth/local"
+	// claimAuthTime is the time of the login. The session
+	// cannot be renewed beyond Web.RefreshExpiration from it
+	claimAuthTime = "auth_time"
+)
+
+// LocalAccounts manages the local user accounts
+type LocalAccounts interface {
+	Authenticate(
+		origin string,
+		username string,
+		password string,
+		code string) (account.User, error)
+	User(username string) (account.User, error)
+	ChangePassword(username string, current string, password string) error
+}
+
+// TokenSigner issues and parses the session tokens of the local accounts
+type TokenSigner interface {
+	TokenParser
+	Sign(claims jwt.MapClaims) (string, error)
+}
+
+// PasswordChanger changes the password of the authenticated user
+type PasswordChanger interface {
+	ChangePassword(ctx echo.Context) error
+}
+
+// credentialsDTO is the login form
+type credentialsDTO struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Code is the TOTP code. Only if the user has enabled it
+	Code string `json:"code"`
+}
+
+// passwordDTO is the change password form
+type passwordDTO struct {
+	Current  string `json:"current"`
+	Password string `json:"password"`
+}
+
+// AuthFlowLocal manages authentication of the local accounts.
+// The session token is a JWT signed by the app
+type AuthFlowLocal struct {
+	Log      *zap.Logger
+	Accounts LocalAccounts
+	Signer   TokenSigner
+	Hub      Hub
+	Config   config.Config
+	// Tokens denies the session tokens on logout. It can be nil
+	Tokens *DenylistParser
+}
+
+// Login checks the credentials of the form and starts the session.
+// The failed logins are limited by user and IP
+func (o *AuthFlowLocal) Login(ctx echo.Context) error {
+	var cred credentialsDTO
+
+	if err := ctx.Bind(&cred); err != nil {
+		o.Log.Error(errLocalRequest, zap.Error(err))
+
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	o.Log.Info(infLogin, zap.String("User", cred.Username))
+	ctx.Set(AuditUserKey, cred.Username)
+
+	u, err := o.Accounts.Authenticate(
+		ctx.RealIP(),
+		cred.Username,
+		cred.Password,
+		cred.Code)
+	if err != nil {
+		o.Log.Warn(
+			errLocalLogin,
+			zap.String("User", cred.Username),
+			zap.String("IP", ctx.RealIP()),
+			zap.Error(err))
+
+		switch {
+		case errors.Is(err, account.ErrLocked):
+			return ctx.NoContent(http.StatusTooManyRequests)
+		case errors.Is(err, account.ErrInvalidCredentials):
+			return ctx.NoContent(http.StatusUnauthorized)
+		}
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	token, err := o.token(u, time.Now())
+	if err != nil {
+		o.Log.Error(errLocalToken, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	setSessionCookies(ctx, o.Config, token, xid.New().String())
+
+	return ctx.NoContent(http.StatusOK)
+}
+
+// Logout deletes cookies
+func (o *AuthFlowLocal) Logout(ctx echo.Context) error {
+	o.Log.Info(infLogoff)
+
+	closeSession(ctx, o.Log, o.Config, o.Hub, o.Tokens)
+
+	return ctx.Redirect(http.StatusFound, RedirectLoginOk)
+}
+
+// Refresh issues a new session token while the current one is valid
+// for the session found by the renewal middleware.
+// The user is read again, so a role change is applied
+// and a deleted user cannot renew the session
+func (o *AuthFlowLocal) Refresh(ctx echo.Context) error {
+	o.Log.Info(infRefresh)
+
+	id, ok := renewedSession(ctx, o.Log)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	c, err := ctx.Cookie(AuthHeaderName)
+	if err != nil {
+		o.Log.Error(errRefresh, zap.Error(err))
+
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	token, err := o.renew(c.Value)
+	if err != nil {
+		o.Log.Error(errRefresh, zap.Error(err))
+
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	setSessionCookies(ctx, o.Config, token, id)
+	refreshClient(ctx, o.Log, o.Hub, id, sessionExpiration(o.Config))
+
+	return ctx.NoContent(http.StatusOK)
+}
+
+// ChangePassword changes the password of the authenticated user
+func (o *AuthFlowLocal) ChangePassword(ctx echo.Context) error {
+	p, ok := ctx.Get(PrincipalKey).(Principal)
+	if !ok {
+		o.Log.Error(errNoPrincipal)
+
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	var pwd passwordDTO
+
+	if err := ctx.Bind(&pwd); err != nil {
+		o.Log.Error(errLocalRequest, zap.Error(err))
+
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	o.Log.Info(infLocalPassword, zap.String("User", p.Subject))
+
+	if err := o.Accounts.ChangePassword(
+		p.Subject,
+		pwd.Current,
+		pwd.Password); err != nil {
+		//
+		o.Log.Warn(
+			errLocalPassword,
+			zap.String("User", p.Subject),
+			zap.Error(err))
+
+		switch {
+		case errors.Is(err, account.ErrInvalidCredentials):
+			return ctx.NoContent(http.StatusForbidden)
+		case errors.Is(err, account.ErrWeakPassword):
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	return ctx.NoContent(http.StatusOK)
+}
+
+// renew validates the current token and signs a new one
+// keeping the time of the login
+func (o *AuthFlowLocal) renew(current string) (string, error) {
+	t, err := o.Signer.ParseJWT(current)
+	if err != nil {
+		return "", err
+	}
+
+	if o.Tokens != nil && o.Tokens.Revoked(t) {
+		return "", errTokenRevoked
+	}
+
+	claims, _ := t.Claims.(jwt.MapClaims)
+	sub, _ := claims.GetSubject()
+	at, _ := claims[claimAuthTime].(float64)
+	authTime := time.Unix(int64(at), 0)
+
+	limit := authTime.Add(
+		time.Duration(o.Config.Web.RefreshExpiration) * time.Minute)
+
+	if o.Config.Web.RefreshExpiration <= 0 || limit.Before(time.Now()) {
+		return "", errSessionExpired
+	}
+
+	u, err := o.Accounts.User(sub)
+	if err != nil {
+		return "", err
+	}
+
+	return o.token(u, authTime)
+}
+
+// token signs the session token. The role is written in the roles claim
+// so the Authorizer reads it as any other provider
+func (o *AuthFlowLocal) token(
+	u account.User,
+	authTime time.Time) (string, error) {
+	//
+	now := time.Now()
+
+	return o.Signer.Sign(jwt.MapClaims{
+		"jti":                    xid.New().String(),
+		"sub":                    u.Username,
+		"username":               u.Username,
+		o.Config.Auth.RolesClaim: []string{u.Role},
+		claimAuthTime:            authTime.Unix(),
+		"iat":                    now.Unix(),
+		"exp":                    now.Add(sessionExpiration(o.Config)).Unix(),
+	})
+}
@@ -0,0 +1,255 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/account"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/internal/session"
+	"github.com/swpoolcontroller/internal/web"
+	"github.com/swpoolcontroller/internal/web/mocks"
+	"github.com/swpoolcontroller/pkg/auth"
+	"go.uber.org/zap"
+)
+
+func newAuthFlowLocal(t *testing.T) (*web.AuthFlowLocal, *mocks.LocalAccounts) {
+	t.Helper()
+
+	acc := mocks.NewLocalAccounts(t)
+
+	return &web.AuthFlowLocal{
+		Log:      zap.NewExample(),
+		Accounts: acc,
+		Signer:   &auth.HMACJWT{Key: []byte("key"), Issuer: "swpc"},
+		Config:   config.Default(),
+	}, acc
+}
+
+func TestAuthFlowLocal_Login(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		body    string
+		mockErr error
+		mock    bool
+		status  int
+		cookies int
+	}{
+		{
+			name:    "Login. It should return StatusOK with the session",
+			body:    `{"username":"user","password":"password1"}`,
+			mock:    true,
+			status:  http.StatusOK,
+			cookies: 3,
+		},
+		{
+			name:    "Login with wrong password. It should return StatusUnauthorized",
+			body:    `{"username":"user","password":"password1"}`,
+			mock:    true,
+			mockErr: account.ErrInvalidCredentials,
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name:    "Login locked. It should return StatusTooManyRequests",
+			body:    `{"username":"user","password":"password1"}`,
+			mock:    true,
+			mockErr: account.ErrLocked,
+			status:  http.StatusTooManyRequests,
+		},
+		{
+			name:   "Login with bad body. It should return StatusBadRequest",
+			body:   `{`,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			o, acc := newAuthFlowLocal(t)
+
+			if tt.mock {
+				acc.On("Authenticate", "192.0.2.1", "user", "password1", "").
+					Return(account.User{Username: "user", Role: "admin"}, tt.mockErr)
+			}
+
+			req := httptest.NewRequest(
+				http.MethodPost,
+				web.RedirectLogin,
+				strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.RemoteAddr = "192.0.2.1:1234"
+
+			rec := httptest.NewRecorder()
+
+			_ = o.Login(echo.New().NewContext(req, rec))
+
+			assert.Equal(t, tt.status, rec.Code)
+
+			r := rec.Result()
+			defer r.Body.Close()
+
+			assert.Len(t, r.Cookies(), tt.cookies, "Cookies")
+		})
+	}
+}
+
+func TestAuthFlowLocal_Refresh(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		authTime   time.Time
+		registered bool
+		status     int
+	}{
+		{
+			name:       "Refresh. It should return StatusOK",
+			authTime:   time.Now(),
+			registered: true,
+			status:     http.StatusOK,
+		},
+		{
+			name:       "Refresh beyond the refresh expiration. It should return StatusUnauthorized",
+			authTime:   time.Now().Add(-48 * time.Hour),
+			registered: true,
+			status:     http.StatusUnauthorized,
+		},
+		{
+			name:     "Refresh of an unregistered session. It should return StatusUnauthorized",
+			authTime: time.Now(),
+			status:   http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			o, acc := newAuthFlowLocal(t)
+			h := mocks.NewHub(t)
+			o.Hub = h
+
+			s, _ := newSessions(t)
+			s.Tokens = &web.DenylistParser{
+				Parser:   o.Signer,
+				Denylist: auth.NewDenylist(),
+			}
+
+			if tt.registered {
+				s.Registry.Open(session.Session{
+					ID:      "123",
+					Expires: time.Now().Add(time.Minute),
+					TokenID: "1",
+				})
+			}
+
+			token, err := o.Signer.Sign(jwt.MapClaims{
+				"jti":       "1",
+				"sub":       "user",
+				"auth_time": tt.authTime.Unix(),
+				"exp":       time.Now().Add(time.Minute).Unix(),
+			})
+			require.NoError(t, err)
+
+			if tt.status == http.StatusOK {
+				acc.On("User", "user").
+					Return(account.User{Username: "user", Role: "viewer"}, nil)
+				h.On(
+					"RefreshClient",
+					mock.Anything,
+					"123",
+					time.Duration(o.Config.Web.SessionExpiration)*time.Minute).
+					Return(nil)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, web.RefreshPath, nil)
+			req.AddCookie(&http.Cookie{Name: web.WSClientIDName, Value: "123"})
+			req.AddCookie(&http.Cookie{Name: web.AuthHeaderName, Value: token})
+
+			rec := httptest.NewRecorder()
+
+			_ = s.Renewal()(o.Refresh)(echo.New().NewContext(req, rec))
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestAuthFlowLocal_ChangePassword(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		mockErr error
+		status  int
+	}{
+		{
+			name:   "Change password. It should return StatusOK",
+			status: http.StatusOK,
+		},
+		{
+			name:    "Change password with wrong current. It should return StatusForbidden",
+			mockErr: account.ErrInvalidCredentials,
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "Change password with weak password. It should return StatusBadRequest",
+			mockErr: account.ErrWeakPassword,
+			status:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			o, acc := newAuthFlowLocal(t)
+
+			acc.On("ChangePassword", "user", "password1", "password2").
+				Return(tt.mockErr)
+
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/api/web/password",
+				strings.NewReader(`{"current":"password1","password":"password2"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.Set(web.PrincipalKey, web.Principal{Subject: "user", Name: "user"})
+
+			_ = o.ChangePassword(c)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
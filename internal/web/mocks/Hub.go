@@ -3,6 +3,9 @@
 package mocks
 
 import (
+	"context"
+	"time"
+
 	mock "github.com/stretchr/testify/mock"
 	"github.com/swpoolcontroller/pkg/iot"
 )
@@ -22,6 +25,20 @@ func (_m *Hub) UnregisterClient(id string) {
 	_m.Called(id)
 }
 
+// RefreshClient provides a mock function with given fields: ctx, id, expiration
+func (_m *Hub) RefreshClient(ctx context.Context, id string, expiration time.Duration) error {
+	ret := _m.Called(ctx, id, expiration)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) error); ok {
+		r0 = rf(ctx, id, expiration)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewHub interface {
 	mock.TestingT
 	Cleanup(func())
@@ -3,7 +3,6 @@
 package mocks
 
 import (
-	jwt "github.com/golang-jwt/jwt/v5"
 	auth "github.com/swpoolcontroller/pkg/auth"
 
 	mock "github.com/stretchr/testify/mock"
@@ -14,6 +13,27 @@ type OAuth2 struct {
 	mock.Mock
 }
 
+// Refresh provides a mock function with given fields: params
+func (_m *OAuth2) Refresh(params auth.OA2RefreshTokenInput) (auth.OA2Token, error) {
+	ret := _m.Called(params)
+
+	var r0 auth.OA2Token
+	if rf, ok := ret.Get(0).(func(auth.OA2RefreshTokenInput) auth.OA2Token); ok {
+		r0 = rf(params)
+	} else {
+		r0 = ret.Get(0).(auth.OA2Token)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(auth.OA2RefreshTokenInput) error); ok {
+		r1 = rf(params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RevokeToken provides a mock function with given fields: params
 func (_m *OAuth2) RevokeToken(params auth.OA2RevokeTokenInput) error {
 	ret := _m.Called(params)
@@ -29,16 +49,14 @@ func (_m *OAuth2) RevokeToken(params auth.OA2RevokeTokenInput) error {
 }
 
 // Token provides a mock function with given fields: params
-func (_m *OAuth2) Token(params auth.OA2TokenInput) (*jwt.Token, error) {
+func (_m *OAuth2) Token(params auth.OA2TokenInput) (auth.OA2Token, error) {
 	ret := _m.Called(params)
 
-	var r0 *jwt.Token
-	if rf, ok := ret.Get(0).(func(auth.OA2TokenInput) *jwt.Token); ok {
+	var r0 auth.OA2Token
+	if rf, ok := ret.Get(0).(func(auth.OA2TokenInput) auth.OA2Token); ok {
 		r0 = rf(params)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*jwt.Token)
-		}
+		r0 = ret.Get(0).(auth.OA2Token)
 	}
 
 	var r1 error
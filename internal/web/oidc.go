@@ -0,0 +1,282 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/pkg/auth"
+	"github.com/swpoolcontroller/pkg/crypto"
+	"go.uber.org/zap"
+)
+
+const (
+	errOIDCFlow      = "OIDC. Generating the authorization flow"
+	errOIDCFlowRead  = "OIDC. Reading the authorization flow cookie"
+	errOIDCFlowState = "OIDC. The state does not match the flow"
+)
+
+const (
+	infSignIn = "OIDC. Redirecting to the provider authorization endpoint"
+)
+
+const (
+	// OIDCFlowName is the cookie that keeps the PKCE verifier
+	// and the nonce during the authorization round trip
+	OIDCFlowName = "OIDCFlow"
+	// RedirectSignIn starts the authorization flow
+	RedirectSignIn = "/auth/signin"
+)
+
+// oidcFlowMaxAge is the time allowed to complete the login in the provider
+const oidcFlowMaxAge = 10 * time.Minute
+
+// OIDCService manages an OpenID Connect provider
+type OIDCService interface {
+	AuthURL(
+		redirectURI string,
+		state string,
+		nonce string,
+		challenge string) string
+	LogoutURL(redirectURI string) string
+	Token(params auth.OIDCTokenInput) (auth.OIDCToken, error)
+	Refresh(refreshToken string) (auth.OIDCToken, error)
+	Revoke(token string, hint string) error
+}
+
+// SignIner starts the authorization flow in the provider
+type SignIner interface {
+	SignIn(ctx echo.Context) error
+}
+
+// oidcFlow is the information that is kept in the browser encrypted
+// between the sign in and the login
+type oidcFlow struct {
+	State    string `json:"s"`
+	Verifier string `json:"v"`
+	Nonce    string `json:"n"`
+}
+
+// AuthFlowOIDC manages authentication of OpenID Connect providers
+// using discovery and the authorization code flow with PKCE
+type AuthFlowOIDC struct {
+	Log     *zap.Logger
+	Service OIDCService
+	States  StateStore
+	Hub     Hub
+	Config  config.Config
+	// Tokens denies the session tokens on logout. It can be nil
+	Tokens *DenylistParser
+}
+
+// SignIn generates the state, the PKCE verifier and the nonce,
+// and redirects to the provider
+func (o *AuthFlowOIDC) SignIn(ctx echo.Context) error {
+	o.Log.Info(infSignIn)
+
+	state, err := o.States.Issue()
+	if err != nil {
+		o.Log.Error(errOIDCFlow, zap.Error(err))
+
+		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
+	}
+
+	pkce, err := auth.NewPKCE()
+	if err != nil {
+		o.Log.Error(errOIDCFlow, zap.Error(err))
+
+		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
+	}
+
+	nonce, err := auth.RandomString(16)
+	if err != nil {
+		o.Log.Error(errOIDCFlow, zap.Error(err))
+
+		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
+	}
+
+	flow := oidcFlow{State: state, Verifier: pkce.Verifier, Nonce: nonce}
+
+	value, err := o.encodeFlow(flow)
+	if err != nil {
+		o.Log.Error(errOIDCFlow, zap.Error(err))
+
+		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
+	}
+
+	cookie := cookies(OIDCFlowName, value, time.Now().Add(oidcFlowMaxAge))
+	cookie.Secure = o.Config.External.TLS
+	// The provider redirects to the login, so the cookie
+	// must be sent in a top-level navigation from another site
+	cookie.SameSite = http.SameSiteLaxMode
+	ctx.SetCookie(cookie)
+
+	return ctx.Redirect(
+		http.StatusFound,
+		o.Service.AuthURL(
+			o.Config.AuthRedirectURI(RedirectLogin),
+			state,
+			nonce,
+			pkce.Challenge))
+}
+
+// Login exchanges the code using the PKCE verifier
+// and validates the id token
+func (o *AuthFlowOIDC) Login(ctx echo.Context) error {
+	state := ctx.QueryParam("state")
+	code := ctx.QueryParam("code")
+
+	o.Log.Info(infLogin, zap.String("Code", code), zap.String("State", state))
+
+	flow, ok := o.readFlow(ctx)
+	if !ok {
+		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
+	}
+
+	if flow.State != state {
+		o.Log.Error(errOIDCFlowState)
+
+		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
+	}
+
+	if err := o.States.Consume(state); err != nil {
+		o.Log.Error(errStateInvalid, zap.Error(err))
+
+		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
+	}
+
+	token, err := o.Service.Token(auth.OIDCTokenInput{
+		Code:        code,
+		RedirectURI: o.Config.AuthRedirectURI(RedirectLogin),
+		Verifier:    flow.Verifier,
+		Nonce:       flow.Nonce,
+	})
+	if err != nil || !token.IDToken.Valid {
+		o.Log.Error(errTkInvalid, zap.Error(err))
+
+		return ctx.Redirect(http.StatusFound, RedirectErrorAuth)
+	}
+
+	ctx.Set(AuditUserKey, tokenName(token.IDToken))
+	setSessionCookies(ctx, o.Config, token.IDToken.Raw, xid.New().String())
+	setRefreshCookie(ctx, o.Log, o.Config, token.RefreshToken)
+
+	return ctx.Redirect(http.StatusFound, RedirectLoginOk)
+}
+
+// Refresh renews the id token using the refresh token
+func (o *AuthFlowOIDC) Refresh(ctx echo.Context) error {
+	return refreshSession(
+		ctx,
+		o.Log,
+		o.Config,
+		o.Hub,
+		func(refreshToken string) (string, string, error) {
+			token, err := o.Service.Refresh(refreshToken)
+			if err != nil {
+				return "", "", err
+			}
+
+			if !token.IDToken.Valid {
+				return "", "", errTokenNotValid
+			}
+
+			return token.IDToken.Raw, token.RefreshToken, nil
+		})
+}
+
+// Logout deletes cookies
+func (o *AuthFlowOIDC) Logout(ctx echo.Context) error {
+	o.Log.Info(infLogoff)
+
+	// The session token is the id token, which cannot be revoked
+	revokeToken(
+		o.Log,
+		refreshToken(ctx, o.Config),
+		auth.TokenHintRefresh,
+		o.Service.Revoke)
+
+	closeSession(ctx, o.Log, o.Config, o.Hub, o.Tokens)
+
+	return ctx.Redirect(http.StatusFound, RedirectLoginOk)
+}
+
+// readFlow reads and removes the flow cookie. The flow is single use
+func (o *AuthFlowOIDC) readFlow(ctx echo.Context) (oidcFlow, bool) {
+	c, err := ctx.Cookie(OIDCFlowName)
+	if err != nil {
+		o.Log.Error(errOIDCFlowRead, zap.Error(err))
+
+		return oidcFlow{}, false
+	}
+
+	cookie := cookies(OIDCFlowName, "", time.Time{})
+	cookie.MaxAge = -1
+	cookie.Secure = o.Config.External.TLS
+	ctx.SetCookie(cookie)
+
+	flow, err := o.decodeFlow(c.Value)
+	if err != nil {
+		o.Log.Error(errOIDCFlowRead, zap.Error(err))
+
+		return oidcFlow{}, false
+	}
+
+	return flow, true
+}
+
+func (o *AuthFlowOIDC) encodeFlow(flow oidcFlow) (string, error) {
+	f, err := json.Marshal(flow)
+	if err != nil {
+		return "", errors.Wrap(err, errOIDCFlow)
+	}
+
+	fe, err := crypto.Encrypt([]byte(o.Config.Web.SecretKey), f)
+	if err != nil {
+		return "", errors.Wrap(err, errOIDCFlow)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(fe), nil
+}
+
+func (o *AuthFlowOIDC) decodeFlow(value string) (oidcFlow, error) {
+	fe, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return oidcFlow{}, errors.Wrap(err, errOIDCFlowRead)
+	}
+
+	f, err := crypto.Decrypt([]byte(o.Config.Web.SecretKey), fe)
+	if err != nil {
+		return oidcFlow{}, errors.Wrap(err, errOIDCFlowRead)
+	}
+
+	var flow oidcFlow
+
+	if err := json.Unmarshal(f, &flow); err != nil {
+		return oidcFlow{}, errors.Wrap(err, errOIDCFlowRead)
+	}
+
+	return flow, nil
+}
@@ -0,0 +1,164 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/internal/web"
+	"github.com/swpoolcontroller/internal/web/mocks"
+	"github.com/swpoolcontroller/pkg/auth"
+	"go.uber.org/zap"
+)
+
+func TestAuthFlowOIDC_SignIn_Login(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	svc := mocks.NewOIDCService(t)
+
+	o := &web.AuthFlowOIDC{
+		Log:     zap.NewExample(),
+		Service: svc,
+		Config:  config.Default(),
+		States:  newStateIssuer(config.Default()),
+	}
+
+	var state, nonce string
+
+	svc.On(
+		"AuthURL",
+		o.Config.AuthRedirectURI(web.RedirectLogin),
+		mock.Anything,
+		mock.Anything,
+		mock.Anything).
+		Run(func(args mock.Arguments) {
+			state = args.String(1)
+			nonce = args.String(2)
+		}).
+		Return("https://idp/authorize")
+
+	req := httptest.NewRequest(http.MethodGet, web.RedirectSignIn, nil)
+	rec := httptest.NewRecorder()
+
+	_ = o.SignIn(e.NewContext(req, rec))
+
+	require.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://idp/authorize", rec.Header().Get("Location"))
+
+	r := rec.Result()
+	defer r.Body.Close()
+
+	require.Len(t, r.Cookies(), 1, "Flow cookie")
+
+	flow := r.Cookies()[0]
+
+	svc.On("Token", mock.MatchedBy(func(p auth.OIDCTokenInput) bool {
+		return p.Code == "code" && p.Nonce == nonce && p.Verifier != ""
+	})).Return(
+		auth.OIDCToken{IDToken: &jwt.Token{Raw: "id", Valid: true}},
+		nil)
+
+	q := url.Values{}
+	q.Set("state", state)
+	q.Set("code", "code")
+
+	req = httptest.NewRequest(http.MethodGet, "/login?"+q.Encode(), nil)
+	req.AddCookie(flow)
+
+	rec = httptest.NewRecorder()
+
+	_ = o.Login(e.NewContext(req, rec))
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, web.RedirectLoginOk, rec.Header().Get("Location"))
+
+	r = rec.Result()
+	defer r.Body.Close()
+
+	// Flow removed, token, auth check and client id
+	assert.Len(t, r.Cookies(), 4, "Cookies")
+}
+
+func TestAuthFlowOIDC_Login_Without_Flow(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/login?state=1&code=2", nil)
+	rec := httptest.NewRecorder()
+
+	o := &web.AuthFlowOIDC{
+		Log:     zap.NewExample(),
+		Service: mocks.NewOIDCService(t),
+		Config:  config.Default(),
+		States:  newStateIssuer(config.Default()),
+	}
+
+	_ = o.Login(e.NewContext(req, rec))
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, web.RedirectErrorAuth, rec.Header().Get("Location"))
+}
+
+func TestAuthFlowOIDC_Logout(t *testing.T) {
+	t.Parallel()
+
+	s := mocks.NewOIDCService(t)
+	h := mocks.NewHub(t)
+	tokens, session := sessionToken(t)
+
+	o := &web.AuthFlowOIDC{
+		Log:     zap.NewExample(),
+		Service: s,
+		Hub:     h,
+		Config:  config.Default(),
+		Tokens:  tokens,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: web.WSClientIDName, Value: "123"})
+	req.AddCookie(&http.Cookie{Name: web.AuthHeaderName, Value: session})
+	req.AddCookie(loginRefreshCookie(t, &web.AuthFlow{
+		Log:    o.Log,
+		Config: o.Config,
+	}))
+
+	// The id token cannot be revoked, only the refresh token
+	s.On("Revoke", "refresh", auth.TokenHintRefresh).Return(nil)
+	h.On("UnregisterClient", "123")
+
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+
+	_ = o.Logout(ctx)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "alice", ctx.Get(web.AuditUserKey))
+
+	_, err := tokens.ParseJWT(session)
+	require.Error(t, err, "The session token must be denied")
+}
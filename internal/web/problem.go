@@ -0,0 +1,60 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/swpoolcontroller/internal/iot"
+)
+
+const (
+	// mimeProblemJSON is the media type of the problem details
+	mimeProblemJSON = "application/problem+json"
+	// problemType has no more semantics than the status
+	problemType = "about:blank"
+)
+
+// Problem is the problem details of a failed request, RFC 9457
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Errors are the invalid fields of the request
+	Errors []iot.FieldError `json:"errors,omitempty"`
+}
+
+// validationProblem responds the invalid fields with
+// the unprocessable entity status
+func validationProblem(
+	ctx echo.Context,
+	detail string,
+	errs iot.ValidationError) error {
+	//
+	ctx.Response().Header().Set(echo.HeaderContentType, mimeProblemJSON)
+
+	return ctx.JSON(http.StatusUnprocessableEntity, Problem{
+		Type:   problemType,
+		Title:  http.StatusText(http.StatusUnprocessableEntity),
+		Status: http.StatusUnprocessableEntity,
+		Detail: detail,
+		Errors: errs,
+	})
+}
@@ -0,0 +1,173 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/swpoolcontroller/internal/config"
+	"go.uber.org/zap"
+)
+
+var errTokenRevoked = errors.New("The token has been revoked")
+
+const (
+	errRevokeProvider = "Auth.Revoking the token at the provider"
+)
+
+const (
+	infRevokeSession = "Auth.The session token has been revoked"
+)
+
+// TokenDenylist keeps the revoked session tokens until they expire
+type TokenDenylist interface {
+	Deny(id string, expiration time.Time)
+	Denied(id string) bool
+}
+
+// DenylistParser parses the session tokens rejecting those
+// revoked by a logout
+type DenylistParser struct {
+	Parser   TokenParser
+	Denylist TokenDenylist
+}
+
+// ParseJWT parses the token and checks that it has not been revoked
+func (p *DenylistParser) ParseJWT(tokenString string) (*jwt.Token, error) {
+	token, err := p.Parser.ParseJWT(tokenString)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if p.Revoked(token) {
+		return nil, errTokenRevoked
+	}
+
+	return token, nil
+}
+
+// Revoked checks whether the token is in the denylist
+func (p *DenylistParser) Revoked(token *jwt.Token) bool {
+	return p.Denylist.Denied(tokenID(token))
+}
+
+// Revoke adds the token to the denylist until it expires.
+// It returns the parsed token, nil if it is not valid
+func (p *DenylistParser) Revoke(tokenString string) *jwt.Token {
+	token, err := p.Parser.ParseJWT(tokenString)
+	if err != nil || !token.Valid {
+		return nil
+	}
+
+	exp, err := token.Claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil
+	}
+
+	p.Denylist.Deny(tokenID(token), exp.Time)
+
+	return token
+}
+
+// tokenID is the jti claim. If the token has not jti,
+// for example some id tokens, it is the hash of the token
+func tokenID(token *jwt.Token) string {
+	if claims, ok := token.Claims.(jwt.MapClaims); ok {
+		if jti, ok := claims["jti"].(string); ok && jti != "" {
+			return jti
+		}
+	}
+
+	return refreshID(token.Raw)
+}
+
+// refreshID is the hash of the token, so the refresh tokens
+// are not kept on the server
+func refreshID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// closeSession denies the session token, unregisters the hub client
+// and removes the cookies. The user is kept for the audit log
+// because the token is no longer valid
+func closeSession(
+	ctx echo.Context,
+	log *zap.Logger,
+	cnf config.Config,
+	hub Hub,
+	tokens *DenylistParser) {
+	//
+	if raw := sessionToken(ctx); raw != "" && tokens != nil {
+		if token := tokens.Revoke(raw); token != nil {
+			ctx.Set(AuditUserKey, tokenName(token))
+			log.Info(infRevokeSession, zap.String("ID", tokenID(token)))
+		}
+	}
+
+	unregisterHub(ctx, log, hub)
+	removeSessionCookies(ctx, cnf)
+}
+
+// revokeToken revokes the token at the provider. The errors are
+// only logged, the session is closed anyway
+func revokeToken(
+	log *zap.Logger,
+	token string,
+	hint string,
+	revoke func(token string, hint string) error) {
+	//
+	if token == "" {
+		return
+	}
+
+	if err := revoke(token, hint); err != nil {
+		log.Warn(errRevokeProvider, zap.String("Hint", hint), zap.Error(err))
+	}
+}
+
+// sessionToken gets the session token of the auth cookie
+func sessionToken(ctx echo.Context) string {
+	c, err := ctx.Cookie(AuthHeaderName)
+	if err != nil {
+		return ""
+	}
+
+	return c.Value
+}
+
+// refreshToken gets the decrypted refresh token of the cookie
+func refreshToken(ctx echo.Context, cnf config.Config) string {
+	c, err := ctx.Cookie(RefreshTokenName)
+	if err != nil || c.Value == "" {
+		return ""
+	}
+
+	token, err := decryptCookie(cnf, c.Value)
+	if err != nil {
+		return ""
+	}
+
+	return token
+}
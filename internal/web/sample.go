@@ -18,10 +18,16 @@
 package web
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
 	"github.com/swpoolcontroller/internal/ai"
+	"github.com/swpoolcontroller/internal/iot"
 	"go.uber.org/zap"
 )
 
@@ -29,6 +35,31 @@ import (
 const (
 	errGettingSample = "Getting the sample of the request body"
 	errSaveSample    = "Saving sample data in repository"
+	errListSamples   = "Listing the samples of the repository"
+	errReadSample    = "Reading the sample of the repository"
+	errDeleteSample  = "Deleting the sample of the repository"
+	errExportSamples = "Exporting the samples of the repository"
+	errSampleFilter  = "Parsing the filter of the samples"
+	errReadMetrics   = "Reading the latest readings of the device"
+	errInvalidSample = "The sample is not valid"
+)
+
+// Query params of the samples API
+const (
+	sampleQualityName  = "quality"
+	sampleChlorineName = "chlorine"
+	sampleOffsetName   = "offset"
+	sampleLimitName    = "limit"
+	sampleIDName       = "id"
+)
+
+const (
+	// sampleMaxAge is the maximum age of the readings of a new sample
+	sampleMaxAge = 2 * time.Minute
+	// defaultSampleLimit is the page size if the limit is not set
+	defaultSampleLimit = 50
+	// maxSampleLimit is the maximum page size
+	maxSampleLimit = 500
 )
 
 const (
@@ -40,18 +71,53 @@ const (
 type SampleWeb struct {
 	Log  *zap.Logger
 	Repo ai.SampleRepo
+	// Metrics reads the readings of the sensors of the new samples
+	Metrics MetricsReader
+}
+
+// sampleDTO is the judgement of the expert. The readings of the
+// sensors are the latest ones of the hub, not the ones of the browser
+type sampleDTO struct {
+	Chlorine float64 `json:"chlorine"`
+	Quality  string  `json:"quality"`
 }
 
-// Save saves the samples in the repo
+// Save saves the judgement of the expert with the latest readings
+// of the device. Conflict if the device has not sent recent readings
 func (s *SampleWeb) Save(ctx echo.Context) error {
-	var sample ai.SampleData
+	var dto sampleDTO
 
-	if err := ctx.Bind(&sample); err != nil {
+	if err := ctx.Bind(&dto); err != nil {
 		s.Log.Error(errGettingSample, zap.Error(err))
 
 		return ctx.NoContent(http.StatusBadRequest)
 	}
 
+	c, cancel := context.WithTimeout(ctx.Request().Context(), statusTimeout)
+	defer cancel()
+
+	metrics, err := s.Metrics.Metrics(c)
+	if err != nil {
+		s.Log.Error(errReadMetrics, zap.Error(err))
+
+		return ctx.NoContent(http.StatusServiceUnavailable)
+	}
+
+	readings, err := ai.NewReadings(metrics, time.Now(), sampleMaxAge)
+	if err != nil {
+		return ctx.NoContent(http.StatusConflict)
+	}
+
+	sample, err := ai.NewSample(readings, dto.Chlorine, dto.Quality)
+	if err != nil {
+		var errs iot.ValidationError
+		if errors.As(err, &errs) {
+			return validationProblem(ctx, errInvalidSample, errs)
+		}
+
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
 	if err := s.Repo.Save(sample); err != nil {
 		s.Log.Error(errSaveSample, zap.Error(err))
 
@@ -61,6 +127,120 @@ func (s *SampleWeb) Save(ctx echo.Context) error {
 	return ctx.NoContent(http.StatusOK)
 }
 
+// List returns the page of the samples of the query filter
+func (s *SampleWeb) List(ctx echo.Context) error {
+	filter, err := sampleFilter(ctx)
+	if err != nil {
+		s.Log.Warn(errSampleFilter, zap.Error(err))
+
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	page, err := s.Repo.List(filter)
+	if err != nil {
+		s.Log.Error(errListSamples, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	return ctx.JSON(http.StatusOK, page)
+}
+
+// Get returns the sample of the id
+func (s *SampleWeb) Get(ctx echo.Context) error {
+	sample, err := s.Repo.Get(ctx.Param(sampleIDName))
+	if err != nil {
+		if errors.Is(err, ai.ErrSampleNotFound) {
+			return ctx.NoContent(http.StatusNotFound)
+		}
+
+		s.Log.Error(errReadSample, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	return ctx.JSON(http.StatusOK, sample)
+}
+
+// Delete deletes the sample of the id
+func (s *SampleWeb) Delete(ctx echo.Context) error {
+	if err := s.Repo.Delete(ctx.Param(sampleIDName)); err != nil {
+		if errors.Is(err, ai.ErrSampleNotFound) {
+			return ctx.NoContent(http.StatusNotFound)
+		}
+
+		s.Log.Error(errDeleteSample, zap.Error(err))
+
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// Export downloads all the samples as csv in the column order
+// that ai/fit.py expects
+func (s *SampleWeb) Export(ctx echo.Context) error {
+	ctx.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	ctx.Response().Header().Set(
+		echo.HeaderContentDisposition,
+		"attachment; filename=samples.csv")
+	ctx.Response().WriteHeader(http.StatusOK)
+
+	if err := s.Repo.Export(ctx.Response()); err != nil {
+		s.Log.Error(errExportSamples, zap.Error(err))
+	}
+
+	return nil
+}
+
+// sampleFilter parses the filter of the query. The limit is
+// defaultSampleLimit if it is not set and at most maxSampleLimit
+func sampleFilter(ctx echo.Context) (ai.SampleFilter, error) {
+	filter := ai.SampleFilter{Limit: defaultSampleLimit}
+
+	var err error
+
+	if v := ctx.QueryParam(sampleQualityName); v != "" {
+		q, err := ai.ParseQuality(v)
+		if err != nil {
+			return filter, errors.Wrap(err, sampleQualityName)
+		}
+
+		filter.Quality = &q
+	}
+
+	if v := ctx.QueryParam(sampleChlorineName); v != "" {
+		cl, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, errors.Wrap(err, sampleChlorineName)
+		}
+
+		filter.Chlorine = &cl
+	}
+
+	if v := ctx.QueryParam(sampleOffsetName); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return filter, errors.Wrap(err, sampleOffsetName)
+		}
+
+		if filter.Offset < 0 {
+			return filter, errors.New(sampleOffsetName)
+		}
+	}
+
+	if v := ctx.QueryParam(sampleLimitName); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, errors.Wrap(err, sampleLimitName)
+		}
+
+		if filter.Limit < 1 || filter.Limit > maxSampleLimit {
+			return filter, errors.New(sampleLimitName)
+		}
+	}
+
+	return filter, nil
+}
+
 // SampleDummyRepo is a not implemented repo
 type SampleDummyRepo struct {
 	Log *zap.Logger
@@ -71,3 +251,32 @@ func (s *SampleDummyRepo) Save(d ai.SampleData) error {
 
 	return nil
 }
+
+// All returns no samples
+func (s *SampleDummyRepo) All() ([]ai.SampleData, error) {
+	s.Log.Info(infNotImplementedRepo)
+
+	return []ai.SampleData{}, nil
+}
+
+// List returns no samples
+func (s *SampleDummyRepo) List(_ ai.SampleFilter) (ai.SamplePage, error) {
+	s.Log.Info(infNotImplementedRepo)
+
+	return ai.SamplePage{Samples: []ai.SampleData{}}, nil
+}
+
+// Get returns ErrSampleNotFound
+func (s *SampleDummyRepo) Get(_ string) (ai.SampleData, error) {
+	return ai.SampleData{}, ai.ErrSampleNotFound
+}
+
+// Delete returns ErrSampleNotFound
+func (s *SampleDummyRepo) Delete(_ string) error {
+	return ai.ErrSampleNotFound
+}
+
+// Export writes the csv header
+func (s *SampleDummyRepo) Export(w io.Writer) error {
+	return ai.WriteSamplesCSV(w, nil)
+}
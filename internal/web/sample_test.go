@@ -0,0 +1,286 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/swpoolcontroller/internal/ai"
+	"github.com/swpoolcontroller/internal/ai/mocks"
+	"github.com/swpoolcontroller/internal/web"
+	wmocks "github.com/swpoolcontroller/internal/web/mocks"
+	"github.com/swpoolcontroller/pkg/iot"
+	"go.uber.org/zap"
+)
+
+func TestSampleWeb_Save(t *testing.T) {
+	t.Parallel()
+
+	recent := iot.Metrics{
+		Temp:     []float64{24, 25},
+		PH:       []float64{7.2, 7.2},
+		ORP:      []float64{650, 650},
+		Received: time.Now().Add(-time.Minute),
+	}
+
+	old := recent
+	old.Received = time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		body    string
+		metrics iot.Metrics
+		hubErr  error
+		save    bool
+		status  int
+		fields  string
+	}{
+		{
+			name:    "Sample. It should save it with the readings of the hub",
+			body:    `{"chlorine":1.5,"quality":"good","temp":99}`,
+			metrics: recent,
+			save:    true,
+			status:  http.StatusOK,
+		},
+		{
+			name:    "Invalid judgement. StatusUnprocessableEntity",
+			body:    `{"chlorine":12,"quality":"god"}`,
+			metrics: recent,
+			status:  http.StatusUnprocessableEntity,
+			fields:  `"field":"chlorine"`,
+		},
+		{
+			name:    "Old readings. StatusConflict",
+			body:    `{"chlorine":1.5,"quality":"good"}`,
+			metrics: old,
+			status:  http.StatusConflict,
+		},
+		{
+			name:   "Hub does not respond. StatusServiceUnavailable",
+			body:   `{"chlorine":1.5,"quality":"good"}`,
+			hubErr: context.DeadlineExceeded,
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "Chlorine as text. StatusBadRequest",
+			body:   `{"chlorine":"1,5","quality":"good"}`,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := mocks.NewSampleRepo(t)
+			if tt.save {
+				repo.On("Save", ai.SampleData{
+					Temp:     24.5,
+					PH:       7.2,
+					ORP:      650,
+					Chlorine: 1.5,
+					Quality:  ai.QualityGood,
+					Taken:    tt.metrics.Received,
+				}).Return(nil)
+			}
+
+			metrics := wmocks.NewMetricsReader(t)
+			if tt.status != http.StatusBadRequest {
+				metrics.On("Metrics", mock.Anything).
+					Return(tt.metrics, tt.hubErr)
+			}
+
+			s := &web.SampleWeb{
+				Log:     zap.NewExample(),
+				Repo:    repo,
+				Metrics: metrics,
+			}
+
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/samples",
+				strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			_ = s.Save(echo.New().NewContext(req, rec))
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.fields)
+		})
+	}
+}
+
+func TestSampleWeb_List(t *testing.T) {
+	t.Parallel()
+
+	page := ai.SamplePage{
+		Samples: []ai.SampleData{{ID: "1", Temp: 24, Quality: ai.QualityGood}},
+		Total:   3,
+	}
+
+	good, chlorine := ai.QualityGood, 1.0
+
+	tests := []struct {
+		name   string
+		query  string
+		filter ai.SampleFilter
+		status int
+		body   string
+	}{
+		{
+			name:   "Default page. It should return the first 50 samples",
+			query:  "",
+			filter: ai.SampleFilter{Limit: 50},
+			status: http.StatusOK,
+			body: "{\"samples\":[{\"id\":\"1\",\"temp\":24,\"ph\":0," +
+				"\"orp\":0,\"quality\":\"good\",\"chlorine\":0," +
+				"\"taken\":\"0001-01-01T00:00:00Z\"}],\"total\":3}\n",
+		},
+		{
+			name:  "Filter. It should pass the filter to the repo",
+			query: "?quality=good&chlorine=1&offset=10&limit=5",
+			filter: ai.SampleFilter{
+				Quality: &good, Chlorine: &chlorine, Offset: 10, Limit: 5,
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Limit over the maximum. StatusBadRequest",
+			query:  "?limit=501",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Unknown quality. StatusBadRequest",
+			query:  "?quality=great",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative offset. StatusBadRequest",
+			query:  "?offset=-1",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := mocks.NewSampleRepo(t)
+			if tt.status == http.StatusOK {
+				repo.On("List", tt.filter).Return(page, nil)
+			}
+
+			s := &web.SampleWeb{Log: zap.NewExample(), Repo: repo}
+
+			req := httptest.NewRequest(
+				http.MethodGet, "/samples"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			_ = s.List(echo.New().NewContext(req, rec))
+
+			assert.Equal(t, tt.status, rec.Code)
+
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSampleWeb_Delete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{
+			name:   "Deleted. StatusNoContent",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "Not found. StatusNotFound",
+			err:    ai.ErrSampleNotFound,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "Repo error. StatusInternalServerError",
+			err:    errors.New("error"),
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := mocks.NewSampleRepo(t)
+			repo.On("Delete", "id1").Return(tt.err)
+
+			s := &web.SampleWeb{Log: zap.NewExample(), Repo: repo}
+
+			req := httptest.NewRequest(http.MethodDelete, "/samples/id1", nil)
+			rec := httptest.NewRecorder()
+			ctx := echo.New().NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("id1")
+
+			_ = s.Delete(ctx)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestSampleWeb_Export(t *testing.T) {
+	t.Parallel()
+
+	repo := mocks.NewSampleRepo(t)
+	repo.On("Export", mock.Anything).Return(func(w io.Writer) error {
+		return ai.WriteSamplesCSV(w, []ai.SampleData{{
+			ID: "1", Temp: 24, PH: 7.2, ORP: 650,
+			Chlorine: 1, Quality: ai.QualityGood,
+		}})
+	})
+
+	s := &web.SampleWeb{Log: zap.NewExample(), Repo: repo}
+
+	req := httptest.NewRequest(http.MethodGet, "/samples/export", nil)
+	rec := httptest.NewRecorder()
+
+	_ = s.Export(echo.New().NewContext(req, rec))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t,
+		"temp,ph,orp,chlorine,quality\n24,7.2,650,1,2\n",
+		rec.Body.String(),
+		"The column order of ai/fit.py")
+}
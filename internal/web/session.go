@@ -0,0 +1,279 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/internal/session"
+	"go.uber.org/zap"
+)
+
+const (
+	errSessionTerminated = "Session. The session has been terminated"
+	errSessionNotFound   = "Session. The session does not exist"
+	errSessionRenewal    = "Session. The credential does not renew any session"
+)
+
+const (
+	infSessionTerminate = "Session. Terminating the session"
+)
+
+const (
+	// SessionIDName is the path param with the session id
+	SessionIDName = "id"
+	// sessionIDKey is the key of the echo context where the session
+	// cookies store the id of the session opened or renewed
+	sessionIDKey = "session.id"
+	// sessionTokenKey is the key of the echo context where the session
+	// cookies store the session token
+	sessionTokenKey = "session.token"
+	// sessionRefreshKey is the key of the echo context where the refresh
+	// cookie stores the id of the refresh token
+	sessionRefreshKey = "session.refresh"
+	// sessionRenewalKey is the key of the echo context where the renewal
+	// middleware stores the id of the session to renew
+	sessionRenewalKey = "session.renewal"
+)
+
+// SessionRegistry keeps the web sessions on the server
+type SessionRegistry interface {
+	Open(s session.Session)
+	Touch(id string, seen time.Time)
+	Close(id string)
+	Renewal(key string) (session.Session, bool)
+	List() []session.Session
+	Terminate(id string) (session.Session, bool)
+	Terminated(id string) bool
+}
+
+// Sessions registers the web sessions keyed by the websocket client id,
+// so the administrators can list them and terminate them
+type Sessions struct {
+	Log      *zap.Logger
+	Registry SessionRegistry
+	Hub      Hub
+	Config   config.Config
+	// Tokens denies the token of the terminated sessions. It can be nil
+	Tokens *DenylistParser
+}
+
+// Start returns a middleware that registers the session opened or renewed
+// by the handler. The login handlers identify the user explicitly
+func (s *Sessions) Start() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			err := next(ctx)
+
+			id, ok := ctx.Get(sessionIDKey).(string)
+			if !ok || id == "" {
+				return err
+			}
+
+			now := time.Now()
+			user, _ := ctx.Get(AuditUserKey).(string)
+			refreshID, _ := ctx.Get(sessionRefreshKey).(string)
+
+			s.Registry.Open(session.Session{
+				ID:        id,
+				User:      user,
+				IP:        ctx.RealIP(),
+				UserAgent: ctx.Request().UserAgent(),
+				Created:   now,
+				LastSeen:  now,
+				Expires:   now.Add(sessionExpiration(s.Config)),
+				TokenID:   s.tokenID(ctx),
+				RefreshID: refreshID,
+			})
+
+			return err
+		}
+	}
+}
+
+// Check returns a middleware that rejects the terminated sessions
+// removing their cookies and updates the last time the others were seen.
+// The requests without session, for example with api keys, are not checked.
+// The tokens of the terminated sessions are denied, so the cookie is only
+// needed without tokens (dev). The renewals are checked by Renewal
+func (s *Sessions) Check() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			id, err := ctx.Cookie(WSClientIDName)
+			if err != nil || id.Value == "" {
+				return next(ctx)
+			}
+
+			if s.Registry.Terminated(id.Value) {
+				s.Log.Warn(errSessionTerminated, zap.String("ID", id.Value))
+
+				return s.reject(ctx)
+			}
+
+			s.Registry.Touch(id.Value, time.Now())
+
+			return next(ctx)
+		}
+	}
+}
+
+// Renewal returns a middleware that only renews a registered session
+// with its credential: the refresh token of the provider or, without it,
+// the session token. The session is found by the credential instead of
+// the cookie, so the terminated sessions and the credentials of other
+// sessions are rejected. Without tokens (dev) the cookie is checked
+func (s *Sessions) Renewal() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			key := s.renewalKey(ctx)
+			if key == "" {
+				return s.Check()(next)(ctx)
+			}
+
+			renewed, ok := s.Registry.Renewal(key)
+			if !ok {
+				s.Log.Warn(errSessionRenewal)
+
+				return s.reject(ctx)
+			}
+
+			ctx.Set(sessionRenewalKey, renewed.ID)
+			s.Registry.Touch(renewed.ID, time.Now())
+
+			return next(ctx)
+		}
+	}
+}
+
+// End returns a middleware that removes the session closed by the logout
+func (s *Sessions) End() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			id, errC := ctx.Cookie(WSClientIDName)
+
+			err := next(ctx)
+
+			if errC == nil {
+				s.Registry.Close(id.Value)
+			}
+
+			return err
+		}
+	}
+}
+
+// List returns the active sessions
+func (s *Sessions) List(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, s.Registry.List())
+}
+
+// Terminate closes the hub client of the session, denies its token
+// and rejects its cookies from now on. Its refresh token is removed
+// with the session, so it cannot be renewed
+func (s *Sessions) Terminate(ctx echo.Context) error {
+	id := ctx.Param(SessionIDName)
+
+	terminated, ok := s.Registry.Terminate(id)
+	if !ok {
+		s.Log.Warn(errSessionNotFound, zap.String("ID", id))
+
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	s.Log.Info(
+		infSessionTerminate,
+		zap.String("ID", id),
+		zap.String("User", terminated.User))
+
+	s.Hub.UnregisterClient(id)
+
+	if s.Tokens != nil && terminated.TokenID != "" {
+		// The cookie of the token lasts 5 minutes longer than the session
+		s.Tokens.Denylist.Deny(
+			terminated.TokenID,
+			terminated.Expires.Add(5*time.Minute))
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// tokenID identifies the session token stored by the handler.
+// It is empty without token (dev) or if it is not valid
+func (s *Sessions) tokenID(ctx echo.Context) string {
+	raw, ok := ctx.Get(sessionTokenKey).(string)
+	if !ok || raw == "" || s.Tokens == nil {
+		return ""
+	}
+
+	token, err := s.Tokens.Parser.ParseJWT(raw)
+	if err != nil {
+		return ""
+	}
+
+	return tokenID(token)
+}
+
+// renewalKey identifies the credential of the request that renews the
+// session: the refresh token or, if there is not, the session token.
+// It is empty without tokens
+func (s *Sessions) renewalKey(ctx echo.Context) string {
+	if token := refreshToken(ctx, s.Config); token != "" {
+		return refreshID(token)
+	}
+
+	raw := sessionToken(ctx)
+	if raw == "" || s.Tokens == nil {
+		return ""
+	}
+
+	// The expired tokens are not renewed either
+	token, err := s.Tokens.Parser.ParseJWT(raw)
+	if err != nil {
+		return ""
+	}
+
+	return tokenID(token)
+}
+
+// reject removes the cookies of the session and returns 401
+func (s *Sessions) reject(ctx echo.Context) error {
+	removeSessionCookies(ctx, s.Config)
+
+	cookie := cookies(WSClientIDName, "", time.Time{})
+	cookie.MaxAge = -1 // Remove cookie
+	cookie.Secure = s.Config.External.TLS
+	ctx.SetCookie(cookie)
+
+	return ctx.NoContent(http.StatusUnauthorized)
+}
+
+// renewedSession returns the id of the session found by the renewal
+// middleware. Without it, the session cannot be renewed
+func renewedSession(ctx echo.Context, log *zap.Logger) (string, bool) {
+	id, ok := ctx.Get(sessionRenewalKey).(string)
+	if !ok || id == "" {
+		log.Error(errSessionRenewal)
+
+		return "", false
+	}
+
+	return id, true
+}
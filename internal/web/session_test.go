@@ -0,0 +1,206 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/internal/config"
+	"github.com/swpoolcontroller/internal/session"
+	"github.com/swpoolcontroller/internal/web"
+	"github.com/swpoolcontroller/internal/web/mocks"
+	"go.uber.org/zap"
+)
+
+func newSessions(t *testing.T) (*web.Sessions, *mocks.Hub) {
+	t.Helper()
+
+	hub := mocks.NewHub(t)
+
+	return &web.Sessions{
+		Log:      zap.NewExample(),
+		Registry: session.NewRegistry(time.Minute),
+		Hub:      hub,
+		Config:   config.Default(),
+	}, hub
+}
+
+func TestSessions_Start(t *testing.T) {
+	t.Parallel()
+
+	s, _ := newSessions(t)
+	login := &web.AuthFlowDev{Log: zap.NewExample(), Config: s.Config}
+
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	req.Header.Set("User-Agent", "browser")
+
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.Set(web.AuditUserKey, "alice")
+
+	_ = s.Start()(login.Login)(ctx)
+
+	list := s.Registry.List()
+	require.Len(t, list, 1)
+	assert.Equal(t, "alice", list[0].User)
+	assert.Equal(t, "browser", list[0].UserAgent)
+	assert.Equal(t, "192.0.2.1", list[0].IP)
+	assert.True(t, list[0].Expires.After(time.Now()))
+
+	// A failed login does not open any session
+	rec = httptest.NewRecorder()
+	_ = s.Start()(func(c echo.Context) error {
+		return c.Redirect(http.StatusFound, web.RedirectErrorAuth)
+	})(echo.New().NewContext(req, rec))
+
+	assert.Len(t, s.Registry.List(), 1)
+}
+
+func TestSessions_Check(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		cookie     string
+		terminated bool
+		status     int
+	}{
+		{
+			name:   "Without session. It should return StatusOK",
+			status: http.StatusOK,
+		},
+		{
+			name:   "Active session. It should return StatusOK",
+			cookie: "1",
+			status: http.StatusOK,
+		},
+		{
+			name:       "Terminated. It should return StatusUnauthorized",
+			cookie:     "1",
+			terminated: true,
+			status:     http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, _ := newSessions(t)
+			s.Registry.Open(session.Session{
+				ID:      "1",
+				Expires: time.Now().Add(time.Minute),
+			})
+
+			if tt.terminated {
+				_, ok := s.Registry.Terminate("1")
+				require.True(t, ok)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/web/config", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{
+					Name:  web.WSClientIDName,
+					Value: tt.cookie,
+				})
+			}
+
+			rec := httptest.NewRecorder()
+
+			_ = s.Check()(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(echo.New().NewContext(req, rec))
+
+			assert.Equal(t, tt.status, rec.Code)
+
+			r := rec.Result()
+			defer r.Body.Close()
+
+			if tt.terminated {
+				assert.NotEmpty(t, r.Cookies(), "Session cookies removed")
+			}
+		})
+	}
+}
+
+func TestSessions_End(t *testing.T) {
+	t.Parallel()
+
+	s, _ := newSessions(t)
+	s.Registry.Open(session.Session{
+		ID:      "1",
+		Expires: time.Now().Add(time.Minute),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: web.WSClientIDName, Value: "1"})
+
+	_ = s.End()(func(c echo.Context) error {
+		return c.Redirect(http.StatusFound, web.RedirectLoginOk)
+	})(echo.New().NewContext(req, httptest.NewRecorder()))
+
+	assert.Empty(t, s.Registry.List())
+	assert.False(t, s.Registry.Terminated("1"))
+}
+
+func TestSessions_Terminate(t *testing.T) {
+	t.Parallel()
+
+	s, hub := newSessions(t)
+	tokens, token := sessionToken(t)
+	s.Tokens = tokens
+
+	s.Registry.Open(session.Session{
+		ID:        "1",
+		Expires:   time.Now().Add(time.Minute),
+		TokenID:   "1",
+		RefreshID: "refresh",
+	})
+
+	terminate := func(id string) int {
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(
+			httptest.NewRequest(http.MethodDelete, "/", nil),
+			rec)
+		ctx.SetParamNames(web.SessionIDName)
+		ctx.SetParamValues(id)
+
+		_ = s.Terminate(ctx)
+
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, terminate("2"))
+
+	hub.On("UnregisterClient", "1")
+
+	assert.Equal(t, http.StatusNoContent, terminate("1"))
+	assert.True(t, s.Registry.Terminated("1"))
+
+	_, err := tokens.ParseJWT(token)
+	assert.Error(t, err, "The token of the session is denied")
+
+	_, ok := s.Registry.Renewal("refresh")
+	assert.False(t, ok, "The refresh token does not renew the session")
+}
@@ -96,6 +96,7 @@ const float ph7 = 1690;
 // Type of messages send to the hub
 #define mtypeMetrics 1
 #define mtypeTraces 2
+#define mtypeConfigAck 3
 
 // CollectMetricsParam are the parameters
 // for collecting metrics
@@ -713,7 +714,7 @@ void config(const char *data)
 {
   Serial.println("(config).Updating configuration");
 
-  StaticJsonDocument<128> doc;
+  StaticJsonDocument<sizeConfig> doc;
   DeserializationError err = deserializeJson(doc, data);
 
   if (err.code() != DeserializationError::Code::Ok)
@@ -764,6 +765,26 @@ void config(const char *data)
     configData.calibratingPH ? "true" : "false");
   Serial.printf("targetPH: %f, ", configData.targetPH);
   Serial.printf("stabilizationTime: %lu)", configData.stabilizationTime);
+
+  ackConfig(doc["ver"]);
+}
+
+// ackConfig acknowledges to the hub that the configuration
+// version has been applied
+void ackConfig(uint32_t version)
+{
+  char ack[12];
+
+  ack[0] = mtypeConfigAck;
+  snprintf(ack + 1, sizeof(ack) - 1, "%lu", (unsigned long)version);
+
+  Serial.printf("(ackConfig).Acknowledging the configuration version %lu\n",
+                (unsigned long)version);
+
+  if (!ws.sendTXT(ack))
+  {
+    Serial.println("(ackConfig-ERROR).Error acknowledging the configuration");
+  }
 }
 
 void initSensorBuffer()
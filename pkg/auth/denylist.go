@@ -0,0 +1,49 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth
+
+import (
+	"time"
+)
+
+// Denylist keeps the identifiers of the revoked tokens until they expire.
+// It is safe for concurrent use
+type Denylist struct {
+	tokens ttlSet
+}
+
+// NewDenylist creates an empty denylist
+func NewDenylist() *Denylist {
+	return &Denylist{tokens: newTTLSet(0)}
+}
+
+// Deny adds the token until its expiration.
+// The expired tokens are purged from time to time
+func (d *Denylist) Deny(id string, expiration time.Time) {
+	d.tokens.add(id, expiration)
+}
+
+// Denied checks whether the token has been revoked and has not expired
+func (d *Denylist) Denied(id string) bool {
+	return d.tokens.has(id)
+}
+
+// Len returns the number of revoked tokens not purged yet
+func (d *Denylist) Len() int {
+	return d.tokens.len()
+}
@@ -0,0 +1,58 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swpoolcontroller/pkg/auth"
+)
+
+func TestDenylist(t *testing.T) {
+	t.Parallel()
+
+	d := auth.NewDenylist()
+
+	d.Deny("active", time.Now().Add(time.Hour))
+	d.Deny("expired", time.Now().Add(-time.Second))
+	d.Deny("soon", time.Now().Add(50*time.Millisecond))
+
+	assert.True(t, d.Denied("active"))
+	assert.False(t, d.Denied("expired"))
+	assert.False(t, d.Denied("unknown"))
+	assert.Equal(t, 2, d.Len())
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.False(t, d.Denied("soon"))
+
+	d.Deny("other", time.Now().Add(time.Hour))
+
+	assert.Equal(t, 3, d.Len(), "Purged when the list doubles")
+
+	// The first purge is with 64 tokens
+	for i := 0; i < 64; i++ {
+		d.Deny(strconv.Itoa(i), time.Now().Add(time.Hour))
+	}
+
+	assert.False(t, d.Denied("soon"))
+	assert.Equal(t, 66, d.Len(), "Without the expired tokens")
+}
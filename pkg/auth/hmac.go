@@ -0,0 +1,66 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	errSignJWT = "Signing JWT token"
+)
+
+// HMACJWT signs and parses the JWT tokens issued by the app itself
+// (HS256), for example for the local accounts
+type HMACJWT struct {
+	Key []byte
+	// Issuer is set in the iss claim and must match when it is parsed
+	Issuer string
+}
+
+// Sign signs the claims. The iss claim is overwritten with the Issuer
+func (h *HMACJWT) Sign(claims jwt.MapClaims) (string, error) {
+	claims["iss"] = h.Issuer
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).
+		SignedString(h.Key)
+	if err != nil {
+		return "", errors.Wrap(err, errSignJWT)
+	}
+
+	return token, nil
+}
+
+// ParseJWT parses the token checking the signature, the issuer
+// and the expiration
+func (h *HMACJWT) ParseJWT(tokenString string) (*jwt.Token, error) {
+	token, err := jwt.Parse(
+		tokenString,
+		func(_ *jwt.Token) (interface{}, error) {
+			return h.Key, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(h.Issuer),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return token, errors.Wrap(err, errParseJWT)
+	}
+
+	return token, nil
+}
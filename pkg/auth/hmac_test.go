@@ -0,0 +1,61 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/pkg/auth"
+)
+
+func TestHMACJWT(t *testing.T) {
+	t.Parallel()
+
+	h := &auth.HMACJWT{Key: []byte("key"), Issuer: "swpc"}
+
+	token, err := h.Sign(jwt.MapClaims{
+		"sub": "user",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	require.NoError(t, err)
+
+	parsed, err := h.ParseJWT(token)
+	require.NoError(t, err)
+	assert.True(t, parsed.Valid)
+
+	sub, _ := parsed.Claims.GetSubject()
+	assert.Equal(t, "user", sub)
+
+	other := &auth.HMACJWT{Key: []byte("other"), Issuer: "swpc"}
+	_, err = other.ParseJWT(token)
+	require.Error(t, err, "Wrong key")
+
+	other = &auth.HMACJWT{Key: []byte("key"), Issuer: "other"}
+	_, err = other.ParseJWT(token)
+	require.Error(t, err, "Wrong issuer")
+
+	token, err = h.Sign(jwt.MapClaims{"sub": "user"})
+	require.NoError(t, err)
+
+	_, err = h.ParseJWT(token)
+	require.Error(t, err, "Without expiration")
+}
@@ -168,6 +168,10 @@ func (k *JWKFetch) Fetch() error {
 // JWT manages JWT operations
 type JWT struct {
 	JWKFetch *JWKFetch
+	// Issuer, if it is not empty, must match the iss claim
+	Issuer string
+	// Audience, if it is not empty, must be contained in the aud claim
+	Audience string
 }
 
 // GetKey gets the public key
@@ -187,7 +191,20 @@ func (k *JWT) GetKey(token *jwt.Token) (interface{}, error) {
 
 // ParseJWT parse the token given a JWK service
 func (k *JWT) ParseJWT(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, k.GetKey)
+	var opts []jwt.ParserOption
+
+	if k.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(k.Issuer))
+	}
+
+	if k.Audience != "" {
+		opts = append(
+			opts,
+			jwt.WithAudience(k.Audience),
+			jwt.WithExpirationRequired())
+	}
+
+	token, err := jwt.Parse(tokenString, k.GetKey, opts...)
 
 	if err != nil {
 		return token, errors.Wrap(err, errParseJWT)
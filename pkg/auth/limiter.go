@@ -0,0 +1,84 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginLimiter limits the failed logins of each key
+// (for example user and IP). When the key reaches MaxAttempts
+// it is locked during LockTime since the last failure.
+// The keys are purged as ttlMap, so a failure is amortised
+// constant time. Operations are protected against concurrency
+type LoginLimiter struct {
+	maxAttempts int
+	lockTime    time.Duration
+
+	// attempts are the failed attempts of each key,
+	// which expire LockTime after the last one
+	attempts ttlMap[int]
+	lock     sync.Mutex
+}
+
+// NewLoginLimiter creates the limiter
+func NewLoginLimiter(maxAttempts int, lockTime time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		maxAttempts: maxAttempts,
+		lockTime:    lockTime,
+		attempts:    newTTLMap[int](0),
+	}
+}
+
+// Locked checks whether the key has exceeded the failed attempts
+func (l *LoginLimiter) Locked(key string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	count, ok := l.attempts.get(key, time.Now())
+
+	return ok && count >= l.maxAttempts
+}
+
+// Fail registers a failed attempt of the key
+func (l *LoginLimiter) Fail(key string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+
+	count, _ := l.attempts.get(key, now)
+	l.attempts.set(key, count+1, now.Add(l.lockTime), now)
+}
+
+// Reset removes the failed attempts of the key
+func (l *LoginLimiter) Reset(key string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.attempts.delete(key)
+}
+
+// Len returns the number of keys with failed attempts not purged yet
+func (l *LoginLimiter) Len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.attempts.len()
+}
@@ -0,0 +1,69 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swpoolcontroller/pkg/auth"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	t.Parallel()
+
+	l := auth.NewLoginLimiter(2, 50*time.Millisecond)
+
+	l.Fail("user")
+	assert.False(t, l.Locked("user"), "One failure")
+
+	l.Fail("user")
+	assert.True(t, l.Locked("user"), "Max attempts")
+	assert.False(t, l.Locked("other"), "Other key")
+
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, l.Locked("user"), "Lock expired")
+
+	l.Fail("user")
+	l.Fail("user")
+	l.Reset("user")
+	assert.False(t, l.Locked("user"), "Reset")
+}
+
+func TestLoginLimiter_Purge(t *testing.T) {
+	t.Parallel()
+
+	l := auth.NewLoginLimiter(2, 200*time.Millisecond)
+
+	l.Fail("old")
+	time.Sleep(250 * time.Millisecond)
+
+	// The first purge is with 64 keys
+	for i := 0; i < 63; i++ {
+		l.Fail(strconv.Itoa(i))
+	}
+
+	assert.Equal(t, 64, l.Len(), "Not purged on every failure")
+
+	l.Fail("new")
+
+	assert.Equal(t, 64, l.Len(), "Without the expired keys")
+	assert.False(t, l.Locked("old"))
+}
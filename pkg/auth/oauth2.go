@@ -32,6 +32,7 @@ import (
 
 const (
 	errTk           = "Get token"
+	errRefreshTk    = "Refresh token"
 	errRevokeTk     = "Revoke token"
 	errJWKCode      = "Get JWK"
 	errRequest      = "New http request"
@@ -48,16 +49,43 @@ type OA2TokenInput struct {
 	RedirectURI string
 }
 
+// OA2RefreshTokenInput defines the parameters of the oauth2 input
+// to renew the access token
+type OA2RefreshTokenInput struct {
+	URL          string
+	RefreshToken string
+}
+
+// OA2Token are the tokens returned by the provider.
+// AccessToken is validated.
+type OA2Token struct {
+	AccessToken *jwt.Token
+	// RefreshToken is empty if the provider does not return it.
+	// In a refresh it is empty if the provider does not rotate it
+	RefreshToken string
+	ExpiresIn    int
+}
+
 type respToken struct {
-	AccessToken string `json:"access_token"` //nolint:golint,tagliatelle
-	ExpiresIn   int    `json:"expires_in"`   //nolint:golint,tagliatelle
+	AccessToken  string `json:"access_token"`  //nolint:golint,tagliatelle
+	RefreshToken string `json:"refresh_token"` //nolint:golint,tagliatelle
+	ExpiresIn    int    `json:"expires_in"`    //nolint:golint,tagliatelle
 }
 
+// Token type hints of the revocation (RFC 7009)
+const (
+	TokenHintAccess  = "access_token"
+	TokenHintRefresh = "refresh_token"
+)
+
 // OA2RevokeTokenInput defines the parameters of the oauth2 input
 // to revoke token
 type OA2RevokeTokenInput struct {
 	URL   string
 	Token string
+	// TypeHint is access_token or refresh_token (RFC 7009).
+	// It is not sent if it is empty
+	TypeHint string
 }
 
 // OAuth2 manages the oauth2 infrastructure
@@ -70,30 +98,52 @@ type OAuth2 struct {
 // Token gets a token given a JWK
 // The JWK service must be persisted throughout
 // the entire application cycle as a cache.
-func (o *OAuth2) Token(params OA2TokenInput) (*jwt.Token, error) {
+func (o *OAuth2) Token(params OA2TokenInput) (OA2Token, error) {
 	data := url.Values{}
 	data.Set("grant_type", "authorization_code")
 	data.Set("client_id", o.ClientID)
 	data.Set("code", params.Code)
 	data.Set("redirect_uri", params.RedirectURI)
 
-	body, err := post(params.URL, data)
+	return o.token(params.URL, data, errTk)
+}
+
+// Refresh renews the access token using the refresh token
+func (o *OAuth2) Refresh(params OA2RefreshTokenInput) (OA2Token, error) {
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("client_id", o.ClientID)
+	data.Set("refresh_token", params.RefreshToken)
+
+	return o.token(params.URL, data, errRefreshTk)
+}
+
+func (o *OAuth2) token(
+	url string,
+	data url.Values,
+	errMessage string) (OA2Token, error) {
+	//
+	body, err := post(url, data)
 	if err != nil {
-		return nil, errors.Wrap(err, errTk)
+		return OA2Token{}, errors.Wrap(err, errMessage)
 	}
 
 	var respToken respToken
 
 	if err := json.Unmarshal(body, &respToken); err != nil {
-		return nil, errors.Wrap(err, errUnmarshallTk)
+		return OA2Token{}, errors.Wrap(err, errUnmarshallTk)
 	}
 
 	jwtToken, err := o.JWT.ParseJWT(respToken.AccessToken)
 	if err != nil {
-		return nil, errors.Wrap(err, errJWTTk)
+		return OA2Token{}, errors.Wrap(err, errJWTTk)
 	}
 
-	return jwtToken, nil
+	return OA2Token{
+		AccessToken:  jwtToken,
+		RefreshToken: respToken.RefreshToken,
+		ExpiresIn:    respToken.ExpiresIn,
+	}, nil
 }
 
 // RevokeToken revokes a token
@@ -102,6 +152,10 @@ func (o *OAuth2) RevokeToken(params OA2RevokeTokenInput) error {
 	data.Set("client_id", o.ClientID)
 	data.Set("token", params.Token)
 
+	if params.TypeHint != "" {
+		data.Set("token_type_hint", params.TypeHint)
+	}
+
 	if _, err := post(params.URL, data); err != nil {
 		return errors.Wrap(err, errRevokeTk)
 	}
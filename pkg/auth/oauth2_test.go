@@ -20,6 +20,7 @@ package auth_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -140,6 +141,41 @@ func TestOAuth2_Token(t *testing.T) {
 	}
 }
 
+func TestOAuth2_Refresh(t *testing.T) {
+	t.Parallel()
+
+	var form url.Values
+
+	reg := func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		form = r.PostForm
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("{"))
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(reg))
+	defer s.Close()
+
+	o := auth.OAuth2{
+		ClientID: "client_id",
+		JWT: &auth.JWT{
+			JWKFetch: auth.NewJWKFetch(s.URL),
+		},
+	}
+
+	_, err := o.Refresh(auth.OA2RefreshTokenInput{
+		URL:          s.URL,
+		RefreshToken: "refresh",
+	})
+
+	require.ErrorContains(t, err, "unexpected end of JSON input", "Error")
+	assert.Equal(t, "refresh_token", form.Get("grant_type"))
+	assert.Equal(t, "refresh", form.Get("refresh_token"))
+	assert.Equal(t, "client_id", form.Get("client_id"))
+}
+
 func TestOAuth2_RevokeToken(t *testing.T) {
 	t.Parallel()
 
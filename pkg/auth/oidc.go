@@ -0,0 +1,346 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+	stringss "github.com/swpoolcontroller/pkg/strings"
+)
+
+var (
+	errOIDCIssuer = errors.New("The discovered issuer does not match")
+	errOIDCNonce  = errors.New("The nonce of the id token does not match")
+	errOIDCIDTk   = errors.New("The provider has not returned an id token")
+)
+
+const (
+	errDiscovery   = "Discovering OpenID configuration"
+	errRandom      = "Generating random string"
+	errOIDCTk      = "Get OpenID token"
+	errOIDCParseTk = "Parsing OpenID id token"
+)
+
+const wellKnownOIDC = "/.well-known/openid-configuration"
+
+// OIDCDiscovery is the OpenID provider metadata
+// published in .well-known/openid-configuration
+type OIDCDiscovery struct {
+	//nolint:tagliatelle
+	Issuer string `json:"issuer"`
+	//nolint:tagliatelle
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	//nolint:tagliatelle
+	TokenEndpoint string `json:"token_endpoint"`
+	//nolint:tagliatelle
+	JWKSURI string `json:"jwks_uri"`
+	//nolint:tagliatelle
+	EndSessionEndpoint string `json:"end_session_endpoint,omitempty"`
+	//nolint:tagliatelle
+	RevocationEndpoint string `json:"revocation_endpoint,omitempty"`
+}
+
+// Discover reads the OpenID provider metadata of the issuer
+func Discover(issuer string) (OIDCDiscovery, error) {
+	var d OIDCDiscovery
+
+	if err := getJSON(
+		strings.TrimSuffix(issuer, "/")+wellKnownOIDC, &d); err != nil {
+		return OIDCDiscovery{}, errors.Wrap(err, errDiscovery)
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return OIDCDiscovery{}, errors.Wrap(errOIDCIssuer, errDiscovery)
+	}
+
+	return d, nil
+}
+
+// PKCE is the proof key for code exchange (RFC 7636)
+type PKCE struct {
+	Verifier  string
+	Challenge string
+}
+
+// NewPKCE generates a verifier and its S256 challenge
+func NewPKCE() (PKCE, error) {
+	verifier, err := RandomString(32)
+	if err != nil {
+		return PKCE{}, err
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	return PKCE{
+		Verifier:  verifier,
+		Challenge: base64.RawURLEncoding.EncodeToString(sum[:]),
+	}, nil
+}
+
+// RandomString returns a random url safe string of n bytes of entropy
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", errors.Wrap(err, errRandom)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// OIDCTokenInput defines the parameters to exchange the code
+type OIDCTokenInput struct {
+	Code        string
+	RedirectURI string
+	Verifier    string
+	Nonce       string
+}
+
+// OIDCToken are the tokens returned by the provider.
+// IDToken is validated.
+type OIDCToken struct {
+	IDToken      *jwt.Token
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+type respOIDCToken struct {
+	IDToken      string `json:"id_token"`      //nolint:tagliatelle
+	AccessToken  string `json:"access_token"`  //nolint:tagliatelle
+	RefreshToken string `json:"refresh_token"` //nolint:tagliatelle
+	ExpiresIn    int    `json:"expires_in"`    //nolint:tagliatelle
+}
+
+// OIDC manages a generic OpenID Connect provider
+// using the authorization code flow with PKCE
+type OIDC struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       string
+	Discovery    OIDCDiscovery
+
+	// JWT validates the id token. It must be configured
+	// with the issuer and the client id as audience
+	JWT *JWT
+}
+
+// NewOIDC discovers the provider and creates the OIDC service.
+// The JWT validator is configured to check iss, aud and exp.
+func NewOIDC(
+	issuer string,
+	clientID string,
+	clientSecret string,
+	scopes string) (*OIDC, error) {
+	//
+	d, err := Discover(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDC{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		Discovery:    d,
+		JWT: &JWT{
+			JWKFetch: NewJWKFetch(d.JWKSURI),
+			Issuer:   d.Issuer,
+			Audience: clientID,
+		},
+	}, nil
+}
+
+// AuthURL returns the URL of the authorization endpoint
+func (o *OIDC) AuthURL(
+	redirectURI string,
+	state string,
+	nonce string,
+	challenge string) string {
+	//
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", o.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", o.Scopes)
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+
+	return withQuery(o.Discovery.AuthorizationEndpoint, q)
+}
+
+// LogoutURL returns the URL of the end session endpoint.
+// If the provider has not end session endpoint, it returns redirectURI
+func (o *OIDC) LogoutURL(redirectURI string) string {
+	if o.Discovery.EndSessionEndpoint == "" {
+		return redirectURI
+	}
+
+	q := url.Values{}
+	q.Set("client_id", o.ClientID)
+	q.Set("post_logout_redirect_uri", redirectURI)
+
+	return withQuery(o.Discovery.EndSessionEndpoint, q)
+}
+
+// Token exchanges the code and validates the id token
+// (signature, iss, aud, exp and nonce)
+func (o *OIDC) Token(params OIDCTokenInput) (OIDCToken, error) {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", params.Code)
+	data.Set("redirect_uri", params.RedirectURI)
+	data.Set("code_verifier", params.Verifier)
+
+	token, err := o.token(data, errOIDCTk)
+	if err != nil {
+		return OIDCToken{}, err
+	}
+
+	claims, _ := token.IDToken.Claims.(jwt.MapClaims)
+	if nonce, _ := claims["nonce"].(string); nonce != params.Nonce {
+		return OIDCToken{}, errOIDCNonce
+	}
+
+	return token, nil
+}
+
+// Refresh renews the tokens using the refresh token.
+// The new id token is validated (signature, iss, aud and exp)
+func (o *OIDC) Refresh(refreshToken string) (OIDCToken, error) {
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", refreshToken)
+
+	return o.token(data, errRefreshTk)
+}
+
+// Revoke revokes the token at the revocation endpoint (RFC 7009).
+// If the provider has not revocation endpoint, it does nothing
+func (o *OIDC) Revoke(token string, hint string) error {
+	if o.Discovery.RevocationEndpoint == "" || token == "" {
+		return nil
+	}
+
+	data := url.Values{}
+	data.Set("token", token)
+
+	if hint != "" {
+		data.Set("token_type_hint", hint)
+	}
+
+	o.client(data)
+
+	if _, err := post(o.Discovery.RevocationEndpoint, data); err != nil {
+		return errors.Wrap(err, errRevokeTk)
+	}
+
+	return nil
+}
+
+func (o *OIDC) token(data url.Values, errMessage string) (OIDCToken, error) {
+	o.client(data)
+
+	body, err := post(o.Discovery.TokenEndpoint, data)
+	if err != nil {
+		return OIDCToken{}, errors.Wrap(err, errMessage)
+	}
+
+	var resp respOIDCToken
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return OIDCToken{}, errors.Wrap(err, errUnmarshallTk)
+	}
+
+	if resp.IDToken == "" {
+		return OIDCToken{}, errOIDCIDTk
+	}
+
+	token, err := o.JWT.ParseJWT(resp.IDToken)
+	if err != nil {
+		return OIDCToken{}, errors.Wrap(err, errOIDCParseTk)
+	}
+
+	return OIDCToken{
+		IDToken:      token,
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    resp.ExpiresIn,
+	}, nil
+}
+
+// client authenticates the client in the form
+func (o *OIDC) client(data url.Values) {
+	data.Set("client_id", o.ClientID)
+
+	if o.ClientSecret != "" {
+		data.Set("client_secret", o.ClientSecret)
+	}
+}
+
+func withQuery(endpoint string, q url.Values) string {
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+
+	return stringss.Concat(endpoint, sep, q.Encode())
+}
+
+func getJSON(url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(
+		context.TODO(),
+		http.MethodGet,
+		url,
+		nil)
+	if err != nil {
+		return errors.Wrap(err, errRequest)
+	}
+
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return errors.Wrap(err, errRequest)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(
+			stringss.Concat(errRequest, ", StatusCode: ", resp.Status))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, errHTTPReadBody)
+	}
+
+	return errors.Wrap(json.Unmarshal(body, v), errHTTPReadBody)
+}
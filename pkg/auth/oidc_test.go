@@ -0,0 +1,305 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/pkg/auth"
+)
+
+type oidcProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+	form   url.Values
+}
+
+func newOIDCProvider(t *testing.T) *oidcProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &oidcProvider{key: key}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(
+		"/.well-known/openid-configuration",
+		func(w http.ResponseWriter, _ *http.Request) {
+			_ = json.NewEncoder(w).Encode(auth.OIDCDiscovery{
+				Issuer:                p.server.URL,
+				AuthorizationEndpoint: p.server.URL + "/authorize",
+				TokenEndpoint:         p.server.URL + "/token",
+				JWKSURI:               p.server.URL + "/jwks",
+				EndSessionEndpoint:    p.server.URL + "/logout",
+				RevocationEndpoint:    p.server.URL + "/revoke",
+			})
+		})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(auth.JWK{Keys: []auth.JWKKey{{
+			Alg: "RS256",
+			Kid: "k1",
+			Kty: "RSA",
+			E: base64.RawURLEncoding.EncodeToString(
+				big.NewInt(int64(key.E)).Bytes()),
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		p.form = r.PostForm
+
+		t := jwt.NewWithClaims(jwt.SigningMethodRS256, p.claims)
+		t.Header["kid"] = "k1"
+
+		idToken, _ := t.SignedString(key)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id_token":      idToken,
+			"access_token":  "access",
+			"refresh_token": "refresh",
+			"expires_in":    3600,
+		})
+	})
+
+	mux.HandleFunc("/revoke", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		p.form = r.PostForm
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	p.server = httptest.NewServer(mux)
+
+	return p
+}
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	p := newOIDCProvider(t)
+	defer p.server.Close()
+
+	d, err := auth.Discover(p.server.URL + "/")
+
+	require.NoError(t, err)
+	assert.Equal(t, p.server.URL+"/token", d.TokenEndpoint)
+	assert.Equal(t, p.server.URL+"/jwks", d.JWKSURI)
+}
+
+func TestNewPKCE(t *testing.T) {
+	t.Parallel()
+
+	pkce, err := auth.NewPKCE()
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(pkce.Verifier))
+
+	assert.Equal(
+		t,
+		base64.RawURLEncoding.EncodeToString(sum[:]),
+		pkce.Challenge)
+}
+
+func TestOIDC_Token(t *testing.T) {
+	t.Parallel()
+
+	valid := func(iss string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   iss,
+			"aud":   "client",
+			"sub":   "user",
+			"nonce": "nonce",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		claims  func(iss string) jwt.MapClaims
+		wantErr bool
+	}{
+		{
+			name:    "Token Ok",
+			claims:  valid,
+			wantErr: false,
+		},
+		{
+			name: "Wrong issuer",
+			claims: func(iss string) jwt.MapClaims {
+				c := valid(iss)
+				c["iss"] = "https://other"
+
+				return c
+			},
+			wantErr: true,
+		},
+		{
+			name: "Wrong audience",
+			claims: func(iss string) jwt.MapClaims {
+				c := valid(iss)
+				c["aud"] = "other"
+
+				return c
+			},
+			wantErr: true,
+		},
+		{
+			name: "Expired",
+			claims: func(iss string) jwt.MapClaims {
+				c := valid(iss)
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+
+				return c
+			},
+			wantErr: true,
+		},
+		{
+			name: "Wrong nonce",
+			claims: func(iss string) jwt.MapClaims {
+				c := valid(iss)
+				c["nonce"] = "other"
+
+				return c
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := newOIDCProvider(t)
+			defer p.server.Close()
+
+			p.claims = tt.claims(p.server.URL)
+
+			o, err := auth.NewOIDC(p.server.URL, "client", "", "openid")
+			require.NoError(t, err)
+
+			token, err := o.Token(auth.OIDCTokenInput{
+				Code:        "code",
+				RedirectURI: "http://localhost/auth/login",
+				Verifier:    "verifier",
+				Nonce:       "nonce",
+			})
+
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, token.IDToken.Valid)
+			assert.Equal(t, "refresh", token.RefreshToken)
+			assert.Equal(t, "verifier", p.form.Get("code_verifier"))
+		})
+	}
+}
+
+func TestOIDC_Refresh(t *testing.T) {
+	t.Parallel()
+
+	p := newOIDCProvider(t)
+	defer p.server.Close()
+
+	// The renewed id token has not nonce
+	p.claims = jwt.MapClaims{
+		"iss": p.server.URL,
+		"aud": "client",
+		"sub": "user",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	o, err := auth.NewOIDC(p.server.URL, "client", "secret", "openid")
+	require.NoError(t, err)
+
+	token, err := o.Refresh("refresh")
+
+	require.NoError(t, err)
+	assert.True(t, token.IDToken.Valid)
+	assert.Equal(t, "refresh_token", p.form.Get("grant_type"))
+	assert.Equal(t, "refresh", p.form.Get("refresh_token"))
+	assert.Equal(t, "secret", p.form.Get("client_secret"))
+}
+
+func TestOIDC_Revoke(t *testing.T) {
+	t.Parallel()
+
+	p := newOIDCProvider(t)
+	defer p.server.Close()
+
+	o, err := auth.NewOIDC(p.server.URL, "client", "secret", "openid")
+	require.NoError(t, err)
+
+	require.NoError(t, o.Revoke("refresh", auth.TokenHintRefresh))
+	assert.Equal(t, "refresh", p.form.Get("token"))
+	assert.Equal(t, "refresh_token", p.form.Get("token_type_hint"))
+	assert.Equal(t, "client", p.form.Get("client_id"))
+	assert.Equal(t, "secret", p.form.Get("client_secret"))
+
+	// Without revocation endpoint nothing is revoked
+	o.Discovery.RevocationEndpoint = ""
+	p.form = nil
+
+	require.NoError(t, o.Revoke("refresh", auth.TokenHintRefresh))
+	assert.Nil(t, p.form)
+}
+
+func TestOIDC_AuthURL(t *testing.T) {
+	t.Parallel()
+
+	o := &auth.OIDC{
+		ClientID: "client",
+		Scopes:   "openid email",
+		Discovery: auth.OIDCDiscovery{
+			AuthorizationEndpoint: "https://idp/authorize",
+		},
+	}
+
+	u, err := url.Parse(o.AuthURL("https://app/auth/login", "st", "no", "ch"))
+	require.NoError(t, err)
+
+	q := u.Query()
+
+	assert.Equal(t, "code", q.Get("response_type"))
+	assert.Equal(t, "client", q.Get("client_id"))
+	assert.Equal(t, "st", q.Get("state"))
+	assert.Equal(t, "no", q.Get("nonce"))
+	assert.Equal(t, "ch", q.Get("code_challenge"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+}
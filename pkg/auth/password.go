@@ -0,0 +1,165 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errHashAlgorithm = errors.New("The password hash algorithm " +
+		"must be argon2 or bcrypt")
+	errHashFormat = errors.New("The password hash has not a valid format")
+)
+
+const (
+	errHashPassword = "Hashing password"
+	errSalt         = "Generating password salt"
+)
+
+const (
+	// PasswordArgon2 hashes the passwords with argon2id
+	PasswordArgon2 = "argon2"
+	// PasswordBcrypt hashes the passwords with bcrypt
+	PasswordBcrypt = "bcrypt"
+)
+
+// argon2id parameters recommended by RFC 9106 for memory constrained
+// environments
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 2
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+const argon2Prefix = "$argon2id$"
+
+// HashPassword hashes the password with the algorithm.
+// The result includes the algorithm, the parameters and the salt,
+// so it can be checked later with CheckPassword
+func HashPassword(algorithm string, password string) (string, error) {
+	switch algorithm {
+	case PasswordArgon2:
+		return hashArgon2(password)
+	case PasswordBcrypt:
+		h, err := bcrypt.GenerateFromPassword(
+			[]byte(password),
+			bcrypt.DefaultCost)
+		if err != nil {
+			return "", errors.Wrap(err, errHashPassword)
+		}
+
+		return string(h), nil
+	}
+
+	return "", errHashAlgorithm
+}
+
+// CheckPassword checks the password against a hash
+// generated by HashPassword with any algorithm
+func CheckPassword(hash string, password string) bool {
+	if strings.HasPrefix(hash, argon2Prefix) {
+		ok, err := checkArgon2(hash, password)
+
+		return err == nil && ok
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// hashArgon2 hashes with argon2id using the PHC string format
+func hashArgon2(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", errors.Wrap(err, errSalt)
+	}
+
+	key := argon2.IDKey(
+		[]byte(password),
+		salt,
+		argon2Time,
+		argon2Memory,
+		argon2Threads,
+		argon2KeyLen)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix,
+		argon2.Version,
+		argon2Memory,
+		argon2Time,
+		argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func checkArgon2(hash string, password string) (bool, error) {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=2", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 { //nolint:gomnd
+		return false, errHashFormat
+	}
+
+	var (
+		version int
+		memory  uint32
+		time    uint32
+		threads uint8
+	)
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil ||
+		version != argon2.Version {
+		return false, errHashFormat
+	}
+
+	if _, err := fmt.Sscanf(
+		parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, errHashFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, errHashFormat
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, errHashFormat
+	}
+
+	other := argon2.IDKey(
+		[]byte(password),
+		salt,
+		time,
+		memory,
+		threads,
+		uint32(len(key))) //nolint:gosec
+
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
@@ -0,0 +1,79 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/pkg/auth"
+)
+
+func TestHashPassword(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		algorithm string
+		prefix    string
+		wantErr   bool
+	}{
+		{
+			name:      "Argon2",
+			algorithm: auth.PasswordArgon2,
+			prefix:    "$argon2id$",
+		},
+		{
+			name:      "Bcrypt",
+			algorithm: auth.PasswordBcrypt,
+			prefix:    "$2a$",
+		},
+		{
+			name:      "Unknown algorithm",
+			algorithm: "md5",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			hash, err := auth.HashPassword(tt.algorithm, "secret-password")
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(hash, tt.prefix), hash)
+			assert.True(t, auth.CheckPassword(hash, "secret-password"))
+			assert.False(t, auth.CheckPassword(hash, "other-password"))
+		})
+	}
+}
+
+func TestCheckPassword_Wrong_Hash(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, auth.CheckPassword("$argon2id$v=19$bad", "secret"))
+	assert.False(t, auth.CheckPassword("", "secret"))
+}
@@ -21,18 +21,37 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/swpoolcontroller/pkg/crypto"
 )
 
-var errIncosState = errors.New("Invalid state")
+var (
+	errIncosState = errors.New("Invalid state")
+	// ErrStateExpired is returned when the state is older than its TTL
+	ErrStateExpired = errors.New("The state has expired")
+	// ErrStateUsed is returned when the nonce of the state is unknown,
+	// because it has already been used or it was not issued
+	ErrStateUsed = errors.New("The state has already been used")
+	// ErrTooManyStates is returned when there are too many states
+	// issued and not used, until some of them expire
+	ErrTooManyStates = errors.New("There are too many pending states")
+)
 
 const (
 	errEncodeState = "Encode state"
 	errDecodeState = "Decode state"
+	errIssueState  = "Issue state"
 )
 
+// nonceSize is the number of random bytes of the state nonce
+const nonceSize = 16
+
+// maxNonces is the maximum number of nonces of the pending states.
+// The states are issued to unauthenticated clients, so they are limited
+const maxNonces = 10000
+
 type stateCodec struct {
 	StateEcrypt []byte `json:"se,omitempty"`
 	StateOrigin []byte `json:"so,omitempty"`
@@ -85,3 +104,106 @@ func DecodeState(key []byte, statec string) ([]byte, error) {
 
 	return stateDecrypt, nil
 }
+
+// statePayload is the plain content of the issued states
+type statePayload struct {
+	Nonce    string `json:"n"`
+	IssuedAt int64  `json:"iat"`
+}
+
+// NonceStore records the nonces of the issued states until they expire.
+// It is safe for concurrent use
+type NonceStore struct {
+	nonces ttlSet
+}
+
+// NewNonceStore creates an empty nonce store
+func NewNonceStore() *NonceStore {
+	return &NonceStore{nonces: newTTLSet(maxNonces)}
+}
+
+// Add records the nonce until its expiration.
+// ErrTooManyStates if the store is full
+func (n *NonceStore) Add(nonce string, expiration time.Time) error {
+	if !n.nonces.add(nonce, expiration) {
+		return ErrTooManyStates
+	}
+
+	return nil
+}
+
+// Consume removes the nonce. It returns false if the nonce
+// was not recorded or it has expired
+func (n *NonceStore) Consume(nonce string) bool {
+	return n.nonces.take(nonce)
+}
+
+// StateIssuer issues single-use oauth2 states. The state embeds
+// the issue time and a random nonce and it is encrypted with Key.
+// The nonce is recorded in Nonces and consumed on login
+type StateIssuer struct {
+	Key    []byte
+	TTL    time.Duration
+	Nonces *NonceStore
+}
+
+// Issue generates a new state
+func (s *StateIssuer) Issue() (string, error) {
+	nonce, err := RandomString(nonceSize)
+	if err != nil {
+		return "", errors.Wrap(err, errIssueState)
+	}
+
+	now := time.Now()
+
+	payload, err := json.Marshal(statePayload{
+		Nonce:    nonce,
+		IssuedAt: now.Unix(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, errIssueState)
+	}
+
+	state, err := EncodeState(s.Key, payload)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.Nonces.Add(nonce, now.Add(s.TTL)); err != nil {
+		return "", err
+	}
+
+	return state, nil
+}
+
+// Consume validates the state, checks its expiration and consumes
+// its nonce, so the state cannot be used again
+func (s *StateIssuer) Consume(state string) error {
+	data, err := DecodeState(s.Key, state)
+	if err != nil {
+		return err
+	}
+
+	var payload statePayload
+
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return errors.Wrap(err, errDecodeState)
+	}
+
+	if payload.Nonce == "" {
+		return errIncosState
+	}
+
+	// The nonce is consumed even if the state has expired
+	used := !s.Nonces.Consume(payload.Nonce)
+
+	if time.Since(time.Unix(payload.IssuedAt, 0)) > s.TTL {
+		return ErrStateExpired
+	}
+
+	if used {
+		return ErrStateUsed
+	}
+
+	return nil
+}
@@ -19,7 +19,9 @@ package auth_test
 
 import (
 	"encoding/base64"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -142,3 +144,78 @@ func TestDecodeState_Error(t *testing.T) {
 		})
 	}
 }
+
+func TestStateIssuer(t *testing.T) {
+	t.Parallel()
+
+	issuer := &auth.StateIssuer{
+		Key:    key,
+		TTL:    time.Minute,
+		Nonces: auth.NewNonceStore(),
+	}
+
+	state, err := issuer.Issue()
+	require.NoError(t, err)
+
+	other, err := issuer.Issue()
+	require.NoError(t, err)
+	assert.NotEqual(t, state, other)
+
+	require.NoError(t, issuer.Consume(state))
+
+	// A captured state cannot be replayed
+	require.ErrorIs(t, issuer.Consume(state), auth.ErrStateUsed)
+
+	require.NoError(t, issuer.Consume(other))
+
+	// The state of other server has not been recorded
+	foreign := &auth.StateIssuer{
+		Key:    key,
+		TTL:    time.Minute,
+		Nonces: auth.NewNonceStore(),
+	}
+
+	state, err = foreign.Issue()
+	require.NoError(t, err)
+	require.ErrorIs(t, issuer.Consume(state), auth.ErrStateUsed)
+
+	_, err = auth.DecodeState(key, state)
+	require.NoError(t, err)
+
+	require.Error(t, issuer.Consume("123"))
+}
+
+func TestStateIssuer_Expired(t *testing.T) {
+	t.Parallel()
+
+	issuer := &auth.StateIssuer{
+		Key:    key,
+		TTL:    -time.Minute,
+		Nonces: auth.NewNonceStore(),
+	}
+
+	state, err := issuer.Issue()
+	require.NoError(t, err)
+
+	require.ErrorIs(t, issuer.Consume(state), auth.ErrStateExpired)
+}
+
+func TestNonceStore_Full(t *testing.T) {
+	t.Parallel()
+
+	n := auth.NewNonceStore()
+	exp := time.Now().Add(time.Minute)
+
+	added := 0
+
+	for n.Add(strconv.Itoa(added), exp) == nil {
+		added++
+	}
+
+	assert.Equal(t, 10000, added, "Maximum pending states")
+	require.ErrorIs(t, n.Add("new", exp), auth.ErrTooManyStates)
+	require.NoError(t, n.Add("0", exp.Add(time.Minute)), "Existing nonce")
+
+	assert.True(t, n.Consume("1"))
+	require.NoError(t, n.Add("new", exp), "After consuming a nonce")
+}
@@ -0,0 +1,127 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errTOTPSecret = "Generating TOTP secret"
+	errTOTPDecode = "Decoding TOTP secret"
+)
+
+// TOTP parameters compatible with the common authenticator apps
+// (RFC 6238: HMAC-SHA1, 6 digits and 30 seconds)
+const (
+	totpDigits    = 6
+	totpPeriod    = 30
+	totpSecretLen = 20
+	// totpSkew is the number of periods accepted before and after
+	// to tolerate clock drift
+	totpSkew = 1
+)
+
+//nolint:gochecknoglobals
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret generates a random base32 secret
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretLen)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", errors.Wrap(err, errTOTPSecret)
+	}
+
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPCode generates the code of the secret for the time
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(
+		strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", errors.Wrap(err, errTOTPDecode)
+	}
+
+	return hotp(key, uint64(t.Unix()/totpPeriod)), nil //nolint:gosec
+}
+
+// ValidateTOTP checks the code of the secret for the time
+// with a tolerance of one period
+func ValidateTOTP(secret string, code string, t time.Time) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+
+	for i := -totpSkew; i <= totpSkew; i++ {
+		c, err := TOTPCode(
+			secret,
+			t.Add(time.Duration(i*totpPeriod)*time.Second))
+		if err != nil {
+			return false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(c), []byte(code)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TOTPURI returns the otpauth URI to register the secret
+// in an authenticator app, usually through a QR code
+func TOTPURI(issuer string, account string, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: q.Encode(),
+	}).String()
+}
+
+// hotp is the HMAC-based one-time password (RFC 4226)
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8) //nolint:gomnd
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000) //nolint:gomnd
+}
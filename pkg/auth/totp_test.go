@@ -0,0 +1,83 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/pkg/auth"
+)
+
+// RFC 6238 test secret "12345678901234567890" in base32
+const totpSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	t.Parallel()
+
+	// RFC 6238 appendix B vectors truncated to 6 digits
+	tests := []struct {
+		time int64
+		want string
+	}{
+		{time: 59, want: "287082"},
+		{time: 1111111109, want: "081804"},
+		{time: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := auth.TOTPCode(totpSecret, time.Unix(tt.time, 0))
+
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, code)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	t.Parallel()
+
+	secret, err := auth.NewTOTPSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	code, err := auth.TOTPCode(secret, now)
+	require.NoError(t, err)
+
+	assert.True(t, auth.ValidateTOTP(secret, code, now), "Now")
+	assert.True(t, auth.ValidateTOTP(secret, code, now.Add(30*time.Second)),
+		"Clock drift")
+	assert.False(t, auth.ValidateTOTP(secret, code, now.Add(5*time.Minute)),
+		"Expired")
+	assert.False(t, auth.ValidateTOTP(secret, "", now), "Empty")
+}
+
+func TestTOTPURI(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.Parse(auth.TOTPURI("swpc", "admin", totpSecret))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/swpc:admin", u.Path)
+	assert.Equal(t, totpSecret, u.Query().Get("secret"))
+}
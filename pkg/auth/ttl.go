@@ -0,0 +1,160 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// minPurgeLen is the number of keys of the first purge
+const minPurgeLen = 64
+
+// ttlItem is a value and when it expires
+type ttlItem[V any] struct {
+	value      V
+	expiration time.Time
+}
+
+// ttlMap maps keys to values that expire. The expired keys are purged
+// when a new key is set and the map has doubled its size since
+// the last purge, so setting is amortised constant time.
+// It is not safe for concurrent use
+type ttlMap[V any] struct {
+	items map[string]ttlItem[V]
+	// limit is the maximum number of keys, 0 without limit
+	limit int
+	// purgeLen is the number of keys that purges the map
+	purgeLen int
+}
+
+func newTTLMap[V any](limit int) ttlMap[V] {
+	return ttlMap[V]{
+		items:    make(map[string]ttlItem[V]),
+		limit:    limit,
+		purgeLen: minPurgeLen,
+	}
+}
+
+// get gets the value of the key if it has not expired
+func (m *ttlMap[V]) get(key string, now time.Time) (V, bool) {
+	item, ok := m.items[key]
+	if !ok || !item.expiration.After(now) {
+		var zero V
+
+		return zero, false
+	}
+
+	return item.value, true
+}
+
+// set sets the value of the key until its expiration.
+// It returns false if the map is full of keys that have not expired
+func (m *ttlMap[V]) set(
+	key string,
+	value V,
+	expiration time.Time,
+	now time.Time) bool {
+	//
+	if len(m.items) >= m.purgeLen {
+		m.purge(now)
+	}
+
+	if !expiration.After(now) {
+		return true
+	}
+
+	if _, ok := m.items[key]; !ok && m.limit > 0 && len(m.items) >= m.limit {
+		return false
+	}
+
+	m.items[key] = ttlItem[V]{value: value, expiration: expiration}
+
+	return true
+}
+
+func (m *ttlMap[V]) delete(key string) {
+	delete(m.items, key)
+}
+
+func (m *ttlMap[V]) len() int {
+	return len(m.items)
+}
+
+// purge removes the expired keys and sets the size of the next purge
+func (m *ttlMap[V]) purge(now time.Time) {
+	for k, item := range m.items {
+		if !item.expiration.After(now) {
+			delete(m.items, k)
+		}
+	}
+
+	m.purgeLen = max(2*len(m.items), minPurgeLen)
+
+	if m.limit > 0 {
+		m.purgeLen = min(m.purgeLen, m.limit)
+	}
+}
+
+// ttlSet is a set of keys that expire, purged as ttlMap.
+// It is safe for concurrent use
+type ttlSet struct {
+	lock  sync.Mutex
+	items ttlMap[struct{}]
+}
+
+func newTTLSet(limit int) ttlSet {
+	return ttlSet{items: newTTLMap[struct{}](limit)}
+}
+
+// add adds the key until its expiration.
+// It returns false if the set is full of keys that have not expired
+func (s *ttlSet) add(key string, expiration time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.items.set(key, struct{}{}, expiration, time.Now())
+}
+
+// has checks whether the key exists and has not expired
+func (s *ttlSet) has(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.items.get(key, time.Now())
+
+	return ok
+}
+
+// take removes the key and checks whether it existed and had not expired
+func (s *ttlSet) take(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.items.get(key, time.Now())
+	s.items.delete(key)
+
+	return ok
+}
+
+func (s *ttlSet) len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.items.len()
+}
@@ -28,7 +28,8 @@ import (
 )
 
 var (
-	errKeyLen = e.New("the key length must be 32 bytes")
+	errKeyLen        = e.New("the key length must be 32 bytes")
+	errCiphertextLen = e.New("the ciphertext is too short")
 )
 
 const (
@@ -79,6 +80,9 @@ func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
 	}
 
 	nonceSize := gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errCiphertextLen
+	}
 
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
 
@@ -0,0 +1,97 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package crypto
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errFSValue = "Getting file secret"
+)
+
+// FileSecret reads the secrets mounted as files in a directory,
+// as Docker secrets (/run/secrets) or Kubernetes secret volumes do.
+// The file name is the secret key and its content is the value
+// without the trailing new line. Hidden files are skipped,
+// so the ..data links of Kubernetes are not read
+type FileSecret struct {
+	Dir string
+}
+
+// Get gets the secrets of the directory. The secret name is not used
+func (s *FileSecret) Get(_ string) (map[string]string, error) {
+	secretValues := make(map[string]string)
+
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return secretValues, errors.Wrap(err, errFSValue)
+	}
+
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+
+		name := filepath.Join(s.Dir, e.Name())
+
+		// Stat follows the links of the mounted secrets
+		info, err := os.Stat(name)
+		if err != nil {
+			return secretValues, errors.Wrap(err, errFSValue)
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		value, err := os.ReadFile(name)
+		if err != nil {
+			return secretValues, errors.Wrap(err, errFSValue)
+		}
+
+		secretValues[e.Name()] = strings.TrimRight(string(value), "\r\n")
+	}
+
+	return secretValues, nil
+}
+
+// EnvSecret reads the secrets of the environment variables
+// that start with the prefix. The secret key is the variable name
+// without the prefix, for example SECRET_apiKey is the key apiKey
+type EnvSecret struct {
+	Prefix string
+}
+
+// Get gets the secrets of the environment. The secret name is not used
+func (s *EnvSecret) Get(_ string) (map[string]string, error) {
+	secretValues := make(map[string]string)
+
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+
+		if key, ok := strings.CutPrefix(k, s.Prefix); ok && key != "" {
+			secretValues[key] = v
+		}
+	}
+
+	return secretValues, nil
+}
@@ -0,0 +1,83 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package crypto_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/pkg/crypto"
+)
+
+func TestFileSecret_Get(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"apiKey":    "key\n",
+		"secretKey": "12345678901234567890123456789012",
+		".hidden":   "hidden",
+	}
+
+	for k, v := range files {
+		require.NoError(t,
+			os.WriteFile(filepath.Join(dir, k), []byte(v), 0o600))
+	}
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0o700))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+
+	s := &crypto.FileSecret{Dir: dir}
+
+	values, err := s.Get("")
+
+	require.NoError(t, err)
+	assert.Equal(t,
+		map[string]string{
+			"apiKey":    "key",
+			"secretKey": "12345678901234567890123456789012",
+		},
+		values)
+}
+
+func TestFileSecret_GetError(t *testing.T) {
+	t.Parallel()
+
+	s := &crypto.FileSecret{Dir: filepath.Join(t.TempDir(), "none")}
+
+	_, err := s.Get("")
+
+	require.Error(t, err)
+}
+
+//nolint:paralleltest // t.Setenv cannot run in parallel
+func TestEnvSecret_Get(t *testing.T) {
+	t.Setenv("SWPCTEST_apiKey", "key")
+	t.Setenv("SWPCTEST_", "empty")
+	t.Setenv("OTHER_apiKey", "other")
+
+	s := &crypto.EnvSecret{Prefix: "SWPCTEST_"}
+
+	values, err := s.Get("")
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"apiKey": "key"}, values)
+}
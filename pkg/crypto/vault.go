@@ -0,0 +1,176 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	stringss "github.com/swpoolcontroller/pkg/strings"
+)
+
+const (
+	errVValue   = "Getting vault secret"
+	errVLogin   = "Vault approle login"
+	errVRequest = "Vault request"
+)
+
+// vaultTimeout limits the requests to the vault server
+const vaultTimeout = 10 * time.Second
+
+// VaultSecret reads the secrets of a HashiCorp Vault KV v2 engine.
+// It authenticates with the Token or, if it is empty,
+// logs in with the AppRole RoleID and SecretID
+type VaultSecret struct {
+	// Address is the vault server URL, for example http://127.0.0.1:8200
+	Address string
+	// Mount is the path of the KV v2 engine, for example secret
+	Mount string
+	// Namespace is the vault enterprise namespace. It can be empty
+	Namespace string
+	Token     string
+	RoleID    string
+	SecretID  string
+}
+
+// Get gets the values of the secret path in the KV v2 engine.
+// The values that are not strings are formatted
+func (s *VaultSecret) Get(secretName string) (map[string]string, error) {
+	secretValues := make(map[string]string)
+
+	token := s.Token
+	if token == "" {
+		t, err := s.login()
+		if err != nil {
+			return secretValues, err
+		}
+
+		token = t
+	}
+
+	var result struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+
+	url := stringss.Concat(
+		s.url(), "/v1/", strings.Trim(s.Mount, "/"),
+		"/data/", strings.Trim(secretName, "/"))
+
+	if err := s.do(http.MethodGet, url, token, nil, &result); err != nil {
+		return secretValues, errors.Wrap(err, errVValue)
+	}
+
+	for k, v := range result.Data.Data {
+		if str, ok := v.(string); ok {
+			secretValues[k] = str
+
+			continue
+		}
+
+		secretValues[k] = fmt.Sprint(v)
+	}
+
+	return secretValues, nil
+}
+
+// login gets a client token with the AppRole credentials
+func (s *VaultSecret) login() (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"role_id":   s.RoleID,
+		"secret_id": s.SecretID,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, errVLogin)
+	}
+
+	var result struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+
+	url := stringss.Concat(s.url(), "/v1/auth/approle/login")
+
+	if err := s.do(http.MethodPost, url, "", body, &result); err != nil {
+		return "", errors.Wrap(err, errVLogin)
+	}
+
+	if result.Auth.ClientToken == "" {
+		return "", errors.New(errVLogin)
+	}
+
+	return result.Auth.ClientToken, nil
+}
+
+func (s *VaultSecret) url() string {
+	return strings.TrimRight(s.Address, "/")
+}
+
+func (s *VaultSecret) do(
+	method string,
+	url string,
+	token string,
+	body []byte,
+	v interface{}) error {
+	//
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(
+		ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, errVRequest)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	if s.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.Namespace)
+	}
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return errors.Wrap(err, errVRequest)
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, errVRequest)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(
+			stringss.Concat(errVRequest, ", StatusCode: ", resp.Status))
+	}
+
+	return errors.Wrap(json.Unmarshal(data, v), errVRequest)
+}
@@ -0,0 +1,211 @@
+/*
+*   Copyright (c) 2022 ELIPCERO
+*   All rights reserved.
+
+*   Licensed under the Apache License, Version 2.0 (the "License");
+*   you may not use this file except in compliance with the License.
+*   You may obtain a copy of the License at
+
+*   http://www.apache.org/licenses/LICENSE-2.0
+
+*   Unless required by applicable law or agreed to in writing, software
+*   distributed under the License is distributed on an "AS IS" BASIS,
+*   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*   See the License for the specific language governing permissions and
+*   limitations under the License.
+ */
+package crypto_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swpoolcontroller/pkg/crypto"
+)
+
+const (
+	vaultToken    = "token"
+	vaultRoleID   = "role"
+	vaultSecretID = "secret"
+)
+
+// vaultServer emulates the KV v2 read and the AppRole login of vault
+func vaultServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v1/auth/approle/login",
+		func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]string
+
+			_ = json.NewDecoder(r.Body).Decode(&body)
+
+			if r.Method != http.MethodPost ||
+				body["role_id"] != vaultRoleID ||
+				body["secret_id"] != vaultSecretID {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"token"}}`))
+		})
+
+	mux.HandleFunc("/v1/kv/data/swpc/server",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != vaultToken {
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"data":{"data":` +
+				`{"apiKey":"key","port":5000},"metadata":{"version":1}}}`))
+		})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestVaultSecret_Get(t *testing.T) {
+	t.Parallel()
+
+	srv := vaultServer(t)
+
+	type args struct {
+		secret *crypto.VaultSecret
+		name   string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "Token. It should return the values",
+			args: args{
+				secret: &crypto.VaultSecret{
+					Address: srv.URL + "/",
+					Mount:   "kv",
+					Token:   vaultToken,
+				},
+				name: "swpc/server",
+			},
+			want: map[string]string{"apiKey": "key", "port": "5000"},
+		},
+		{
+			name: "AppRole. It should return the values",
+			args: args{
+				secret: &crypto.VaultSecret{
+					Address:  srv.URL,
+					Mount:    "/kv/",
+					RoleID:   vaultRoleID,
+					SecretID: vaultSecretID,
+				},
+				name: "/swpc/server",
+			},
+			want: map[string]string{"apiKey": "key", "port": "5000"},
+		},
+		{
+			name: "Invalid token. It should return an error",
+			args: args{
+				secret: &crypto.VaultSecret{
+					Address: srv.URL,
+					Mount:   "kv",
+					Token:   "invalid",
+				},
+				name: "swpc/server",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid AppRole. It should return an error",
+			args: args{
+				secret: &crypto.VaultSecret{
+					Address:  srv.URL,
+					Mount:    "kv",
+					RoleID:   vaultRoleID,
+					SecretID: "invalid",
+				},
+				name: "swpc/server",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown path. It should return an error",
+			args: args{
+				secret: &crypto.VaultSecret{
+					Address: srv.URL,
+					Mount:   "kv",
+					Token:   vaultToken,
+				},
+				name: "swpc/none",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			values, err := tt.args.secret.Get(tt.args.name)
+
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, values)
+		})
+	}
+}
+
+// TestVaultSecret_GetDevServer runs against a vault dev server:
+//
+//	vault server -dev -dev-root-token-id=root
+//	VAULT_ADDR=http://127.0.0.1:8200 VAULT_TOKEN=root go test ./pkg/crypto
+func TestVaultSecret_GetDevServer(t *testing.T) {
+	t.Parallel()
+
+	addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		t.Skip("VAULT_ADDR and VAULT_TOKEN are not set")
+	}
+
+	// The dev server mounts a KV v2 engine in secret/
+	req, err := http.NewRequestWithContext(
+		context.TODO(),
+		http.MethodPost,
+		addr+"/v1/secret/data/swpc-test",
+		bytes.NewReader([]byte(`{"data":{"apiKey":"key"}}`)))
+	require.NoError(t, err)
+
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	s := &crypto.VaultSecret{Address: addr, Mount: "secret", Token: token}
+
+	values, err := s.Get("swpc-test")
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"apiKey": "key"}, values)
+}
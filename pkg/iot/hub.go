@@ -42,8 +42,14 @@ const (
 	errParseStartTime     = "Parser transmission start time"
 	errParseEndTime       = "Parser transmission end time"
 	errHeartbeatTime      = "IOT Device heartbeat timeout"
+	errConfigAck          = "Parsing the configuration acknowledgement"
+	errConfigStatus       = "Sending the configuration status"
+	errMetrics            = "Parsing the metrics of the device"
 )
 
+// ErrClosed is returned by the requests to a stopped hub
+var ErrClosed = errors.New("The hub is closed")
+
 const (
 	infSendStateDesac = "Hub.An attempt has been made to send " +
 		"a message but the hub is not in transmission mode"
@@ -58,11 +64,17 @@ const (
 	infTransmit       = "Hub.Sending information to the client"
 	infHubIdle        = "Hub.Broadcast state but no communication is detected"
 	infClientDied     = "Hub.Client died by expiration"
+	infClientRefresh  = "Hub.Client expiration extended"
+	infClientNotFound = "Hub.Client not found"
 	infArraySize      = "Hub.Array size after removing expired clients"
 	infNotify         = "Hub.Notifying state"
 	infConfigChanged  = "Hub.The configuration has been changed"
+	infSettingsChange = "Hub.The settings have been changed"
 	infStateChanged   = "Hub.The state has been changed"
 	infSendAction     = "Hub.Send action to iot device"
+	infConfigApplied  = "Hub.The configuration has been applied by the device"
+	infConfigOldAck   = "Hub.The device has acknowledged an old configuration"
+	infVersion        = "Version"
 	infDeviceID       = "DeviceID"
 	infIOTDevice      = "IOT device"
 	infClientID       = "ClientID"
@@ -116,6 +128,18 @@ const (
 // In this case, send a message with hub state
 const stateMessageType = "0"
 
+// configMessageType is the type of the message with the
+// ConfigStatus in json that is sent to the client
+const configMessageType = "2"
+
+// deviceMetricsMessageType is the type of the message with the
+// buffer of sensor readings in json that the iot device sends
+const deviceMetricsMessageType = 1
+
+// deviceAckMessageType is the type of the message that the iot device
+// sends when it applies a configuration. The message is its version
+const deviceAckMessageType = 3
+
 // DeviceMessageType is the type that differentiates
 // the message that is sent to the iot device
 type deviceMessageType uint8
@@ -180,6 +204,20 @@ type DeviceConfigDTO struct {
 	DeviceConfig
 	HBI  uint8 `json:"hbi"`
 	HBTC uint8 `json:"hbtc"`
+	// Version is acknowledged by the device when it is applied
+	Version uint32 `json:"ver"`
+}
+
+// ConfigStatus is the delivery of the configuration to the device.
+// Each configuration sent to the hub is a new version, which is pending
+// until the device acknowledges it, usually when it wakes up
+type ConfigStatus struct {
+	Version uint32 `json:"version"`
+	Pending bool   `json:"pending"`
+	// Sent is when the version was sent, nil if the device is asleep
+	Sent *time.Time `json:"sent,omitempty"`
+	// Applied is when the device acknowledged the version
+	Applied *time.Time `json:"applied,omitempty"`
 }
 
 func (mc *DeviceConfigDTO) message() (string, error) {
@@ -191,6 +229,25 @@ func (mc *DeviceConfigDTO) message() (string, error) {
 	return string(m), nil
 }
 
+// Metrics is the latest buffer of sensor readings sent by the device,
+// the oldest reading first
+type Metrics struct {
+	Temp []float64 `json:"temp"`
+	PH   []float64 `json:"ph"`
+	ORP  []float64 `json:"orp"`
+	// Received is when the hub received the buffer,
+	// zero if no buffer has been received
+	Received time.Time `json:"received"`
+}
+
+// metricsDTO is the buffer sent by the device. The device sends
+// the readings as strings
+type metricsDTO struct {
+	Temp []json.Number `json:"temp"`
+	PH   []json.Number `json:"ph"`
+	ORP  []json.Number `json:"orp"`
+}
+
 type HeartbeatConfig struct {
 	// HeartbeatInterval is the interval that
 	// the iot device sends a ping for heartbeat
@@ -218,6 +275,17 @@ type Config struct {
 	NotificationTime time.Duration `json:"notificationTime"`
 }
 
+// Settings are the parameters of the hub that can be changed
+// while it is running
+type Settings struct {
+	HeartbeatConfig
+	CommLatency      time.Duration
+	TaskTime         time.Duration
+	NotificationTime time.Duration
+	// TraceLevel is the minimum level of the traces sent
+	TraceLevel TraceLevel
+}
+
 func (c *Config) string() string {
 	r, err := json.Marshal(c)
 	if err != nil {
@@ -299,16 +367,22 @@ func (d *deviceController) Link(device Device) error {
 	return err
 }
 
-// SendConfig sends configuration changes
-func (d *deviceController) SendConfig(cnf DeviceConfig) error {
+// SendConfig sends configuration changes with its version
+func (d *deviceController) SendConfig(
+	cnf DeviceConfig,
+	version uint32) error {
+	//
 	if d.IsClosed() {
 		return nil
 	}
 
+	hb := d.heartbeat()
+
 	cnfdto := DeviceConfigDTO{
-		HBI:          uint8(d.HeartbeatInterval.Seconds()),
-		HBTC:         d.HeartbeatTimeoutCount,
+		HBI:          uint8(hb.HeartbeatInterval.Seconds()),
+		HBTC:         hb.HeartbeatTimeoutCount,
 		DeviceConfig: cnf,
+		Version:      version,
 	}
 
 	msgc, err := cnfdto.message()
@@ -420,7 +494,9 @@ func (d *deviceController) readMessage() bool {
 				strings.Format(
 					errHeartbeatTime,
 					strings.FMTValue(infHBInterval, timeout.String()),
-					strings.FMTValue(infHBTimeoutCount, string(d.HeartbeatTimeoutCount))))
+					strings.FMTValue(
+						infHBTimeoutCount,
+						string(d.heartbeat().HeartbeatTimeoutCount))))
 		}
 
 		closedManual := d.IsClosed()
@@ -446,13 +522,29 @@ func (d *deviceController) readMessage() bool {
 }
 
 func (d *deviceController) readDeadLine() time.Duration {
-	timeout := (d.HeartbeatInterval + d.HeartbeatPingTime) *
-		time.Duration(d.HeartbeatTimeoutCount)
+	hb := d.heartbeat()
+	timeout := (hb.HeartbeatInterval + hb.HeartbeatPingTime) *
+		time.Duration(hb.HeartbeatTimeoutCount)
 	_ = d.Connection.SetReadDeadline(time.Now().Add(timeout))
 
 	return timeout
 }
 
+// heartbeat returns the heartbeat config. It can be changed
+// by the hub while the messages are read
+func (d *deviceController) heartbeat() HeartbeatConfig {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	return d.HeartbeatConfig
+}
+
+func (d *deviceController) setHeartbeat(h HeartbeatConfig) {
+	d.mtx.Lock()
+	d.HeartbeatConfig = h
+	d.mtx.Unlock()
+}
+
 func (d *deviceController) setClosed(closed bool) {
 	d.mtx.Lock()
 	d.closed = closed
@@ -533,6 +625,12 @@ func (c *Client) sendMessage(message string) error {
 	return nil
 }
 
+// clientRefresh extends the expiration of a client
+type clientRefresh struct {
+	id         string
+	expiration time.Duration
+}
+
 func (c *Client) expired() bool {
 	return c.expiration.Before(time.Now())
 }
@@ -593,19 +691,32 @@ type Hub struct {
 	levelTrace TraceLevel
 
 	config Config
+	// cstatus is the delivery of the config to the device
+	cstatus ConfigStatus
+	// metrics is the latest buffer of the device
+	metrics Metrics
 
 	regd chan Device
 
 	reg   chan Client
 	unreg chan string
+	refc  chan clientRefresh
 
 	err     chan error
 	trace   chan Trace
 	send    chan string
 	sconfig chan DeviceConfig
+	settc   chan Settings
 	statec  chan chan State
+	cstatec chan chan ConfigStatus
+	metricc chan chan Metrics
 	closec  chan struct{}
 
+	// done is closed when the hub stops, before its channels
+	done chan struct{}
+	// reqLock keeps the channels open while a request is sent
+	reqLock sync.RWMutex
+
 	// notifySign Controls how often the hub sends
 	// notifications to the client due to lack of communication
 	notifySign time.Time
@@ -629,16 +740,22 @@ func NewHub(
 		clients:     []Client{},
 		device:      newDeviceController(cnf.HeartbeatConfig),
 		config:      cnf,
+		cstatus:     ConfigStatus{Pending: true},
 		regd:        make(chan Device),
 		reg:         make(chan Client),
 		unreg:       make(chan string),
+		refc:        make(chan clientRefresh),
 		send:        make(chan string),
 		sconfig:     make(chan DeviceConfig),
+		settc:       make(chan Settings),
 		levelTrace:  levelTrace,
 		trace:       trace,
 		err:         err,
 		statec:      make(chan chan State),
+		cstatec:     make(chan chan ConfigStatus),
+		metricc:     make(chan chan Metrics),
 		closec:      make(chan struct{}),
+		done:        make(chan struct{}),
 		lastMessage: time.Time{},
 		notifySign:  time.Now(),
 		state:       Dead,
@@ -660,19 +777,35 @@ func (h *Hub) UnregisterClient(id string) {
 	h.unreg <- id
 }
 
+// RefreshClient extends the expiration of the client
+// from now, without reconnecting it.
+// It fails if the hub stops or the context is done before receiving it
+func (h *Hub) RefreshClient(
+	ctx context.Context,
+	id string,
+	expiration time.Duration) error {
+	//
+	return request(ctx, h, h.refc, clientRefresh{id: id, expiration: expiration})
+}
+
 // Config sends the config to the hub
 func (h *Hub) Config(cnf DeviceConfig) {
 	h.sconfig <- cnf
 }
 
+// Reconfigure changes the timings, the heartbeat and the trace level
+// of the running hub. The new heartbeat is sent to the device.
+// It fails if the hub stops or the context is done before receiving them
+func (h *Hub) Reconfigure(ctx context.Context, s Settings) error {
+	return request(ctx, h, h.settc, s)
+}
+
 // Status request hub state via channel
 func (h *Hub) State(ctx context.Context) (State, error) {
 	resp := make(chan State)
 
-	select {
-	case h.statec <- resp:
-	case <-ctx.Done():
-		return Inactive, errors.Wrap(ctx.Err(), "request")
+	if err := request(ctx, h, h.statec, resp); err != nil {
+		return Inactive, err
 	}
 
 	select {
@@ -683,6 +816,60 @@ func (h *Hub) State(ctx context.Context) (State, error) {
 	}
 }
 
+// ConfigStatus requests the delivery of the config to the device
+func (h *Hub) ConfigStatus(ctx context.Context) (ConfigStatus, error) {
+	resp := make(chan ConfigStatus)
+
+	if err := request(ctx, h, h.cstatec, resp); err != nil {
+		return ConfigStatus{}, err
+	}
+
+	select {
+	case status := <-resp:
+		return status, nil
+	case <-ctx.Done():
+		return ConfigStatus{}, errors.Wrap(ctx.Err(), "resp")
+	}
+}
+
+// Metrics requests the latest buffer of sensor readings of the device
+func (h *Hub) Metrics(ctx context.Context) (Metrics, error) {
+	resp := make(chan Metrics)
+
+	if err := request(ctx, h, h.metricc, resp); err != nil {
+		return Metrics{}, err
+	}
+
+	select {
+	case metrics := <-resp:
+		return metrics, nil
+	case <-ctx.Done():
+		return Metrics{}, errors.Wrap(ctx.Err(), "resp")
+	}
+}
+
+// request sends the request to the running hub. ErrClosed if the hub
+// is stopped. The read lock keeps the channel open while it is sent
+func request[T any](ctx context.Context, h *Hub, c chan T, req T) error {
+	h.reqLock.RLock()
+	defer h.reqLock.RUnlock()
+
+	select {
+	case <-h.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c <- req:
+		return nil
+	case <-h.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "request")
+	}
+}
+
 // Stop finishes the hub.
 // The force param closes all channels and force to exist of the goroutine
 func (h *Hub) Stop() {
@@ -704,14 +891,22 @@ func (h *Hub) Run() { //nolint:cyclop
 				h.registerClient(client, check)
 			case id := <-h.unreg:
 				h.unregister(id, check)
+			case r := <-h.refc:
+				h.refreshClient(r)
 			case m := <-h.device.onRecieveMessage:
-				h.sendMessageToClients(m)
+				h.recieveDeviceMessage(m)
 			case err := <-h.device.onError:
 				h.processDeviceError(err)
 			case resps := <-h.statec:
 				resps <- h.state
+			case resps := <-h.cstatec:
+				resps <- h.cstatus
+			case resps := <-h.metricc:
+				resps <- h.metrics
 			case cnf := <-h.sconfig:
 				h.sendConfigMessageToDevice(cnf)
+			case s := <-h.settc:
+				h.reconfigure(s, check)
 			case <-check.C:
 				h.idleBroadcast()
 				h.notifyState()
@@ -740,7 +935,7 @@ func (h *Hub) registerDevice(device Device, check *time.Timer) {
 				strings.FMTValue(infDeviceID, device.ID)))
 	}
 
-	if err := h.device.SendConfig(h.config.DeviceConfig); err != nil {
+	if err := h.sendConfig(); err != nil {
 		h.err <- errors.Wrap(
 			err,
 			strings.Format(
@@ -821,6 +1016,35 @@ func (h *Hub) unregister(id string, check *time.Timer) {
 		})
 }
 
+// refreshClient extends the expiration of the client
+func (h *Hub) refreshClient(r clientRefresh) {
+	pos := h.findClient(r.id)
+	if pos == 255 {
+		h.sendTrace(
+			Trace{
+				Level: InfoLevel,
+				Message: strings.Format(
+					infClientNotFound,
+					strings.FMTValue(infClientID, r.id)),
+			})
+
+		return
+	}
+
+	h.clients[pos].expiration = time.Now().Add(r.expiration)
+
+	h.sendTrace(
+		Trace{
+			Level: InfoLevel,
+			Message: strings.Format(
+				infClientRefresh,
+				strings.FMTValue(infClientID, r.id),
+				strings.FMTValue(
+					infExpirationDate,
+					h.clients[pos].expiration.String())),
+		})
+}
+
 // sendMessageToClients send message to the all clients registered.
 // If sending the message throw a error, the client is removed
 func (h *Hub) sendMessageToClients(message string) {
@@ -845,12 +1069,80 @@ func (h *Hub) sendMessageToClients(message string) {
 		})
 }
 
+// recieveDeviceMessage acknowledges the config or
+// sends the message to the clients. The metrics are kept
+// as the latest buffer
+func (h *Hub) recieveDeviceMessage(message string) {
+	if len(message) > 0 && message[0] == deviceAckMessageType {
+		h.ackConfig(message[1:])
+
+		return
+	}
+
+	if len(message) > 0 && message[0] == deviceMetricsMessageType {
+		h.keepMetrics(message[1:])
+	}
+
+	h.sendMessageToClients(message)
+}
+
+// keepMetrics parses the buffer of the device as the latest one
+func (h *Hub) keepMetrics(message string) {
+	var dto metricsDTO
+
+	if err := json.Unmarshal([]byte(message), &dto); err != nil {
+		h.err <- errors.Wrap(
+			err,
+			strings.Format(
+				errMetrics,
+				strings.FMTValue(infDeviceID, h.device.ID)))
+
+		return
+	}
+
+	metrics := Metrics{Received: time.Now()}
+
+	for _, r := range []struct {
+		from []json.Number
+		to   *[]float64
+	}{
+		{dto.Temp, &metrics.Temp},
+		{dto.PH, &metrics.PH},
+		{dto.ORP, &metrics.ORP},
+	} {
+		*r.to = make([]float64, 0, len(r.from))
+
+		for _, n := range r.from {
+			v, err := n.Float64()
+			if err != nil {
+				h.err <- errors.Wrap(
+					err,
+					strings.Format(
+						errMetrics,
+						strings.FMTValue(infDeviceID, h.device.ID)))
+
+				return
+			}
+
+			*r.to = append(*r.to, v)
+		}
+	}
+
+	h.metrics = metrics
+}
+
 // sendConfigMessageToDevice sends the configuration
-// you have changed to the iot device
+// you have changed to the iot device as a new version,
+// pending until the device acknowledges it
 func (h *Hub) sendConfigMessageToDevice(cnf DeviceConfig) {
 	h.config.DeviceConfig = cnf
 
-	if err := h.device.SendConfig(cnf); err != nil {
+	h.cstatus = ConfigStatus{
+		Version: h.cstatus.Version + 1,
+		Pending: true,
+	}
+
+	if err := h.sendConfig(); err != nil {
 		h.err <- errors.Wrap(
 			err,
 			strings.Format(
@@ -858,6 +1150,8 @@ func (h *Hub) sendConfigMessageToDevice(cnf DeviceConfig) {
 				strings.FMTValue(infDeviceID, h.device.ID)))
 	}
 
+	h.notifyConfigStatus()
+
 	h.setState(false)
 
 	h.sendTrace(
@@ -869,6 +1163,122 @@ func (h *Hub) sendConfigMessageToDevice(cnf DeviceConfig) {
 		})
 }
 
+// reconfigure applies the settings. The heartbeat is sent
+// to the device with its config, and the timer check is reset
+// with the new task time
+func (h *Hub) reconfigure(s Settings, check *time.Timer) {
+	heartbeat := h.config.HeartbeatConfig != s.HeartbeatConfig
+
+	h.config.HeartbeatConfig = s.HeartbeatConfig
+	h.config.CommLatency = s.CommLatency
+	h.config.TaskTime = s.TaskTime
+	h.config.NotificationTime = s.NotificationTime
+	h.levelTrace = s.TraceLevel
+
+	if heartbeat {
+		h.device.setHeartbeat(s.HeartbeatConfig)
+
+		if err := h.sendConfig(); err != nil {
+			h.err <- errors.Wrap(
+				err,
+				strings.Format(
+					errSendDevice,
+					strings.FMTValue(infDeviceID, h.device.ID)))
+		}
+	}
+
+	h.tryReactiveTimerCheck(check)
+
+	h.sendTrace(
+		Trace{
+			Level: InfoLevel,
+			Message: strings.Format(
+				infSettingsChange,
+				strings.FMTValue(infConfig, h.config.string())),
+		})
+}
+
+// sendConfig sends the config version to the device if it is linked.
+// While it is pending, the time it was sent is updated
+func (h *Hub) sendConfig() error {
+	if h.device.IsClosed() {
+		return nil
+	}
+
+	if err := h.device.SendConfig(
+		h.config.DeviceConfig,
+		h.cstatus.Version); err != nil {
+		//
+		return err
+	}
+
+	if h.cstatus.Pending {
+		now := time.Now()
+		h.cstatus.Sent = &now
+	}
+
+	return nil
+}
+
+// ackConfig applies the version acknowledged by the device.
+// The acknowledgements of the previous versions are ignored
+func (h *Hub) ackConfig(message string) {
+	version, err := strconv.ParseUint(message, 10, 32)
+	if err != nil {
+		h.err <- errors.Wrap(
+			err,
+			strings.Format(
+				errConfigAck,
+				strings.FMTValue(infDeviceID, h.device.ID)))
+
+		return
+	}
+
+	if uint32(version) != h.cstatus.Version {
+		h.sendTrace(
+			Trace{
+				Level: WarnLevel,
+				Message: strings.Format(
+					infConfigOldAck,
+					strings.FMTValue(infVersion, message)),
+			})
+
+		return
+	}
+
+	if !h.cstatus.Pending {
+		return
+	}
+
+	now := time.Now()
+	h.cstatus.Pending = false
+	h.cstatus.Applied = &now
+
+	h.notifyConfigStatus()
+
+	h.sendTrace(
+		Trace{
+			Level: InfoLevel,
+			Message: strings.Format(
+				infConfigApplied,
+				strings.FMTValue(infVersion, message)),
+		})
+}
+
+// notifyConfigStatus sends the config status to the clients
+func (h *Hub) notifyConfigStatus() {
+	status, err := json.Marshal(h.cstatus)
+	if err != nil {
+		h.err <- errors.Wrap(err, errConfigStatus)
+
+		return
+	}
+
+	h.sendMessage(
+		strings.Concat(configMessageType, string(status)),
+		errConfigStatus)
+}
+
 func (h *Hub) processDeviceError(err error) {
 	h.err <- err
 	h.setState(false)
@@ -1182,13 +1592,23 @@ func (h *Hub) close(check *time.Timer) {
 
 	h.state = Closed
 
+	// The pending requests return before the channels are closed
+	close(h.done)
+
+	h.reqLock.Lock()
+	defer h.reqLock.Unlock()
+
 	close(h.err)
 	close(h.trace)
 	close(h.reg)
 	close(h.unreg)
+	close(h.refc)
 	close(h.send)
 	close(h.sconfig)
 	close(h.statec)
+	close(h.settc)
+	close(h.cstatec)
+	close(h.metricc)
 	close(h.closec)
 }
 
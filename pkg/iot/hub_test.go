@@ -508,8 +508,9 @@ func TestHub_SendConfigToDevice(t *testing.T) {
 				TargetPH:           7.2,
 				StabilizationTime:  10,
 			},
-			HBI:  10,
-			HBTC: 1,
+			HBI:     10,
+			HBTC:    1,
+			Version: 1,
 		},
 		unmarshalHeartbeat(msgd[2]),
 		"Device config message")
@@ -517,6 +518,283 @@ func TestHub_SendConfigToDevice(t *testing.T) {
 	assert.Empty(t, trace.Errors, "Errors")
 }
 
+func TestHub_ConfigAck(t *testing.T) {
+	t.Parallel()
+
+	cnf := iot.Config{
+		DeviceConfig: iot.DeviceConfig{
+			WakeUpTime:         1,
+			CollectMetricsTime: 800,
+			Buffer:             5,
+			IniSendTime:        "00:00",
+			EndSendTime:        "00:01",
+		},
+		CommLatency:      1 * time.Millisecond,
+		TaskTime:         50 * time.Second,
+		NotificationTime: 50 * time.Second,
+		HeartbeatConfig: iot.HeartbeatConfig{
+			HeartbeatInterval:     10 * time.Second,
+			HeartbeatPingTime:     1 * time.Second,
+			HeartbeatTimeoutCount: 1,
+		},
+	}
+
+	trace := newTrace()
+
+	wscs, wscc, err := newWS()
+	require.NoError(t, err, "New web client socket")
+
+	defer wscs.Close()
+	defer wscc.Close()
+
+	hub := iot.NewHub(cnf, iot.DebugLevel, trace.CHTrace, trace.CHError)
+	defer hub.Stop()
+
+	hub.Run()
+
+	hub.RegisterClient(iot.NewClient("c1", wscs, 10*time.Minute))
+
+	// The device is asleep, so the config is pending
+	hub.Config(cnf.DeviceConfig)
+
+	status := configStatus(t, hub)
+
+	assert.Equal(t, uint32(1), status.Version, "Asleep. Version")
+	assert.True(t, status.Pending, "Asleep. Pending")
+	assert.Nil(t, status.Sent, "Asleep. Sent")
+
+	msgc := readMessages(wscc, 1)
+	assert.True(t,
+		strings.HasPrefix(msgc[0], `2{"version":1,"pending":true`),
+		"Asleep. Client config status message")
+
+	wsds, wsdc, err := newWS()
+	require.NoError(t, err, "New web device socket")
+
+	defer wsds.Close()
+	defer wsdc.Close()
+
+	hub.RegisterDevice(iot.Device{ID: "d1", Connection: wsds})
+
+	msgd := readMessages(wsdc, 1)
+	assert.Equal(t, uint32(1), unmarshalHeartbeat(msgd[0]).Version,
+		"Wake up. Device config version")
+
+	status = configStatus(t, hub)
+
+	assert.True(t, status.Pending, "Wake up. Pending")
+	assert.NotNil(t, status.Sent, "Wake up. Sent")
+
+	// The acknowledgements of other versions are ignored
+	for _, ack := range []string{"\x030", "\x031"} {
+		err := wsdc.WriteMessage(websocket.TextMessage, []byte(ack))
+		require.NoError(t, err, "Write device ack")
+	}
+
+	msgc = readMessages(wscc, 1)
+	assert.True(t,
+		strings.HasPrefix(msgc[0], `2{"version":1,"pending":false`),
+		"Applied. Client config status message")
+
+	status = configStatus(t, hub)
+
+	assert.False(t, status.Pending, "Applied. Pending")
+	assert.NotNil(t, status.Applied, "Applied. Applied")
+
+	assert.Contains(t, trace.Traces,
+		"Hub.The device has acknowledged an old configuration (Version: 0, )")
+	assert.Empty(t, trace.Errors, "Errors")
+}
+
+func TestHub_Metrics(t *testing.T) {
+	t.Parallel()
+
+	cnf := iot.Config{
+		DeviceConfig: iot.DeviceConfig{
+			WakeUpTime:         1,
+			CollectMetricsTime: 800,
+			Buffer:             5,
+			IniSendTime:        "00:01",
+			EndSendTime:        "00:02",
+		},
+		CommLatency:      1 * time.Millisecond,
+		TaskTime:         50 * time.Second,
+		NotificationTime: 50 * time.Second,
+		HeartbeatConfig: iot.HeartbeatConfig{
+			HeartbeatInterval:     10 * time.Second,
+			HeartbeatPingTime:     0,
+			HeartbeatTimeoutCount: 1,
+		},
+	}
+
+	trace := newTrace()
+
+	wscs, wscc, err := newWS()
+	require.NoError(t, err, "New web client socket")
+
+	defer wscs.Close()
+	defer wscc.Close()
+
+	wsds, wsdc, err := newWS()
+	require.NoError(t, err, "New web device socket")
+
+	defer wsds.Close()
+	defer wsdc.Close()
+
+	hub := iot.NewHub(cnf, iot.DebugLevel, trace.CHTrace, trace.CHError)
+	defer hub.Stop()
+
+	hub.Run()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	metrics, err := hub.Metrics(ctx)
+
+	require.NoError(t, err, "Metrics")
+	assert.True(t, metrics.Received.IsZero(), "No buffer received")
+
+	hub.RegisterClient(iot.NewClient("c1", wscs, 10*time.Minute))
+	hub.RegisterDevice(iot.Device{ID: "d1", Connection: wsds})
+
+	readMessages(wsdc, 2)
+
+	buffer := "\x01" + `{"temp":["24.5","25"],"ph":["7.2","7.3"],` +
+		`"orp":["650","660.5"]}`
+
+	err = wsdc.WriteMessage(websocket.TextMessage, []byte(buffer))
+	require.NoError(t, err, "Write device metrics")
+
+	msgc := readMessages(wscc, 1)
+	assert.Equal(t, []string{buffer}, msgc, "Sent to the clients")
+
+	metrics, err = hub.Metrics(ctx)
+
+	require.NoError(t, err, "Metrics")
+	assert.Equal(t, []float64{24.5, 25}, metrics.Temp, "Temp")
+	assert.Equal(t, []float64{7.2, 7.3}, metrics.PH, "PH")
+	assert.Equal(t, []float64{650, 660.5}, metrics.ORP, "ORP")
+	assert.False(t, metrics.Received.IsZero(), "Received")
+}
+
+func configStatus(t *testing.T, hub *iot.Hub) iot.ConfigStatus {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	status, err := hub.ConfigStatus(ctx)
+	require.NoError(t, err, "Config status")
+
+	return status
+}
+
+func TestHub_Reconfigure(t *testing.T) {
+	t.Parallel()
+
+	cnf := iot.Config{
+		DeviceConfig: iot.DeviceConfig{
+			WakeUpTime:         1,
+			CollectMetricsTime: 800,
+			Buffer:             5,
+			IniSendTime:        "00:00",
+			EndSendTime:        "00:01",
+		},
+		CommLatency:      1 * time.Millisecond,
+		TaskTime:         50 * time.Second,
+		NotificationTime: 50 * time.Second,
+		HeartbeatConfig: iot.HeartbeatConfig{
+			HeartbeatInterval:     10 * time.Second,
+			HeartbeatPingTime:     1 * time.Second,
+			HeartbeatTimeoutCount: 1,
+		},
+	}
+
+	trace := newTrace()
+
+	wsds, wsdc, err := newWS()
+	require.NoError(t, err, "New web device socket")
+
+	defer wsds.Close()
+	defer wsdc.Close()
+
+	hub := iot.NewHub(cnf, iot.DebugLevel, trace.CHTrace, trace.CHError)
+	defer hub.Stop()
+
+	hub.Run()
+
+	hub.RegisterDevice(iot.Device{ID: "d1", Connection: wsds})
+
+	err = hub.Reconfigure(context.Background(), iot.Settings{
+		HeartbeatConfig: iot.HeartbeatConfig{
+			HeartbeatInterval:     20 * time.Second,
+			HeartbeatPingTime:     2 * time.Second,
+			HeartbeatTimeoutCount: 3,
+		},
+		CommLatency:      1 * time.Millisecond,
+		TaskTime:         40 * time.Second,
+		NotificationTime: 40 * time.Second,
+		TraceLevel:       iot.InfoLevel,
+	})
+	require.NoError(t, err, "Reconfigure")
+
+	msgd := readMessages(wsdc, 3)
+	require.Len(t, msgd, 3)
+
+	hb := unmarshalHeartbeat(msgd[2])
+	assert.Equal(t, uint8(20), hb.HBI, "Heartbeat interval sent")
+	assert.Equal(t, uint8(3), hb.HBTC, "Heartbeat timeout count sent")
+	assert.Equal(t, uint8(5), hb.Buffer, "Device config kept")
+
+	assert.Empty(t, trace.Errors, "Errors")
+}
+
+func TestHub_Closed(t *testing.T) {
+	t.Parallel()
+
+	cnf := iot.Config{
+		DeviceConfig: iot.DeviceConfig{
+			WakeUpTime:         1,
+			CollectMetricsTime: 800,
+			Buffer:             5,
+			IniSendTime:        "00:00",
+			EndSendTime:        "00:01",
+		},
+		CommLatency:      1 * time.Millisecond,
+		TaskTime:         50 * time.Second,
+		NotificationTime: 50 * time.Second,
+		HeartbeatConfig: iot.HeartbeatConfig{
+			HeartbeatInterval:     10 * time.Second,
+			HeartbeatPingTime:     1 * time.Second,
+			HeartbeatTimeoutCount: 1,
+		},
+	}
+
+	trace := newTrace()
+
+	hub := iot.NewHub(cnf, iot.DebugLevel, trace.CHTrace, trace.CHError)
+	hub.Run()
+	hub.Stop()
+
+	// Without a deadline, the requests would wait forever
+	ctx := context.Background()
+
+	err := hub.Reconfigure(ctx, iot.Settings{TaskTime: 40 * time.Second})
+	require.ErrorIs(t, err, iot.ErrClosed, "Reconfigure")
+
+	_, err = hub.Metrics(ctx)
+	require.ErrorIs(t, err, iot.ErrClosed, "Metrics")
+
+	_, err = hub.ConfigStatus(ctx)
+	require.ErrorIs(t, err, iot.ErrClosed, "ConfigStatus")
+
+	_, err = hub.State(ctx)
+	require.ErrorIs(t, err, iot.ErrClosed, "State")
+
+	err = hub.RefreshClient(ctx, "c1", time.Hour)
+	require.ErrorIs(t, err, iot.ErrClosed, "RefreshClient")
+}
+
 func TestHub_ClientDead(t *testing.T) {
 	t.Parallel()
 
@@ -571,6 +849,54 @@ func TestHub_ClientDead(t *testing.T) {
 	assert.Empty(t, trace.Errors, "Errors")
 }
 
+func TestHub_RefreshClient(t *testing.T) {
+	t.Parallel()
+
+	cnf := iot.Config{
+		DeviceConfig: iot.DeviceConfig{
+			WakeUpTime:         1,
+			CollectMetricsTime: 800,
+			Buffer:             1,
+			IniSendTime:        "00:00",
+			EndSendTime:        "00:01",
+		},
+		CommLatency:      1 * time.Millisecond,
+		TaskTime:         10 * time.Millisecond,
+		NotificationTime: 50 * time.Second,
+		HeartbeatConfig: iot.HeartbeatConfig{
+			HeartbeatInterval:     10 * time.Second,
+			HeartbeatPingTime:     0,
+			HeartbeatTimeoutCount: 1,
+		},
+	}
+
+	trace := newTrace()
+
+	wscs, wscc, err := newWS()
+	require.NoError(t, err, "New web client socket")
+
+	defer wscs.Close()
+	defer wscc.Close()
+
+	client := iot.NewClient("c1", wscs, time.Second)
+
+	hub := iot.NewHub(cnf, iot.DebugLevel, trace.CHTrace, trace.CHError)
+	defer hub.Stop()
+
+	hub.Run()
+
+	hub.RegisterClient(client)
+	require.NoError(t, hub.RefreshClient(context.Background(), "c1", time.Hour))
+	require.NoError(t, hub.RefreshClient(context.Background(), "c2", time.Hour))
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.True(t, hasTrace(trace.Traces, "Hub.Client expiration extended"))
+	assert.True(t, hasTrace(trace.Traces, "Hub.Client not found"))
+
+	assert.Empty(t, trace.Errors, "Errors")
+}
+
 func TestHub_Multi_Link_Device(t *testing.T) {
 	t.Parallel()
 
@@ -765,6 +1091,16 @@ func assertState(t *testing.T, hub *iot.Hub, expected iot.State) bool {
 	return false
 }
 
+func hasTrace(traces []string, prefix string) bool {
+	for _, tr := range traces {
+		if strings.HasPrefix(tr, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func newWS() (*websocket.Conn, *websocket.Conn, error) {
 	connsc := make(chan *websocket.Conn)
 
@@ -20,6 +20,11 @@ chmod +x "$path_target/predict.sh"
 cp ./ai/model/*_wq "$path_target/"
 cp ./ai/model/*_cl "$path_target/"
 
+# The server predicts with the json models. The python environment
+# is only used by predict.sh if they cannot be read
+cp ./ai/model/*_wq.json "$path_target/"
+cp ./ai/model/*_cl.json "$path_target/"
+
 python3 -m venv "$path_target/.venv/swpc_predict"
 source "$path_target/.venv/swpc_predict/bin/activate"
 
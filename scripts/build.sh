@@ -24,6 +24,8 @@ if [[ $option == '-ai' || $option == '' ]]; then
   $path_scripts/build-ai.sh "$deploy_path"
 fi
 
-GOOS=linux GOARCH=amd64 go build -ldflags="-w -s" -o "$deploy_path/swpc-server" cmd/swpc-server/main.go
+version=$(git describe --tags --always --dirty 2>/dev/null || echo dev)
+
+GOOS=linux GOARCH=amd64 go build -ldflags="-w -s -X main.version=$version" -o "$deploy_path/swpc-server" ./cmd/swpc-server
 
 echo "Release deployment: '$deploy_path'"
@@ -14,6 +14,7 @@ User=swpc
 EnvironmentFile=/etc/swpc/swpc.env
 WorkingDirectory=/opt/swpc/bin
 ExecStart=/opt/swpc/bin/swpc-server
+ExecReload=/bin/kill -HUP \$MAINPID
 StandardOutput=append:/var/log/swpc/swpc.log
 StandardError=append:/var/log/swpc/swpc.log
 Restart=on-failure
@@ -47,7 +47,7 @@ interface SampleState {
   open: boolean;
   cl: number;
   clValid: boolean;
-  waterQuality: number;
+  waterQuality: string;
   saving: boolean;
 }
 
@@ -68,7 +68,7 @@ export default class Sample extends React.Component<any, SampleState> {
       open: false,
       cl: 0,
       clValid: true,
-      waterQuality: 0,
+      waterQuality: "bad",
       saving: false
     };
 
@@ -82,7 +82,7 @@ export default class Sample extends React.Component<any, SampleState> {
     this.setState({
       cl: 0,
       clValid: true,
-      waterQuality: 0,
+      waterQuality: "bad",
       saving: false
     });
 
@@ -108,19 +108,34 @@ export default class Sample extends React.Component<any, SampleState> {
         headers: {
           "Content-Type": "application/json"
         },
+        // The server takes the latest readings of the micro controller
         body: JSON.stringify({
-          "temp": this.meassureTemp.current?.state.value.toString(),
-          "ph": this.meassurePh.current?.state.value.toString(),
-          "orp": this.meassureOrp.current?.state.value.toString(),
-          "chlorine": this.state.cl.toString(),
-          "quality": this.state.waterQuality.toString(),
+          "chlorine": this.state.cl,
+          "quality": this.state.waterQuality,
         })
       },
         async (result: Response) => {
-          this.setState({ open: false });
+          this.setState({ open: false, saving: false });
           if (result.ok) {
             return true;
           }
+          if (result.status === 422) {
+            const problem = await result.json();
+            this.props.alert.current.content(
+              "Muestra no válida",
+              problem.errors
+                .map((e: { field: string, message: string }) => e.field + ": " + e.message)
+                .join(". "));
+            this.props.alert.current.open();
+            return true;
+          }
+          if (result.status === 409 || result.status === 503) {
+            this.props.alert.current.content(
+              "Métricas no disponibles",
+              "El micro-controlador no ha enviado métricas recientes. Vuelva a intentarlo cuando esté transmitiendo");
+            this.props.alert.current.open();
+            return true;
+          }
           return false;
         },
         () => {
@@ -155,7 +170,8 @@ export default class Sample extends React.Component<any, SampleState> {
             <DialogContentText id="alert-dialog-slide-description">
               En base a las métricas obtenidas por el micro-controlador,
               rellene los valores del cloro medidos por usted
-              y categorice la calidad del agua.
+              y categorice la calidad del agua. El servidor guarda la media
+              de las últimas métricas recibidas del micro-controlador.
             </DialogContentText>
             <Stack marginTop="20px" spacing={2}>
               <TextField
@@ -180,12 +196,12 @@ export default class Sample extends React.Component<any, SampleState> {
                 value={this.state.waterQuality}
                 onChange={event =>
                   this.setState(
-                    { waterQuality: Number(event.target.value) })}
+                    { waterQuality: String(event.target.value) })}
                 size="small"
               >
-                <MenuItem value={0}>Mala</MenuItem>
-                <MenuItem value={1}>Regular</MenuItem>
-                <MenuItem value={2}>Buena</MenuItem>
+                <MenuItem value="bad">Mala</MenuItem>
+                <MenuItem value="regular">Regular</MenuItem>
+                <MenuItem value="good">Buena</MenuItem>
               </Select>
             </Stack>
             <Stack
@@ -21,6 +21,9 @@ export interface AppConfig {
     checkAuthName: string
     iotConfig: boolean
     aiSample: boolean
+    permissions: string[]
+    refreshInterval: number
+    changePassword?: boolean
 }
 
 const keyAppConfig = "app-config";
@@ -57,6 +60,8 @@ export function appConfig(): AppConfig {
       authLogoutUrl: "",
       checkAuthName: "",
       iotConfig: true,
-      aiSample: true
+      aiSample: true,
+      permissions: [],
+      refreshInterval: 0
     };
 }
\ No newline at end of file
@@ -20,6 +20,7 @@ import { BrowserRouter, Route, Routes } from 'react-router-dom';
 import Dashboard from '../dashboard/dashboard';
 import AuthError, { urlAuthError } from '../auth/auth_error';
 import Logout from '../auth/logout';
+import { ChangePassword, LocalLogin, urlChangePassword, urlLocalLogin } from '../auth/local';
 import PrivateRoute from '../router'
 import { loadAppConfig } from './config';
 import Ups from '../info/ups';
@@ -38,6 +39,14 @@ export default async function main(root: Root) {
                   <Route path="/auth/logout" element={
                     <Logout redirectURL="/"/>
                   }/>
+                  <Route path={urlLocalLogin} element={
+                    <LocalLogin />
+                  }/>
+                  <Route path={urlChangePassword} element={
+                    <PrivateRoute>
+                      <ChangePassword />
+                    </PrivateRoute>
+                  }/>
                   <Route path="/" element={
                     <PrivateRoute>
                       <Dashboard />
@@ -0,0 +1,161 @@
+/*
+ *   Copyright (c) 2022 ELIPCERO
+ *   All rights reserved.
+
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+
+ *   http://www.apache.org/licenses/LICENSE-2.0
+
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+import React, { useState } from 'react';
+import CssBaseline from '@mui/material/CssBaseline';
+import Box from '@mui/material/Box';
+import Button from '@mui/material/Button';
+import Container from '@mui/material/Container';
+import TextField from '@mui/material/TextField';
+import Typography from '@mui/material/Typography';
+import MuiAlert from '@mui/material/Alert';
+
+export const urlLocalLogin = "/auth/local"
+export const urlChangePassword = "/auth/password"
+
+// LocalLogin is the login form of the local accounts
+export function LocalLogin() {
+  const [username, setUsername] = useState("");
+  const [password, setPassword] = useState("");
+  const [code, setCode] = useState("");
+  const [message, setMessage] = useState("");
+
+  const handleSubmit = async (event: React.FormEvent<HTMLFormElement>) => {
+    event.preventDefault();
+
+    try {
+      const res = await fetch("/auth/login", {
+        method: "POST",
+        headers: {"Content-Type": "application/json"},
+        body: JSON.stringify({username: username, password: password, code: code})
+      });
+
+      switch (res.status) {
+        case 200:
+          window.location.href = "/";
+          return;
+        case 401:
+          setMessage("Usuario, contraseña o código incorrectos");
+          return;
+        case 429:
+          setMessage("Demasiados intentos fallidos. Inténtelo de nuevo más tarde");
+          return;
+        default:
+          console.log("auth.local. Fetch to login server. Status: " + res.status);
+          setMessage("Se ha producido un problema en la autenticación");
+      }
+    } catch (ex) {
+      console.log("auth.local. Fetch to login server: " + ex);
+      setMessage("Se ha producido un problema en la autenticación");
+    }
+  };
+
+  return (
+    <Container component="main" maxWidth="xs">
+      <CssBaseline />
+      <Box component="form" onSubmit={handleSubmit} sx={{ mt: 8 }}>
+        <Typography variant="h5" component="h1" gutterBottom>
+          Controlador de piscinas
+        </Typography>
+        {message && <MuiAlert severity="error">{message}</MuiAlert>}
+        <TextField margin="normal" required fullWidth label="Usuario"
+          autoComplete="username" autoFocus value={username}
+          onChange={(e) => setUsername(e.target.value)}/>
+        <TextField margin="normal" required fullWidth label="Contraseña"
+          type="password" autoComplete="current-password" value={password}
+          onChange={(e) => setPassword(e.target.value)}/>
+        <TextField margin="normal" fullWidth label="Código de verificación (si está activado)"
+          autoComplete="one-time-code" value={code}
+          onChange={(e) => setCode(e.target.value)}/>
+        <Button type="submit" fullWidth variant="contained" sx={{ mt: 3 }}>
+          Entrar
+        </Button>
+      </Box>
+    </Container>
+  );
+}
+
+// ChangePassword is the form to change the password of the local account
+export function ChangePassword() {
+  const [current, setCurrent] = useState("");
+  const [password, setPassword] = useState("");
+  const [repeat, setRepeat] = useState("");
+  const [message, setMessage] = useState({error: false, text: ""});
+
+  const handleSubmit = async (event: React.FormEvent<HTMLFormElement>) => {
+    event.preventDefault();
+
+    if (password !== repeat) {
+      setMessage({error: true, text: "Las contraseñas no coinciden"});
+      return;
+    }
+
+    try {
+      const res = await fetch("/api/web/password", {
+        method: "POST",
+        headers: {"Content-Type": "application/json"},
+        body: JSON.stringify({current: current, password: password})
+      });
+
+      switch (res.status) {
+        case 200:
+          setMessage({error: false, text: "La contraseña se ha cambiado"});
+          return;
+        case 400:
+          setMessage({error: true, text: "La contraseña debe tener al menos 8 caracteres"});
+          return;
+        case 403:
+          setMessage({error: true, text: "La contraseña actual no es correcta"});
+          return;
+        default:
+          console.log("auth.local. Fetch to change password. Status: " + res.status);
+          setMessage({error: true, text: "Se ha producido un problema cambiando la contraseña"});
+      }
+    } catch (ex) {
+      console.log("auth.local. Fetch to change password: " + ex);
+      setMessage({error: true, text: "Se ha producido un problema cambiando la contraseña"});
+    }
+  };
+
+  return (
+    <Container component="main" maxWidth="xs">
+      <CssBaseline />
+      <Box component="form" onSubmit={handleSubmit} sx={{ mt: 8 }}>
+        <Typography variant="h5" component="h1" gutterBottom>
+          Cambiar contraseña
+        </Typography>
+        {message.text &&
+          <MuiAlert severity={message.error ? "error" : "success"}>{message.text}</MuiAlert>}
+        <TextField margin="normal" required fullWidth label="Contraseña actual"
+          type="password" autoComplete="current-password" value={current}
+          onChange={(e) => setCurrent(e.target.value)}/>
+        <TextField margin="normal" required fullWidth label="Nueva contraseña"
+          type="password" autoComplete="new-password" value={password}
+          onChange={(e) => setPassword(e.target.value)}/>
+        <TextField margin="normal" required fullWidth label="Repita la nueva contraseña"
+          type="password" autoComplete="new-password" value={repeat}
+          onChange={(e) => setRepeat(e.target.value)}/>
+        <Button type="submit" fullWidth variant="contained" sx={{ mt: 3 }}>
+          Cambiar
+        </Button>
+        <Button href="/" fullWidth sx={{ mt: 1 }}>
+          Volver
+        </Button>
+      </Box>
+    </Container>
+  );
+}
@@ -21,3 +21,25 @@ import { appConfig } from "../app/config";
 export function logoff() {
     window.location.href = appConfig().authLogoutUrl;
 }
+
+
+// keepSession renews the session periodically while the page is open.
+// It returns the timer id to stop the renewal, or null if it is disabled
+export function keepSession(): number | null {
+    const interval = appConfig().refreshInterval;
+
+    if (!interval) {
+        return null;
+    }
+
+    return window.setInterval(async () => {
+        try {
+            const res = await fetch("/auth/refresh", {method: "POST"});
+            if (res.status == 401) {
+                logoff();
+            }
+        } catch (ex) {
+            console.log("keepSession. Renewing the session: " + ex);
+        }
+    }, interval * 1000);
+}
@@ -66,6 +66,8 @@ interface ConfigState {
 export default class Config extends React.Component<any, ConfigState> {
 
   private fetch?: Fetch;
+  // etag is the revision of the loaded configuration, sent back when saving
+  private etag = "*";
 
   constructor(props: any) {
     super(props);
@@ -109,6 +111,7 @@ export default class Config extends React.Component<any, ConfigState> {
         actions.activeLoadingConfig(false);
         if (result.ok) {
           try {
+            this.etag = result.headers.get("ETag") ?? "*";
             const res = await result.json();
             this.setControl(
               res.wakeup,
@@ -181,13 +184,13 @@ export default class Config extends React.Component<any, ConfigState> {
       }
     }
 
-    if (!(this.state.bufferValue >= 3 && this.state.bufferValue <= 60)) {
+    if (!(this.state.bufferValue >= 3 && this.state.bufferValue <= 20)) {
       this.setState({ bufferValid: false });
       valid = false;
     }
 
     if (!(this.state.stabilizationTimeValue >= 5 &&
-      this.state.stabilizationTimeValue <= 30)) {
+      this.state.stabilizationTimeValue <= 60)) {
 
       this.setState({ stabilizationTimeValid: false });
       valid = false;
@@ -265,7 +268,8 @@ export default class Config extends React.Component<any, ConfigState> {
       this.fetch?.send("/api/web/config", {
         method: "POST",
         headers: {
-          "Content-Type": "application/json"
+          "Content-Type": "application/json",
+          "If-Match": this.etag
         },
         body: JSON.stringify({
           "wakeup": this.state.wakeupValue,
@@ -286,6 +290,23 @@ export default class Config extends React.Component<any, ConfigState> {
           if (result.ok) {
             return true;
           }
+          if (result.status === 422) {
+            const problem = await result.json();
+            this.props.alert.current.content(
+              "Configuración no válida",
+              problem.errors
+                .map((e: { field: string, message: string }) => e.field + ": " + e.message)
+                .join(". "));
+            this.props.alert.current.open();
+            return true;
+          }
+          if (result.status === 412) {
+            this.props.alert.current.content(
+              "Configuración modificada",
+              "Otro usuario ha guardado la configuración mientras la editaba. Vuelva a abrirla para ver los cambios");
+            this.props.alert.current.open();
+            return true;
+          }
           return false;
         },
         () => {
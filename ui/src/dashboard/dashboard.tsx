@@ -28,7 +28,7 @@ import Meassure, { defaulBoxColor } from './meassure';
 import AppBar from '@mui/material/AppBar';
 import ExitToAppIcon from '@mui/icons-material/ExitToApp'
 import RefreshIcon from '@mui/icons-material/Refresh';
-import { AppRegistration, Assignment } from '@mui/icons-material';
+import { AppRegistration, Assignment, Password } from '@mui/icons-material';
 import Config from '../config/config';
 import Tooltip from '@mui/material/Tooltip';
 import { CircularProgress } from '@mui/material';
@@ -38,10 +38,11 @@ import { colorPurple } from '../support/color';
 import SocketFactory, { CommStatus, Metrics } from '../net/socket';
 import { Websocket } from 'websocket-ts/lib';
 import { MediaQuery, MediaQueryAPI } from '../support/mediaquery';
-import { logoff } from '../auth/user';
+import { keepSession, logoff } from '../auth/user';
 import * as literals from '../support/literals';
 import Sample from '../ai/sample';
 import { appConfig } from '../app/config';
+import { urlChangePassword } from '../auth/local';
 import Fetch from '../net/fetch';
 
 const drawerWidth: number = 255;
@@ -92,6 +93,9 @@ export default class Dashboard extends React.Component<any, DashboardState> impl
   // After that it will be the user who requests them
   private ondemandData: boolean;
 
+  // Renews the session while the dashboard is open
+  private sessionTimer: number | null = null;
+
   constructor(props: any) {
     super(props);
 
@@ -259,7 +263,15 @@ export default class Dashboard extends React.Component<any, DashboardState> impl
     logoff();
   }
 
+  componentWillUnmount(): void {
+    if (this.sessionTimer != null) {
+      window.clearInterval(this.sessionTimer);
+    }
+  }
+
   componentDidMount(): void {
+    this.sessionTimer = keepSession();
+
     // Pipeline between the chart and meassure. 
     // it sends the last meassure received by chart to the meassure control
     if (this.chartTemp.current) {
@@ -352,6 +364,13 @@ export default class Dashboard extends React.Component<any, DashboardState> impl
                 </IconButton>
               </Tooltip>
             )}
+            {appConfig().changePassword && (
+              <Tooltip title="Cambiar contraseña">
+                <IconButton color="inherit" href={urlChangePassword}>
+                  <Password />
+                </IconButton>
+              </Tooltip>
+            )}
             <Tooltip title="Salir">
               <IconButton color="inherit" onClick={() => this.exit()}>
                 <ExitToAppIcon />
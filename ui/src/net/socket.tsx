@@ -34,9 +34,19 @@ export interface Metrics {
   orp: number[];
 }
 
+// ConfigStatus is the delivery of the configuration to the micro-controller.
+// It is pending until the micro-controller wakes up and applies it
+export interface ConfigStatus {
+  version: number;
+  pending: boolean;
+  sent?: string;
+  applied?: string;
+}
+
 export interface SocketEvent {
   streamMetrics: (metrics: Metrics) => void;
   status: (status: CommStatus) => void;
+  configStatus: (status: ConfigStatus) => void;
 }
 
 // SocketFactory Manages socket iteration with the server
@@ -59,7 +69,8 @@ export default class SocketFactory {
 
     this.event = {
       streamMetrics: () => { },
-      status: () => { }
+      status: () => { },
+      configStatus: () => { }
     }
   }
 
@@ -125,6 +136,8 @@ export default class SocketFactory {
               this.alert.current.open();
               this.actions.activeStandby(true)
             }
+          } else if (message.messageType == MessageType.config) {
+            this.event.configStatus(message.configMessage());
           } else {
             this.setStatus(CommStatus.broadcasting);
             this.actions.activeStandby(false);
@@ -151,7 +164,9 @@ enum MessageType {
   // control is a message of control type
   control,
   // control is a message of metric type
-  metrics
+  metrics,
+  // config is a message with the status of the configuration
+  config
 }
 
 // MessageFactory builds the message
@@ -160,7 +175,16 @@ class MessageFactory {
   private rawMessage: string;
 
   constructor(msg: string) {
-    this.messageType = msg.at(0) == "0" ? MessageType.control : MessageType.metrics
+    switch (msg.at(0)) {
+      case "0":
+        this.messageType = MessageType.control;
+        break;
+      case "2":
+        this.messageType = MessageType.config;
+        break;
+      default:
+        this.messageType = MessageType.metrics;
+    }
     this.rawMessage = msg.substring(1)
   }
 
@@ -171,6 +195,11 @@ class MessageFactory {
       CommStatus.inactive
   }
 
+  // configMessage gets the status of the configuration
+  configMessage(): ConfigStatus {
+    return JSON.parse(this.rawMessage);
+  }
+
   metricsMessage(): Metrics {
     const metrics = JSON.parse(this.rawMessage);
     return {